@@ -82,6 +82,40 @@ func BenchmarkAddPatterns_Large(b *testing.B) {
 	}
 }
 
+// BenchmarkLoad_Large compares restoring a large rule set with
+// UnmarshalBinary against parsing it again with AddPatterns.
+func BenchmarkLoad_Large(b *testing.B) {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sb, "*.ext%d\n", i)
+		fmt.Fprintf(&sb, "src/dir%d/\n", i)
+		fmt.Fprintf(&sb, "**/cache%d/**/[a-z]?.tmp\n", i)
+		fmt.Fprintf(&sb, "!keep%d.ext%d\n", i, i)
+	}
+	content := []byte(sb.String())
+	m := New()
+	m.AddPatterns("", content)
+	data, err := m.MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			New().AddPatterns("", content)
+		}
+	})
+	b.Run("binary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := New().UnmarshalBinary(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 // BenchmarkMatch_Miss measures matching a non-ignored path
 func BenchmarkMatch_Miss(b *testing.B) {
 	b.ReportAllocs()
@@ -182,6 +216,65 @@ func BenchmarkMatch_DoubleStarDeep(b *testing.B) {
 	}
 }
 
+// BenchmarkMatch_RedundantDoubleStar measures "**/*.log", whose leading
+// **/ is dropped at parse time, against the same rule with the ** segment
+// kept, as it was matched before.
+func BenchmarkMatch_RedundantDoubleStar(b *testing.B) {
+	path := "src/app/internal/logs/debug.log"
+
+	b.Run("simplified", func(b *testing.B) {
+		b.ReportAllocs()
+		m := New()
+		m.AddPatterns("", []byte("**/*.log\n"))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			m.Match(path, false)
+		}
+	})
+
+	b.Run("unsimplified", func(b *testing.B) {
+		b.ReportAllocs()
+		m := New()
+		m.AddPatterns("", []byte("**/*.log\n"))
+		r := &m.rules[0]
+		r.segments = append([]segment{{doubleStar: true}}, r.segments...)
+		r.fixedLen = false
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			m.Match(path, false)
+		}
+	})
+}
+
+// BenchmarkMatch_AnchoredOnly measures a manifest-style config of exact
+// top-level names against a deep path, with AnchoredOnly and with the
+// default floating interpretation, which tries every name at every depth.
+func BenchmarkMatch_AnchoredOnly(b *testing.B) {
+	var manifest strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&manifest, "asset%d.bin\n", i)
+	}
+	path := "src/pkg/internal/render/shaders/main.go"
+
+	for _, tc := range []struct {
+		name string
+		opts MatcherOptions
+	}{
+		{"default", MatcherOptions{}},
+		{"anchored-only", MatcherOptions{AnchoredOnly: true}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			m := NewWithOptions(tc.opts)
+			m.AddPatterns("", []byte(manifest.String()))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Match(path, false)
+			}
+		})
+	}
+}
+
 // BenchmarkMatch_ManyRules measures matching against many rules
 func BenchmarkMatch_ManyRules(b *testing.B) {
 	b.ReportAllocs()
@@ -215,6 +308,32 @@ func BenchmarkMatch_ManyRulesHit(b *testing.B) {
 	}
 }
 
+// BenchmarkMatch_EarlyHitTrailingRules measures an early decisive ignore
+// followed by many non-negation rules: Match stops at the hit, while
+// MatchWithReason must scan on to report the last matching rule.
+func BenchmarkMatch_EarlyHitTrailingRules(b *testing.B) {
+	m := New()
+	var sb strings.Builder
+	sb.WriteString("!keep.log\n*.log\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, "*.ext%d\n", i)
+	}
+	m.AddPatterns("", []byte(sb.String()))
+
+	b.Run("Match", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m.Match("debug.log", false)
+		}
+	})
+	b.Run("MatchWithReason", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m.MatchWithReason("debug.log", false)
+		}
+	})
+}
+
 // BenchmarkMatch_Negation measures negation pattern performance
 func BenchmarkMatch_Negation(b *testing.B) {
 	b.ReportAllocs()
@@ -320,6 +439,48 @@ func BenchmarkMatch_CaseInsensitiveUpper(b *testing.B) {
 	}
 }
 
+// BenchmarkMatchMany_CaseInsensitive compares already-lowercase input, which
+// skips path lowering entirely, against mixed-case input that must be lowered
+// (pattern segments are lowered once at AddPatterns time in both cases).
+func BenchmarkMatchMany_CaseInsensitive(b *testing.B) {
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	m.AddPatterns("", []byte("*.LOG\nBUILD/\nnode_modules/\n!Keep.log\n"))
+
+	inputs := map[string][]string{
+		"lower": {"src/main.go", "build/out.js", "logs/debug.log", "a/b/keep.log"},
+		"mixed": {"Src/Main.go", "BUILD/out.js", "Logs/Debug.LOG", "A/b/Keep.Log"},
+	}
+	for _, name := range []string{"lower", "mixed"} {
+		paths := inputs[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m.MatchMany(paths, nil)
+			}
+		})
+	}
+}
+
+// BenchmarkMatch_CaseInsensitivePatternCount matches lowercase input against
+// a growing number of uppercase patterns. Patterns are folded at AddPatterns
+// time, so allocations stay at zero however many patterns are consulted.
+func BenchmarkMatch_CaseInsensitivePatternCount(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		var content strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&content, "DIR%d/*.TMP%d\n", i, i)
+		}
+		m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+		m.AddPatterns("", []byte(content.String()))
+		b.Run(fmt.Sprintf("patterns=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				m.Match("src/dir5/cache.tmp5", false)
+			}
+		})
+	}
+}
+
 // BenchmarkNormalizePath measures path normalization overhead
 func BenchmarkNormalizePath(b *testing.B) {
 	b.ReportAllocs()
@@ -348,7 +509,26 @@ src/**/test/
 `)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		parseLines("", content, -1, "")
+		parseLines("", content, "", defaultParseOptions)
+	}
+}
+
+// BenchmarkParseSegments measures splitting and classifying a large pattern
+// set; run with -benchmem to compare allocations.
+func BenchmarkParseSegments(b *testing.B) {
+	var patterns []string
+	for i := 0; i < 100; i++ {
+		patterns = append(patterns,
+			fmt.Sprintf("*.ext%d", i),
+			fmt.Sprintf("src/dir%d/", i),
+			fmt.Sprintf("**/cache%d/**/[a-z]?.tmp", i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range patterns {
+			parseSegments(p)
+		}
 	}
 }
 
@@ -401,3 +581,21 @@ func BenchmarkMatchGlob_CharClass(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkSnapshot_Concurrent is BenchmarkMatch_Concurrent against a
+// Snapshot, which takes no lock per match.
+func BenchmarkSnapshot_Concurrent(b *testing.B) {
+	b.ReportAllocs()
+	m := New()
+	m.AddPatterns("", []byte("*.log\nbuild/\n**/node_modules/**\n"))
+	s := m.Snapshot()
+
+	b.RunParallel(func(pb *testing.PB) {
+		paths := []string{"src/main.go", "debug.log", "build/out.js", "node_modules/x/y.js"}
+		i := 0
+		for pb.Next() {
+			s.Match(paths[i%len(paths)], false)
+			i++
+		}
+	})
+}
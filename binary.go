@@ -0,0 +1,324 @@
+package ignore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBinaryFormat is returned by UnmarshalBinary when data is not a matcher
+// encoding it can load: it is truncated or corrupt, was written by an
+// unsupported format version, or holds case-folded rules for a
+// case-sensitive matcher. Wrapped errors carry the detail.
+var ErrBinaryFormat = errors.New("invalid binary matcher encoding")
+
+// binaryMagic starts every encoding produced by MarshalBinary.
+const binaryMagic = "gign"
+
+// binaryVersion is the format version written after binaryMagic. Bump it
+// whenever the layout below changes.
+const binaryVersion = 3
+
+// Header flags.
+const (
+	binFolded = 1 << iota // rule segments are lowercased (CaseInsensitive)
+)
+
+// Rule flags.
+const (
+	binNegate = 1 << iota
+	binDirOnly
+	binAnchored
+	binFixedLen
+	binFinal
+)
+
+// Segment flags.
+const (
+	binWildcard = 1 << iota
+	binDoubleStar
+	binMinOne
+	binHasQuestion
+	binHasEscape
+	binHasCharClass
+)
+
+// MarshalBinary encodes the compiled rules in a compact, versioned binary
+// form, implementing encoding.BinaryMarshaler. A CLI run many times can
+// cache the result and restore it with UnmarshalBinary instead of parsing
+// its ignore files again on every start.
+//
+// The encoding holds the rules exactly as compiled, with their patterns,
+// raw lines, sources, line numbers and scopes, along with the force-tracked
+// directories and the scope of a Sub view. Options are not encoded, and
+// neither are warnings, predicates, raw content or history.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MarshalBinary() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	buf := append([]byte(binaryMagic), binaryVersion)
+	var flags byte
+	if m.opts.CaseInsensitive {
+		flags |= binFolded
+	}
+	buf = append(buf, flags)
+	buf = appendBinaryString(buf, m.prefix)
+
+	buf = binary.AppendUvarint(buf, uint64(len(m.forceTracked)))
+	for _, dir := range m.forceTracked {
+		buf = appendBinaryString(buf, dir)
+	}
+
+	segCount := 0
+	for i := range m.rules {
+		segCount += len(m.rules[i].segments)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(m.rules)))
+	buf = binary.AppendUvarint(buf, uint64(segCount))
+	for i := range m.rules {
+		r := &m.rules[i]
+		buf = appendBinaryString(buf, r.pattern)
+		buf = appendBinaryString(buf, r.raw)
+		buf = appendBinaryString(buf, r.canonical)
+		buf = appendBinaryString(buf, r.basePath)
+		buf = appendBinaryString(buf, r.source)
+		buf = binary.AppendUvarint(buf, uint64(r.line))
+		buf = binary.AppendUvarint(buf, uint64(r.stripped))
+		buf = append(buf, binaryFlags(r.negate, r.dirOnly, r.anchored, r.fixedLen, r.final))
+		buf = binary.AppendUvarint(buf, uint64(len(r.segments)))
+		for _, seg := range r.segments {
+			buf = appendBinaryString(buf, seg.value)
+			buf = append(buf, binaryFlags(seg.wildcard, seg.doubleStar, seg.minOne,
+				seg.hasQuestion, seg.hasEscape, seg.hasCharClass))
+			buf = binary.AppendUvarint(buf, uint64(seg.starCount))
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces m's rules, force-tracked directories and Sub
+// view scope with those encoded in data by MarshalBinary, implementing
+// encoding.BinaryUnmarshaler. m keeps its own options, warnings and
+// predicates, so create it with the options the cache was built under:
+//
+//	m := ignore.NewWithOptions(opts)
+//	if err := m.UnmarshalBinary(cached); err != nil {
+//	    // rebuild from the ignore files
+//	}
+//
+// As with Merge, options that act at parse time keep the effect they had
+// when the rules were compiled. Rules from a case-sensitive matcher are
+// case-folded when m is CaseInsensitive; rules from a case-insensitive one
+// cannot be unfolded, so loading them into a case-sensitive m fails. If the
+// rules exceed m's MaxPatterns, the excess is dropped with a warning.
+//
+// On error, m is left unchanged and the error wraps ErrBinaryFormat.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) UnmarshalBinary(data []byte) error {
+	// Decode from one string copy of data, so every pattern, path and
+	// segment value is a substring of it rather than a separate allocation.
+	d := binaryDecoder{data: string(data)}
+	if magic := d.bytes(len(binaryMagic)); magic != binaryMagic {
+		return fmt.Errorf("%w: missing header", ErrBinaryFormat)
+	}
+	if v := d.byte(); v != binaryVersion && d.err == nil {
+		return fmt.Errorf("%w: unsupported version %d", ErrBinaryFormat, v)
+	}
+	folded := d.byte()&binFolded != 0
+	prefix := d.string()
+
+	forceTracked := make([]string, d.count())
+	for i := range forceTracked {
+		forceTracked[i] = d.string()
+	}
+
+	rules := make([]rule, d.count())
+	segments := make([]segment, d.count()) // shared by all rules
+	for i := range rules {
+		// Fill each rule and segment in a local and store it once, which
+		// keeps heap writes (and GC write barriers) to one per item.
+		var r rule
+		r.pattern = d.string()
+		r.raw = d.string()
+		r.canonical = d.string()
+		r.basePath = d.string()
+		r.source = d.string()
+		r.line = int(d.uvarint())
+		r.stripped = int(d.uvarint())
+		flags := d.byte()
+		r.negate = flags&binNegate != 0
+		r.dirOnly = flags&binDirOnly != 0
+		r.anchored = flags&binAnchored != 0
+		r.fixedLen = flags&binFixedLen != 0
+		r.final = flags&binFinal != 0
+		switch {
+		case r.basePath == "":
+		case i > 0 && r.basePath == rules[i-1].basePath:
+			r.basePathSlash = rules[i-1].basePathSlash
+			r.baseSegCount = rules[i-1].baseSegCount
+		default:
+			r.basePathSlash = r.basePath + "/"
+			r.baseSegCount = len(splitPath(r.basePath))
+		}
+
+		n := d.count()
+		if n > len(segments) {
+			d.fail("segment count exceeds total")
+			break
+		}
+		r.segments, segments = segments[:n:n], segments[n:]
+		for j := range r.segments {
+			var seg segment
+			seg.value = d.string()
+			flags := d.byte()
+			seg.wildcard = flags&binWildcard != 0
+			seg.doubleStar = flags&binDoubleStar != 0
+			seg.minOne = flags&binMinOne != 0
+			seg.hasQuestion = flags&binHasQuestion != 0
+			seg.hasEscape = flags&binHasEscape != 0
+			seg.hasCharClass = flags&binHasCharClass != 0
+			seg.starCount = int(d.uvarint())
+			r.segments[j] = seg
+		}
+		rules[i] = r
+	}
+	if len(d.data) > 0 || len(segments) > 0 {
+		d.fail("trailing data")
+	}
+	if d.err != nil {
+		return fmt.Errorf("%w: %v", ErrBinaryFormat, d.err)
+	}
+
+	m.mu.Lock()
+	if folded && !m.opts.CaseInsensitive {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: rules are case-folded but the matcher is case-sensitive", ErrBinaryFormat)
+	}
+	if m.opts.CaseInsensitive && !folded {
+		foldRules(rules)
+		for i, dir := range forceTracked {
+			forceTracked[i] = strings.ToLower(dir)
+		}
+	}
+
+	var warnings []ParseWarning
+	if limit := m.opts.MaxPatterns; limit >= 0 && len(rules) > limit {
+		rules = rules[:limit]
+		warnings = append(warnings, ParseWarning{Message: "maximum pattern count reached, excess patterns truncated"})
+	}
+	m.rules = rules
+	m.forceTracked = forceTracked
+	m.prefix = prefix
+	m.negateEnd = 0
+	for i := range rules {
+		if rules[i].negate {
+			m.negateEnd = i + 1
+		}
+	}
+	handler := m.opts.WarningHandler
+	if handler == nil {
+		m.warnings = append(m.warnings, warnings...)
+	}
+	m.mu.Unlock()
+
+	if handler != nil {
+		for _, w := range warnings {
+			handler(w)
+		}
+	}
+	return nil
+}
+
+// appendBinaryString appends s to buf, preceded by its length as a uvarint.
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// binaryFlags packs bits into a flag byte, the first bit lowest.
+func binaryFlags(bits ...bool) byte {
+	var flags byte
+	for i, set := range bits {
+		if set {
+			flags |= 1 << i
+		}
+	}
+	return flags
+}
+
+// binaryDecoder reads the fields written by MarshalBinary from data. After
+// the first error every read returns a zero value, so callers check err
+// once at the end.
+type binaryDecoder struct {
+	data string
+	err  error
+}
+
+// fail records msg as the error unless one is already set.
+func (d *binaryDecoder) fail(msg string) {
+	if d.err == nil {
+		d.err = errors.New(msg)
+	}
+}
+
+func (d *binaryDecoder) bytes(n int) string {
+	if d.err != nil {
+		return ""
+	}
+	if n > len(d.data) {
+		d.fail("unexpected end of data")
+		return ""
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *binaryDecoder) byte() byte {
+	if b := d.bytes(1); b != "" {
+		return b[0]
+	}
+	return 0
+}
+
+// uvarint reads a value written by binary.AppendUvarint.
+func (d *binaryDecoder) uvarint() uint64 {
+	var v uint64
+	for i := 0; d.err == nil; i++ {
+		if i == binary.MaxVarintLen64 || i == len(d.data) {
+			d.fail("malformed varint")
+			break
+		}
+		b := d.data[i]
+		v |= uint64(b&0x7f) << (7 * i)
+		if b < 0x80 {
+			if i == binary.MaxVarintLen64-1 && b > 1 {
+				d.fail("malformed varint")
+				break
+			}
+			d.data = d.data[i+1:]
+			return v
+		}
+	}
+	return 0
+}
+
+// count reads a length prefix. Every counted item takes at least one byte,
+// so a count larger than the remaining data is rejected before the caller
+// allocates for it.
+func (d *binaryDecoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("count exceeds remaining data")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *binaryDecoder) string() string {
+	return d.bytes(d.count())
+}
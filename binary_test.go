@@ -0,0 +1,135 @@
+package ignore
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalBinary_RoundTrip(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{DoubleStarMinOne: true, Canonicalize: true})
+	m.AddPatterns("", []byte("*.log\nbuild/\n!keep.log\n/dist\na/**/b\n[Tt]emp?\n\\#literal\n"))
+	m.AddPatternsWithSource("src", "src/.gitignore", []byte("*.tmp\n!/gen/\n"))
+	m.AddForceTrackedDir("build/keepme")
+
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	got := NewWithOptions(MatcherOptions{DoubleStarMinOne: true})
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	if got.RuleCount() != m.RuleCount() {
+		t.Fatalf("RuleCount() = %d, want %d", got.RuleCount(), m.RuleCount())
+	}
+	paths := []string{
+		"debug.log", "keep.log", "build/out.js", "build/keepme/a.o", "dist",
+		"src/dist", "a/b", "a/x/b", "Temp1", "#literal", "src/x.tmp",
+		"src/gen/y.tmp", "main.go",
+	}
+	for _, p := range paths {
+		for _, isDir := range []bool{false, true} {
+			if g, w := got.MatchWithReason(p, isDir), m.MatchWithReason(p, isDir); !reflect.DeepEqual(g, w) {
+				t.Errorf("MatchWithReason(%q, %v) = %+v, want %+v", p, isDir, g, w)
+			}
+		}
+	}
+	if !reflect.DeepEqual(got.rules, m.rules) || !reflect.DeepEqual(got.forceTracked, m.forceTracked) {
+		t.Errorf("decoded rules differ:\ngot  %+v\nwant %+v", got.rules, m.rules)
+	}
+
+	// Encoding is deterministic.
+	again, _ := got.MarshalBinary()
+	if string(again) != string(data) {
+		t.Error("re-encoding a decoded matcher should give the same bytes")
+	}
+}
+
+func TestUnmarshalBinary_CaseFolding(t *testing.T) {
+	sensitive := New()
+	sensitive.AddPatterns("docs", []byte("Draft.md\n"))
+	data, _ := sensitive.MarshalBinary()
+
+	insensitive := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	if err := insensitive.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if !insensitive.Match("docs/DRAFT.MD", false) {
+		t.Error("rules from a case-sensitive encoding should be folded")
+	}
+
+	data, _ = insensitive.MarshalBinary()
+	err := New().UnmarshalBinary(data)
+	if !errors.Is(err, ErrBinaryFormat) {
+		t.Errorf("loading folded rules into a case-sensitive matcher: err = %v, want ErrBinaryFormat", err)
+	}
+}
+
+func TestUnmarshalBinary_Invalid(t *testing.T) {
+	m := New()
+	m.AddPatterns("src", []byte("*.log\n!keep.log\n"))
+	data, _ := m.MarshalBinary()
+
+	target := New()
+	target.AddPatterns("", []byte("*.tmp\n"))
+
+	// Every truncation of a valid encoding is rejected without panicking.
+	for n := 0; n < len(data); n++ {
+		if err := target.UnmarshalBinary(data[:n]); !errors.Is(err, ErrBinaryFormat) {
+			t.Fatalf("UnmarshalBinary(data[:%d]) = %v, want ErrBinaryFormat", n, err)
+		}
+	}
+
+	bad := map[string][]byte{
+		"empty":         nil,
+		"wrong magic":   append([]byte("nope"), data[4:]...),
+		"wrong version": append(append([]byte(binaryMagic), binaryVersion+1), data[5:]...),
+		"trailing data": append(append([]byte(nil), data...), 0),
+		"huge count":    append([]byte(binaryMagic), binaryVersion, 0, 0, 0xff, 0xff, 0xff, 0xff, 0x0f),
+	}
+	for name, b := range bad {
+		if err := target.UnmarshalBinary(b); !errors.Is(err, ErrBinaryFormat) {
+			t.Errorf("%s: err = %v, want ErrBinaryFormat", name, err)
+		}
+	}
+
+	// A failed load leaves the matcher unchanged.
+	if target.RuleCount() != 1 || !target.Match("a.tmp", false) {
+		t.Error("a failed UnmarshalBinary should not modify the matcher")
+	}
+}
+
+func TestUnmarshalBinary_MaxPatterns(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("a\nb\nc\n"))
+	data, _ := m.MarshalBinary()
+
+	var warnings []ParseWarning
+	limited := NewWithOptions(MatcherOptions{
+		MaxPatterns:    2,
+		WarningHandler: func(w ParseWarning) { warnings = append(warnings, w) },
+	})
+	if err := limited.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if limited.RuleCount() != 2 || len(warnings) != 1 {
+		t.Errorf("RuleCount() = %d with warnings %v, want 2 rules and a truncation warning", limited.RuleCount(), warnings)
+	}
+}
+
+func TestMarshalBinary_SubView(t *testing.T) {
+	m := New()
+	m.AddPatterns("pkg", []byte("/gen/\n"))
+	sub := m.Sub("pkg")
+	data, _ := sub.MarshalBinary()
+
+	got := New()
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if !got.Match("gen/x.go", false) || got.Match("pkg/gen/x.go", false) {
+		t.Error("a decoded Sub view should keep its scope")
+	}
+}
@@ -0,0 +1,136 @@
+package ignore
+
+// MatcherBuilder assembles a Matcher from options and pattern sources with
+// a chainable API, for setups that combine global, file, and inline
+// patterns:
+//
+//	m, err := ignore.NewBuilder().
+//	    CaseInsensitive().
+//	    MaxBacktrack(5000).
+//	    AddGlobal().
+//	    AddFile(".gitignore").
+//	    AddPatterns("", []byte("*.tmp\n")).
+//	    Build()
+//
+// Each option method sets the corresponding MatcherOptions field, and each
+// Add method records a call to the Matcher method of the same name. Nothing
+// is read until Build, which loads the sources in the order they were added,
+// so later sources take precedence as usual. Parse warnings are collected on
+// the built matcher (see Warnings) unless a WarningHandler is set.
+//
+// A MatcherBuilder is not safe for concurrent use. It may be reused: each
+// Build starts from a new Matcher.
+type MatcherBuilder struct {
+	opts  MatcherOptions
+	steps []func(*Matcher) error
+}
+
+// NewBuilder returns a MatcherBuilder with default options and no sources.
+func NewBuilder() *MatcherBuilder {
+	return &MatcherBuilder{}
+}
+
+// Options replaces all options with opts. Option methods called afterwards
+// still apply on top of it.
+func (b *MatcherBuilder) Options(opts MatcherOptions) *MatcherBuilder {
+	b.opts = opts
+	return b
+}
+
+// CaseInsensitive sets MatcherOptions.CaseInsensitive.
+func (b *MatcherBuilder) CaseInsensitive() *MatcherBuilder {
+	b.opts.CaseInsensitive = true
+	return b
+}
+
+// MaxBacktrack sets MatcherOptions.MaxBacktrackIterations.
+func (b *MatcherBuilder) MaxBacktrack(n int) *MatcherBuilder {
+	b.opts.MaxBacktrackIterations = n
+	return b
+}
+
+// MaxPatterns sets MatcherOptions.MaxPatterns.
+func (b *MatcherBuilder) MaxPatterns(n int) *MatcherBuilder {
+	b.opts.MaxPatterns = n
+	return b
+}
+
+// MaxPatternLength sets MatcherOptions.MaxPatternLength.
+func (b *MatcherBuilder) MaxPatternLength(n int) *MatcherBuilder {
+	b.opts.MaxPatternLength = n
+	return b
+}
+
+// WarningHandler sets MatcherOptions.WarningHandler.
+func (b *MatcherBuilder) WarningHandler(h WarningHandler) *MatcherBuilder {
+	b.opts.WarningHandler = h
+	return b
+}
+
+// AddPatterns adds content under basePath, as Matcher.AddPatterns does.
+// content is not copied; do not modify it before Build.
+func (b *MatcherBuilder) AddPatterns(basePath string, content []byte) *MatcherBuilder {
+	return b.add(func(m *Matcher) error {
+		m.AddPatterns(basePath, content)
+		return nil
+	})
+}
+
+// AddFile adds the patterns of the file at path at the root, as
+// Matcher.AddPatternsFromFile("", path) does. A missing file fails Build.
+func (b *MatcherBuilder) AddFile(path string) *MatcherBuilder {
+	return b.AddFileAt("", path)
+}
+
+// AddFileAt adds the patterns of the file at path under basePath, as
+// Matcher.AddPatternsFromFile does. A missing file fails Build.
+func (b *MatcherBuilder) AddFileAt(basePath, path string) *MatcherBuilder {
+	return b.add(func(m *Matcher) error {
+		return m.AddPatternsFromFile(basePath, path)
+	})
+}
+
+// AddGlobal adds the user's global gitignore, as Matcher.AddGlobalPatterns
+// does.
+func (b *MatcherBuilder) AddGlobal() *MatcherBuilder {
+	return b.add((*Matcher).AddGlobalPatterns)
+}
+
+// AddSystem adds the system gitignore, as Matcher.AddSystemPatterns does.
+func (b *MatcherBuilder) AddSystem() *MatcherBuilder {
+	return b.add((*Matcher).AddSystemPatterns)
+}
+
+// AddExclude adds gitDir/info/exclude, as Matcher.AddExcludePatterns does.
+func (b *MatcherBuilder) AddExclude(gitDir string) *MatcherBuilder {
+	return b.add(func(m *Matcher) error {
+		return m.AddExcludePatterns(gitDir)
+	})
+}
+
+// AddPreset adds a built-in preset, as Matcher.AddPreset does. An unknown
+// name fails Build.
+func (b *MatcherBuilder) AddPreset(name string) *MatcherBuilder {
+	return b.add(func(m *Matcher) error {
+		return m.AddPreset(name)
+	})
+}
+
+// add records a source to load at Build time.
+func (b *MatcherBuilder) add(step func(*Matcher) error) *MatcherBuilder {
+	b.steps = append(b.steps, step)
+	return b
+}
+
+// Build creates a Matcher with the configured options and loads every
+// source in the order it was added. It stops at the first source that
+// fails and returns its error with a nil Matcher.
+func (b *MatcherBuilder) Build() (*Matcher, error) {
+	m := NewWithOptions(b.opts)
+	for _, step := range b.steps {
+		if err := step(m); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
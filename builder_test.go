@@ -0,0 +1,109 @@
+package ignore
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatcherBuilder_EquivalentToImperative(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(tmp, "nonexistent"))
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+	writeTree(t, tmp, map[string]string{
+		"git/ignore":             "*.swp\n",
+		"repo/.gitignore":        "build/\n*.LOG\nbad\\\n",
+		"repo/src/.gitignore":    "!keep.log\n",
+		"repo/.git/info/exclude": "secret.txt\n",
+	})
+	repo := filepath.Join(tmp, "repo")
+
+	built, err := NewBuilder().
+		CaseInsensitive().
+		MaxBacktrack(5000).
+		AddGlobal().
+		AddExclude(filepath.Join(repo, ".git")).
+		AddFile(filepath.Join(repo, ".gitignore")).
+		AddFileAt("src", filepath.Join(repo, "src", ".gitignore")).
+		AddPreset("go").
+		AddPatterns("", []byte("*.tmp\n")).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := NewWithOptions(MatcherOptions{CaseInsensitive: true, MaxBacktrackIterations: 5000})
+	for _, err := range []error{
+		want.AddGlobalPatterns(),
+		want.AddExcludePatterns(filepath.Join(repo, ".git")),
+		want.AddPatternsFromFile("", filepath.Join(repo, ".gitignore")),
+		want.AddPatternsFromFile("src", filepath.Join(repo, "src", ".gitignore")),
+		want.AddPreset("go"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want.AddPatterns("", []byte("*.tmp\n"))
+
+	if built.opts.MaxBacktrackIterations != 5000 || !built.opts.CaseInsensitive {
+		t.Errorf("options = %+v, want CaseInsensitive and MaxBacktrackIterations 5000", built.opts)
+	}
+	if built.RuleCount() != want.RuleCount() {
+		t.Errorf("RuleCount() = %d, want %d", built.RuleCount(), want.RuleCount())
+	}
+	if got := built.Warnings(); len(got) != 1 || !reflect.DeepEqual(got, want.Warnings()) {
+		t.Errorf("Warnings() = %v, want %v", got, want.Warnings())
+	}
+	for _, p := range []string{"a.swp", "secret.txt", "build/x", "debug.log", "src/keep.log", "x.tmp", "main.go", "app.test"} {
+		if got, w := built.MatchWithReason(p, false), want.MatchWithReason(p, false); !reflect.DeepEqual(got, w) {
+			t.Errorf("MatchWithReason(%q) = %+v, want %+v", p, got, w)
+		}
+	}
+}
+
+func TestMatcherBuilder_Errors(t *testing.T) {
+	b := NewBuilder().
+		AddPatterns("", []byte("*.log\n")).
+		AddFile(filepath.Join(t.TempDir(), "missing"))
+	if m, err := b.Build(); err == nil || m != nil {
+		t.Errorf("Build() = %v, %v; want an error for a missing file", m, err)
+	}
+	if _, err := NewBuilder().AddPreset("nope").Build(); err == nil {
+		t.Error("Build() should fail for an unknown preset")
+	}
+}
+
+func TestMatcherBuilder_OptionsAndReuse(t *testing.T) {
+	var handled []ParseWarning
+	b := NewBuilder().
+		MaxPatterns(5).
+		Options(MatcherOptions{CommentChar: ';'}).
+		MaxPatternLength(8).
+		WarningHandler(func(w ParseWarning) { handled = append(handled, w) }).
+		AddPatterns("", []byte("; note\n#hash\nwaytoolongpattern\n"))
+
+	m, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.opts.MaxPatterns != DefaultMaxPatterns {
+		t.Errorf("MaxPatterns = %d; Options should replace earlier settings", m.opts.MaxPatterns)
+	}
+	if !m.Match("#hash", false) || m.RuleCount() != 1 {
+		t.Errorf("CommentChar ';' should apply; RuleCount() = %d", m.RuleCount())
+	}
+	if len(handled) != 1 || len(m.Warnings()) != 0 {
+		t.Errorf("warnings should go to the handler: handled %v, collected %v", handled, m.Warnings())
+	}
+
+	// Each Build starts from a fresh Matcher.
+	m2, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2.AddPatterns("", []byte("*.go\n"))
+	if m.Match("main.go", false) {
+		t.Error("matchers from separate Build calls should be independent")
+	}
+}
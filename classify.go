@@ -0,0 +1,128 @@
+package ignore
+
+import (
+	"strings"
+)
+
+// FileTree is a directory tree from a prior scan, as passed to Classify.
+// Name is a single path element; Children is only meaningful for
+// directories.
+type FileTree struct {
+	Name     string
+	IsDir    bool
+	Children []FileTree
+}
+
+// ClassifiedTree mirrors a FileTree node with its match decision attached.
+type ClassifiedTree struct {
+	Name  string
+	IsDir bool
+
+	// Path is the slash-separated path of the node relative to the tree
+	// root, as it would be passed to Match. Empty for the root itself.
+	Path string
+
+	// Result is what MatchWithReason(Path, IsDir) returns for the node. The
+	// zero value for the root.
+	Result MatchResult
+
+	Children []ClassifiedTree
+}
+
+// Classify annotates every node of tree with its MatchResult in a single
+// traversal. The root node stands for the directory the rules are relative
+// to (the repository root, or basePath for a Sub view): its Name is not part
+// of any path and it is not classified itself.
+//
+// Parents are classified before their children, which lets Classify skip
+// work that per-path calls repeat. Once a directory is ignored by a rule,
+// nothing under it can be re-included, so its descendants take over its
+// result without evaluating any rules; they are still present in the output.
+// Below a directory that is not ignored, a child only needs its own rules
+// checked, since its ancestors are already known to be kept.
+//
+// The read lock is held for the whole traversal, so every result reflects
+// the same rule set. OnMatch, if configured, is called once per classified
+// node in depth-first pre-order after the lock has been released.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) Classify(tree FileTree) ClassifiedTree {
+	root := ClassifiedTree{Name: tree.Name, IsDir: tree.IsDir}
+
+	m.mu.RLock()
+	depth := 0
+	if m.prefix != "" {
+		depth = strings.Count(m.prefix, "/") + 1
+	}
+	root.Children = m.classifyChildren("", depth, tree.Children, false, MatchResult{})
+	m.mu.RUnlock()
+
+	if m.observing() {
+		m.reportClassified(root.Children)
+	}
+	return root
+}
+
+// classifyChildren classifies the children of the directory at parent,
+// whose path has depth segments (including any Sub prefix). When pruned is
+// set the directory is ignored and ignoredBy is its result. Callers must
+// hold mu.
+func (m *Matcher) classifyChildren(parent string, depth int, children []FileTree, pruned bool, ignoredBy MatchResult) []ClassifiedTree {
+	if len(children) == 0 {
+		return nil
+	}
+	out := make([]ClassifiedTree, len(children))
+	for i, child := range children {
+		node := ClassifiedTree{Name: child.Name, IsDir: child.IsDir, Path: child.Name}
+		if parent != "" {
+			node.Path = parent + "/" + child.Name
+		}
+
+		childPruned := pruned
+		if pruned {
+			node.Result = ignoredBy
+			node.Result.PathDepth = depth + 1
+		} else {
+			node.Result = m.classifyNode(node.Path, depth, child.IsDir)
+			// A force-tracked directory below could re-include descendants.
+			childPruned = child.IsDir && node.Result.Matched && node.Result.Ignored && len(m.forceTracked) == 0
+		}
+		node.Children = m.classifyChildren(node.Path, node.Result.PathDepth, child.Children, childPruned, node.Result)
+		out[i] = node
+	}
+	return out
+}
+
+// classifyNode decides path, whose parent (at depth segments) is known not
+// to be ignored. Callers must hold mu.
+func (m *Matcher) classifyNode(path string, depth int, isDir bool) MatchResult {
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return MatchResult{}
+	}
+	if result, ok := m.gitDirResult(pathSegments, isDir); ok {
+		return result
+	}
+	ctx := m.newContext()
+
+	if len(pathSegments) != depth+1 || len(m.forceTracked) > 0 || m.fallback != nil {
+		// A Name holding separators or dot segments, or a force-tracked
+		// directory overriding the parent: the shortcut's assumption about
+		// the parent does not hold, so decide in full. A fallback may
+		// decide what m's rules leave unmatched, which only resolve
+		// consults.
+		return m.resolve(len(m.rules), path, pathSegments, isDir, &ctx)
+	}
+	result, _ := evaluateRules(m.consulted(), len(m.rules), path, pathSegments, isDir, false, &ctx)
+	result.PathDepth = len(pathSegments)
+	return m.applyDefault(result)
+}
+
+// reportClassified reports each node's result in depth-first pre-order.
+func (m *Matcher) reportClassified(nodes []ClassifiedTree) {
+	for i := range nodes {
+		m.report(nodes[i].Path, nodes[i].IsDir, nodes[i].Result)
+		m.reportClassified(nodes[i].Children)
+	}
+}
@@ -0,0 +1,140 @@
+package ignore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func classifyFixture() FileTree {
+	file := func(name string) FileTree { return FileTree{Name: name} }
+	dir := func(name string, children ...FileTree) FileTree {
+		return FileTree{Name: name, IsDir: true, Children: children}
+	}
+	return dir("repo",
+		file("README.md"),
+		dir("build",
+			file("keep.txt"),
+			file("out.js"),
+			dir("assets", file("logo.png")),
+		),
+		dir("logs",
+			file("debug.log"),
+			file("keep.log"),
+		),
+		dir("src",
+			file("main.go"),
+			file("trace.log"),
+		),
+	)
+}
+
+// flattenClassified maps each node's path to its result.
+func flattenClassified(nodes []ClassifiedTree, out map[string]ClassifiedTree) {
+	for _, n := range nodes {
+		out[n.Path] = n
+		flattenClassified(n.Children, out)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n!build/keep.txt\nlogs/*\n!logs/keep.log\n*.log\n!src/trace.log\n"))
+
+	tree := m.Classify(classifyFixture())
+	if tree.Name != "repo" || tree.Path != "" || tree.Result.Matched {
+		t.Errorf("root = %+v, want unclassified repo node", tree)
+	}
+
+	nodes := make(map[string]ClassifiedTree)
+	flattenClassified(tree.Children, nodes)
+
+	tests := []struct {
+		path    string
+		ignored bool
+		rule    string
+	}{
+		{"README.md", false, ""},
+		{"build", true, "build/"},
+		{"build/keep.txt", true, "build/"}, // cannot be re-included
+		{"build/assets/logo.png", true, "build/"},
+		{"logs", false, ""},
+		{"logs/debug.log", true, "*.log"},
+		{"logs/keep.log", true, "*.log"}, // a later rule re-ignores it
+		{"src/main.go", false, ""},
+		{"src/trace.log", false, "!src/trace.log"},
+	}
+	for _, tt := range tests {
+		n, ok := nodes[tt.path]
+		if !ok {
+			t.Errorf("node %q missing from result", tt.path)
+			continue
+		}
+		if n.Result.Ignored != tt.ignored || n.Result.Rule != tt.rule {
+			t.Errorf("Classify %q = %v, want ignored=%t rule=%q", tt.path, n.Result, tt.ignored, tt.rule)
+		}
+	}
+
+	// Every node agrees with the per-path API, PathDepth included.
+	for path, n := range nodes {
+		if want := m.MatchWithReason(path, n.IsDir); !reflect.DeepEqual(n.Result, want) {
+			t.Errorf("Classify %q = %+v, MatchWithReason = %+v", path, n.Result, want)
+		}
+	}
+}
+
+func TestClassify_NegatedFileUnderIgnoredDir(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("logs/*\n!logs/keep.log\n"))
+
+	tree := m.Classify(FileTree{IsDir: true, Children: []FileTree{
+		{Name: "logs", IsDir: true, Children: []FileTree{
+			{Name: "keep.log"},
+			{Name: "old", IsDir: true, Children: []FileTree{{Name: "keep.log"}}},
+		}},
+	}})
+
+	nodes := make(map[string]ClassifiedTree)
+	flattenClassified(tree.Children, nodes)
+	want := map[string]bool{
+		"logs":              false,
+		"logs/keep.log":     false, // negation re-includes it
+		"logs/old":          true,
+		"logs/old/keep.log": true, // inside an ignored directory
+	}
+	for path, ignored := range want {
+		if got := nodes[path].Result.Ignored; got != ignored {
+			t.Errorf("Classify %q ignored = %t, want %t", path, got, ignored)
+		}
+	}
+}
+
+func TestClassify_SubAndOptions(t *testing.T) {
+	var seen int
+	m := NewWithOptions(MatcherOptions{
+		DefaultIgnored: true,
+		OnMatch:        func(MatchResult) { seen++ },
+	})
+	m.AddPatterns("", []byte("!*.go\n!pkg/\n"))
+	sub := m.Sub("pkg")
+
+	tree := sub.Classify(FileTree{IsDir: true, Children: []FileTree{
+		{Name: "a.go"},
+		{Name: "a.txt"},
+		{Name: "inner", IsDir: true, Children: []FileTree{{Name: "b.go"}}},
+		{Name: "x/y.go"}, // separators in a name fall back to a full decision
+	}})
+
+	nodes := make(map[string]ClassifiedTree)
+	flattenClassified(tree.Children, nodes)
+	for path, n := range nodes {
+		if want := sub.matchWithReason(path, n.IsDir); !reflect.DeepEqual(n.Result, want) {
+			t.Errorf("Classify %q = %+v, MatchWithReason = %+v", path, n.Result, want)
+		}
+	}
+	if nodes["inner/b.go"].Result.Ignored {
+		t.Error("unmatched directory ignored only by default should not prune its contents")
+	}
+	if seen != len(nodes) {
+		t.Errorf("OnMatch called %d times, want %d", seen, len(nodes))
+	}
+}
@@ -0,0 +1,100 @@
+package ignore
+
+import "io/fs"
+
+// Compact returns a new Matcher with the same decisions as m for every path
+// but fewer rules, for tools that clean up ignore files. It removes:
+//
+//   - exact duplicates: of two rules with the same pattern, scope and
+//     polarity, the later one is dropped, or the earlier one if a rule of
+//     the opposite polarity lies between them;
+//   - rules made dead by an earlier rule of the same polarity that matches
+//     every path they could, with no rule of the opposite polarity in an
+//     overlapping scope between them: "debug.log" after "*.log", or any
+//     ignore rule after "*" in its scope.
+//
+// Removal is conservative: a rule is kept unless it is provably redundant,
+// using the same subset test as Lint and UnreachableRules, so a rule that
+// is kept may still be dead. The remaining rules keep their order, patterns,
+// sources and line numbers. Decisions agree on every path whose match stays
+// within the backtrack budget; with fewer rules to try, a path that used to
+// exhaust the budget may be decided more accurately.
+//
+// The result has m's options, scope, force-tracked directories, predicates
+// and fallback, but not its warnings, raw content or history. m is not
+// modified.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) Compact() *Matcher {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	kept := make([]rule, 0, len(m.rules))
+	for i := range m.rules {
+		r := &m.rules[i]
+		drop, replace := compactCheck(kept, r)
+		if drop {
+			continue
+		}
+		if replace >= 0 {
+			kept = append(kept[:replace], kept[replace+1:]...)
+		}
+		kept = append(kept, *r)
+	}
+
+	c := &Matcher{
+		rules:        kept[:len(kept):len(kept)],
+		opts:         m.opts,
+		prefix:       m.prefix,
+		forceTracked: append([]string(nil), m.forceTracked...),
+		predicates:   append([]func(path string, info fs.FileInfo) bool(nil), m.predicates...),
+		fallback:     m.fallback,
+		trace:        newMatchTrace(m.opts.TraceHistory),
+	}
+	for i := range c.rules {
+		if c.rules[i].negate {
+			c.negateEnd = i + 1
+		}
+	}
+	return c
+}
+
+// compactCheck decides what Compact does with r given the rules kept so
+// far. drop reports that an earlier kept rule of r's polarity covers r with
+// no opposite-polarity rule in an overlapping scope between them: whenever
+// r would be the last match, the last match without it has the same
+// polarity. Otherwise replace is the index of an earlier exact duplicate of
+// r to remove instead, which r makes dead, or -1.
+func compactCheck(kept []rule, r *rule) (drop bool, replace int) {
+	replace = -1
+	blocked := false
+	for i := len(kept) - 1; i >= 0; i-- {
+		e := &kept[i]
+		if e.negate != r.negate {
+			if scopeCovers(e.basePath, r.basePath) || scopeCovers(r.basePath, e.basePath) {
+				blocked = true
+			}
+			continue
+		}
+		if sameRule(e, r) {
+			if !blocked {
+				return true, -1
+			}
+			return false, i
+		}
+		if !blocked && !r.negate && covers(e, r) {
+			return true, -1
+		}
+	}
+	return false, -1
+}
+
+// covers reports whether the ignore rule e matches every path the ignore
+// rule r does, as far as can be proven: e is a catch-all of an enclosing
+// scope or shadows r (see shadows).
+func covers(e, r *rule) bool {
+	if e.isCatchAll() && scopeCovers(e.basePath, r.basePath) {
+		return true
+	}
+	return shadows(e, r)
+}
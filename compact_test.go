@@ -0,0 +1,170 @@
+package ignore
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// compactCorpus returns every path of up to three components over a small
+// alphabet of names the test configs mention, each as a file and as a
+// directory, so Compact is checked against every combination rather than
+// a hand-picked sample.
+func compactCorpus() ([]string, []bool) {
+	names := []string{"a", "src", "build", "debug.log", "keep.log", "x.tmp", "gen"}
+	var paths []string
+	level := []string{""}
+	for depth := 0; depth < 3; depth++ {
+		var next []string
+		for _, parent := range level {
+			for _, name := range names {
+				p := name
+				if parent != "" {
+					p = parent + "/" + name
+				}
+				next = append(next, p)
+			}
+		}
+		paths = append(paths, next...)
+		level = next
+	}
+	all := append(append([]string(nil), paths...), paths...)
+	isDirs := make([]bool, len(all))
+	for i := len(paths); i < len(all); i++ {
+		isDirs[i] = true
+	}
+	return all, isDirs
+}
+
+func TestCompact(t *testing.T) {
+	type source struct{ base, content string }
+	tests := []struct {
+		name    string
+		sources []source
+		want    []string // BasePath:Pattern of the remaining rules
+	}{
+		{
+			name:    "duplicates and shadowed names",
+			sources: []source{{"", "*.log\ndebug.log\n*.log\nbuild/\nbuild/\n"}},
+			want:    []string{":*.log", ":build/"},
+		},
+		{
+			name:    "duplicate across a negation keeps the later copy",
+			sources: []source{{"", "*.log\n!keep.log\n*.log\n"}},
+			want:    []string{":!keep.log", ":*.log"},
+		},
+		{
+			name:    "negation reopens a shadowed rule",
+			sources: []source{{"", "*.log\n!debug.log\ndebug.log\n"}},
+			want:    []string{":*.log", ":!debug.log", ":debug.log"},
+		},
+		{
+			name:    "duplicate negations",
+			sources: []source{{"", "*\n!*.log\n!*.log\n!src/\n"}},
+			want:    []string{":*", ":!*.log", ":!src/"},
+		},
+		{
+			name:    "catch-all covers nested scopes",
+			sources: []source{{"", "*\n"}, {"src", "*.tmp\ngen/\n"}},
+			want:    []string{":*"},
+		},
+		{
+			name:    "negation in a sibling scope does not block",
+			sources: []source{{"", "*.tmp\n"}, {"gen", "!a\n"}, {"src", "x.tmp\n"}},
+			want:    []string{":*.tmp", "gen:!a"},
+		},
+		{
+			name:    "negation in an enclosing scope blocks",
+			sources: []source{{"src", "*.tmp\n"}, {"", "!x.tmp\n"}, {"src", "x.tmp\n"}},
+			want:    []string{"src:*.tmp", ":!x.tmp", "src:x.tmp"},
+		},
+		{
+			name:    "floating name covers its anchored form but not dir-only",
+			sources: []source{{"", "build\nbuild/\n/build\n"}},
+			want:    []string{":build", ":build/"},
+		},
+		{
+			name:    "nothing to remove",
+			sources: []source{{"", "*.log\n!keep.log\nsrc/gen/\n"}},
+			want:    []string{":*.log", ":!keep.log", ":src/gen/"},
+		},
+	}
+
+	paths, isDirs := compactCorpus()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			for _, s := range tt.sources {
+				m.AddPatterns(s.base, []byte(s.content))
+			}
+			before := m.RuleCount()
+			c := m.Compact()
+
+			var got []string
+			for i := range c.rules {
+				ri := c.rules[i].info(i)
+				got = append(got, ri.BasePath+":"+ri.Pattern)
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("Compact() rules = %q, want %q", got, tt.want)
+			}
+			if m.RuleCount() != before {
+				t.Errorf("Compact() changed the receiver: %d rules, want %d", m.RuleCount(), before)
+			}
+			if d := Diff(m, c, paths, isDirs); d != nil {
+				t.Errorf("Compact() changed %d decisions, first %+v", len(d), d[0])
+			}
+		})
+	}
+}
+
+func TestCompact_Options(t *testing.T) {
+	paths, isDirs := compactCorpus()
+
+	// A final negation decides at its first match; dropping either copy
+	// must not change that.
+	first := NewWithOptions(MatcherOptions{ReturnFirstNegationWins: true})
+	first.AddPatterns("", []byte("!keep.log\n*.log\n!keep.log\n"))
+	if c := first.Compact(); c.RuleCount() != 2 || !Equivalent(first, c, paths, isDirs) {
+		t.Errorf("ReturnFirstNegationWins: %d rules, diffs %+v", c.RuleCount(), Diff(first, c, paths, isDirs))
+	}
+
+	ci := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	ci.AddPatterns("", []byte("*.log\nDEBUG.LOG\n"))
+	if c := ci.Compact(); c.RuleCount() != 1 || !Equivalent(ci, c, paths, isDirs) {
+		t.Errorf("CaseInsensitive: %d rules, diffs %+v", c.RuleCount(), Diff(ci, c, paths, isDirs))
+	}
+
+	tracked := New()
+	tracked.AddPatterns("", []byte("build/\nbuild/\n"))
+	tracked.AddForceTrackedDir("build/gen")
+	c := tracked.Compact()
+	if c.RuleCount() != 1 || c.Match("build/gen/a", false) || !c.Match("build/a", false) {
+		t.Error("Compact() should keep force-tracked directories")
+	}
+}
+
+func TestCompact_RandomConfigs(t *testing.T) {
+	pool := []string{
+		"*", "/*", "**", "*.log", "debug.log", "/debug.log", "keep.log", "build", "build/",
+		"/build/", "src/build", "*.tmp", "x.tmp", "src/", "gen/**", "**/gen", "a",
+		"!*.log", "!keep.log", "!build/", "!src/", "!a", "!/x.tmp", "!**/gen",
+	}
+	bases := []string{"", "", "src", "src/gen", "a"}
+	paths, isDirs := compactCorpus()
+	rng := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 300; n++ {
+		m := New()
+		var desc []string
+		for k := 0; k < 1+rng.Intn(8); k++ {
+			base, pat := bases[rng.Intn(len(bases))], pool[rng.Intn(len(pool))]
+			m.AddPatterns(base, []byte(pat+"\n"))
+			desc = append(desc, base+":"+pat)
+		}
+		c := m.Compact()
+		if d := Diff(m, c, paths, isDirs); d != nil {
+			t.Fatalf("rules %q: Compact() changed %d decisions, first %+v", strings.Join(desc, " "), len(d), d[0])
+		}
+	}
+}
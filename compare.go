@@ -0,0 +1,96 @@
+package ignore
+
+import (
+	"sort"
+)
+
+// MatchDiff records one path on which two matchers disagree. See Diff.
+type MatchDiff struct {
+	Path  string
+	IsDir bool
+	A, B  MatchResult // each matcher's result, including the deciding rule
+}
+
+// Equivalent reports whether a and b reach the same ignore decision for
+// every path in paths. isDirs follows the MatchMany convention: isDirs[i]
+// is the directory flag for paths[i], and a nil or shorter isDirs treats the
+// remaining paths as files.
+//
+// Equivalence is only established over the given sample, so it is as
+// strong as the path set: use a representative corpus (for example every
+// path in the working tree) when checking that a cleaned-up ignore file
+// behaves like the original.
+func Equivalent(a, b *Matcher, paths []string, isDirs []bool) bool {
+	ra := a.MatchMany(paths, isDirs)
+	rb := b.MatchMany(paths, isDirs)
+	for i := range ra {
+		if ra[i] != rb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff is the detailed form of Equivalent: it returns, in input order, every
+// path whose ignore decision differs between a and b, along with both
+// MatchResults so the responsible rules can be reported. Returns nil if the
+// matchers agree on every path.
+func Diff(a, b *Matcher, paths []string, isDirs []bool) []MatchDiff {
+	ra := a.MatchManyWithReason(paths, isDirs)
+	rb := b.MatchManyWithReason(paths, isDirs)
+
+	var diffs []MatchDiff
+	for i := range ra {
+		if ra[i].Ignored != rb[i].Ignored {
+			diffs = append(diffs, MatchDiff{
+				Path:  paths[i],
+				IsDir: i < len(isDirs) && isDirs[i],
+				A:     ra[i],
+				B:     rb[i],
+			})
+		}
+	}
+	return diffs
+}
+
+// Mismatch records one path on which a matcher disagrees with an expected
+// decision. See CheckExpectations.
+type Mismatch struct {
+	Path   string
+	IsDir  bool
+	Want   bool        // the expected ignore decision
+	Result MatchResult // the matcher's result, including the deciding rule
+}
+
+// CheckExpectations compares m's decision for each path in expectations
+// with the expected ignored flag, for regression tests that lock in the
+// intended behavior of a set of ignore files. It returns every path on
+// which m disagrees, sorted by path, with the MatchResult naming the rule
+// responsible. A path is a directory if isDirs maps it to true; a nil
+// isDirs treats every path as a file. Returns nil if m agrees throughout.
+//
+// Thread-safe: can be called concurrently.
+func CheckExpectations(m *Matcher, expectations map[string]bool, isDirs map[string]bool) []Mismatch {
+	paths := make([]string, 0, len(expectations))
+	for p := range expectations {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	dirs := make([]bool, len(paths))
+	for i, p := range paths {
+		dirs[i] = isDirs[p]
+	}
+
+	var mismatches []Mismatch
+	for i, r := range m.MatchManyWithReason(paths, dirs) {
+		if want := expectations[paths[i]]; r.Ignored != want {
+			mismatches = append(mismatches, Mismatch{
+				Path:   paths[i],
+				IsDir:  dirs[i],
+				Want:   want,
+				Result: r,
+			})
+		}
+	}
+	return mismatches
+}
@@ -0,0 +1,96 @@
+package ignore
+
+import (
+	"testing"
+)
+
+var compareCorpus = []string{
+	"main.go", "debug.log", "logs", "logs/app.log", "build", "build/out.js",
+	"src/keep.log", "src/main.go", "vendor/lib.go", "tmp/x.tmp",
+}
+
+var compareIsDirs = []bool{false, false, true, false, true, false, false, false, false, false}
+
+func TestEquivalent_Dedup(t *testing.T) {
+	original := New()
+	original.AddPatterns("", []byte("*.log\nbuild/\n*.log\n/build/\n*.tmp\n!src/keep.log\n"))
+
+	cleaned := New()
+	cleaned.AddPatterns("", []byte("*.log\nbuild/\n*.tmp\n!src/keep.log\n"))
+
+	if !Equivalent(original, cleaned, compareCorpus, compareIsDirs) {
+		t.Errorf("Equivalent() = false, want true; diffs: %+v", Diff(original, cleaned, compareCorpus, compareIsDirs))
+	}
+	if d := Diff(original, cleaned, compareCorpus, compareIsDirs); d != nil {
+		t.Errorf("Diff() = %+v, want nil", d)
+	}
+}
+
+func TestEquivalent_SemanticChange(t *testing.T) {
+	original := New()
+	original.AddPatterns("", []byte("*.log\nbuild/\n!src/keep.log\n"))
+
+	changed := New()
+	changed.AddPatterns("", []byte("*.log\nbuild/\n"))
+
+	if Equivalent(original, changed, compareCorpus, compareIsDirs) {
+		t.Error("Equivalent() = true, want false")
+	}
+
+	diffs := Diff(original, changed, compareCorpus, compareIsDirs)
+	if len(diffs) != 1 {
+		t.Fatalf("Diff() = %+v, want 1 entry", diffs)
+	}
+	d := diffs[0]
+	if d.Path != "src/keep.log" || d.IsDir || d.A.Ignored || !d.B.Ignored || d.A.Rule != "!src/keep.log" || d.B.Rule != "*.log" {
+		t.Errorf("Diff()[0] = %+v, want src/keep.log kept by !src/keep.log vs ignored by *.log", d)
+	}
+}
+
+func TestEquivalent_ShortIsDirs(t *testing.T) {
+	a := New()
+	a.AddPatterns("", []byte("build/\n"))
+	b := New()
+
+	// Without isDirs, "build" is a file, which build/ does not match.
+	if !Equivalent(a, b, []string{"build"}, nil) {
+		t.Error("Equivalent(build as file) = false, want true")
+	}
+	if Equivalent(a, b, []string{"build"}, []bool{true}) {
+		t.Error("Equivalent(build as dir) = true, want false")
+	}
+}
+
+func TestCheckExpectations(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\nbuild/\n!keep.log\n"))
+
+	expectations := map[string]bool{
+		"debug.log":    true,
+		"src/keep.log": false,
+		"build":        true,
+		"main.go":      false,
+		"notes.log":    false, // deliberate mismatch: *.log ignores it
+	}
+	got := CheckExpectations(m, expectations, map[string]bool{"build": true})
+	if len(got) != 1 {
+		t.Fatalf("CheckExpectations = %+v, want one mismatch", got)
+	}
+	if mm := got[0]; mm.Path != "notes.log" || mm.Want || mm.IsDir || !mm.Result.Ignored || mm.Result.Rule != "*.log" {
+		t.Errorf("mismatch = %+v, want notes.log expected kept but ignored by *.log", mm)
+	}
+
+	// Without isDirs, build is checked as a file, which build/ does not match.
+	got = CheckExpectations(m, expectations, nil)
+	var paths []string
+	for _, mm := range got {
+		paths = append(paths, mm.Path)
+	}
+	if !equalStrings(paths, []string{"build", "notes.log"}) {
+		t.Errorf("mismatched paths = %q, want [build notes.log] in sorted order", paths)
+	}
+
+	if got := CheckExpectations(m, nil, nil); got != nil {
+		t.Errorf("CheckExpectations(nil) = %+v, want nil", got)
+	}
+}
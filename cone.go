@@ -0,0 +1,95 @@
+package ignore
+
+import (
+	"sort"
+	"strings"
+)
+
+// NewConeMatcher returns a Matcher for a sparse checkout in cone mode
+// ("git sparse-checkout set --cone dirs..."): Match reports true for paths
+// outside the cone, which a sparse checkout leaves out of the working tree,
+// and false for paths inside it.
+//
+// As in git, the cone holds everything under each of dirs, every file at the
+// root, and the files directly inside each ancestor of dirs — but not that
+// ancestor's other subdirectories. For dirs ["a/b"], "README.md", "a/x.go"
+// and "a/b/c/d.go" are inside the cone; "a/other/y.go" and "z/w.go" are not.
+//
+// The matcher holds the ignore-form counterpart of the anchored,
+// directory-only patterns git writes for the cone ("/*/", "!/a/", "/a/*/",
+// "!a/b/"), so MatchWithReason names the rule that placed a path outside.
+// With no dirs, only the root files are in the cone.
+//
+// dirs are normalized like basePaths. Entries under another entry are
+// redundant; entries that escape the root, contain a NUL byte, or cannot be
+// written as a pattern (see SuggestPattern) are skipped. An entry naming the
+// root itself puts the whole tree in the cone.
+func NewConeMatcher(dirs ...string) *Matcher {
+	var cone []string
+	for _, dir := range dirs {
+		if escapesRoot(dir) || strings.IndexByte(dir, 0) >= 0 {
+			continue
+		}
+		dir = strings.TrimPrefix(normalizePath(dir), "/")
+		if dir == "" || dir == "." {
+			return New() // the whole tree
+		}
+		if SuggestPattern(dir, true) == "" {
+			continue // not expressible as a pattern (see SuggestPattern)
+		}
+		cone = append(cone, dir)
+	}
+
+	// Keep only the outermost entries; an entry under another adds nothing.
+	sort.Strings(cone)
+	var outer []string
+	for _, dir := range cone {
+		covered := false
+		for _, o := range outer {
+			if scopeCovers(o, dir) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			outer = append(outer, dir)
+		}
+	}
+
+	// Each ancestor of a cone entry excludes its subdirectories, then
+	// re-includes the children on the way to an entry. The root always
+	// excludes its subdirectories, even with no entries.
+	children := map[string][]string{"": nil}
+	parents := []string{""}
+	for _, dir := range outer {
+		segs := splitPath(dir)
+		for i := range segs {
+			parent := strings.Join(segs[:i], "/")
+			child := strings.Join(segs[:i+1], "/")
+			kids, seen := children[parent]
+			if !seen {
+				parents = append(parents, parent)
+			}
+			children[parent] = appendUnique(kids, child)
+		}
+	}
+	sort.Strings(parents)
+
+	var b strings.Builder
+	for _, parent := range parents {
+		if parent == "" {
+			b.WriteString("/*/\n")
+		} else {
+			b.WriteString(SuggestPattern(parent, true) + "*/\n")
+		}
+		kids := children[parent]
+		sort.Strings(kids)
+		for _, child := range kids {
+			b.WriteString("!" + SuggestPattern(child, true) + "\n")
+		}
+	}
+
+	m := New()
+	m.AddPatterns("", []byte(b.String()))
+	return m
+}
@@ -0,0 +1,110 @@
+package ignore
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewConeMatcher(t *testing.T) {
+	m := NewConeMatcher("a/b", "docs")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool // true = outside the cone
+	}{
+		{"README.md", false, false}, // root files are always in the cone
+		{"a/x.go", false, false},    // files directly in an ancestor
+		{"a/b", true, false},        // the cone directory itself
+		{"a/b/y.go", false, false},  // everything under it
+		{"a/b/c/d/z.go", false, false},
+		{"docs/guide/intro.md", false, false},
+		{"a/other", true, true}, // sibling directory of the cone
+		{"a/other/y.go", false, true},
+		{"z/w.go", false, true},    // unrelated top-level directory
+		{"a/bc/x.go", false, true}, // prefix of a name is not the name
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	if r := m.MatchWithReason("a/other/y.go", false); r.Rule != "/a/*/" {
+		t.Errorf("MatchWithReason(a/other/y.go).Rule = %q, want %q", r.Rule, "/a/*/")
+	}
+}
+
+func TestNewConeMatcher_Entries(t *testing.T) {
+	// Nested and duplicate entries collapse to the outermost one.
+	m := NewConeMatcher("./a/", "a/b", "a", "../outside", "x\x00y")
+	if m.Match("a/b/c/d.go", false) || m.Match("a/other/e.go", false) {
+		t.Error("everything under a should be in the cone")
+	}
+	if !m.Match("outside/f.go", false) || !m.Match("x", true) {
+		t.Error("skipped entries should not widen the cone")
+	}
+
+	// Glob characters in directory names are matched literally.
+	m = NewConeMatcher("src/[gen]")
+	if m.Match("src/[gen]/a.go", false) || !m.Match("src/g/a.go", false) {
+		t.Error("cone directory names should not be treated as globs")
+	}
+
+	// The root entry covers the whole tree; no entries cover only root files.
+	if NewConeMatcher(".").Match("any/dir/f", false) {
+		t.Error("a root entry should put every path in the cone")
+	}
+	m = NewConeMatcher()
+	if m.Match("f", false) || !m.Match("dir/f", false) {
+		t.Error("an empty cone holds the root files only")
+	}
+}
+
+// TestGitParity_ConeMode checks NewConeMatcher against the working tree git
+// produces for "git sparse-checkout set --cone".
+func TestGitParity_ConeMode(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	files := []string{
+		"r.txt", "A/a.txt", "A/B/b.txt", "A/B/C/c.txt", "A/X/x.txt",
+		"D/d.txt", "D/E/e.txt", "F/f.txt",
+	}
+	cone := []string{"A/B", "D"}
+
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.email=test@test.com", "-c", "user.name=Test"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("git %v failed (sparse-checkout may be unsupported): %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", "-A")
+	git("commit", "-q", "-m", "init")
+	git(append([]string{"sparse-checkout", "set", "--cone"}, cone...)...)
+
+	m := NewConeMatcher(cone...)
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f)))
+		inGit := err == nil
+		if inCone := !m.Match(f, false); inCone != inGit {
+			t.Errorf("%s: in cone = %v, git checked out = %v", f, inCone, inGit)
+		}
+	}
+}
@@ -231,6 +231,40 @@ func TestEdgeCases_Whitespace(t *testing.T) {
 	}
 }
 
+// TestEdgeCases_LeadingWhitespaceOption contrasts git's default (leading
+// whitespace is part of the pattern) with TrimLeadingWhitespace.
+func TestEdgeCases_LeadingWhitespaceOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		path     string
+		wantGit  bool
+		wantTrim bool
+	}{
+		{"leading space, spaced name", " leading.txt", " leading.txt", true, false},
+		{"leading space, plain name", " leading.txt", "leading.txt", false, true},
+		{"leading tab", "\tbuild/", "build/x", false, true},
+		{"indented comment", "  # note", "  # note", true, false},
+		{"indented negation", "*.log\n  !keep.log", "keep.log", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			git := New()
+			git.AddPatterns("", []byte(tt.pattern+"\n"))
+			if got := git.Match(tt.path, false); got != tt.wantGit {
+				t.Errorf("default: Match(%q) = %v, want %v", tt.path, got, tt.wantGit)
+			}
+
+			trim := NewWithOptions(MatcherOptions{TrimLeadingWhitespace: true})
+			trim.AddPatterns("", []byte(tt.pattern+"\n"))
+			if got := trim.Match(tt.path, false); got != tt.wantTrim {
+				t.Errorf("TrimLeadingWhitespace: Match(%q) = %v, want %v", tt.path, got, tt.wantTrim)
+			}
+		})
+	}
+}
+
 // TestEdgeCases_EscapedBackslash covers pattern "foo\\" — the gitignore escape
 // for a literal backslash in a filename. This scenario is Unix-only: on
 // Windows, backslash is the path separator and gets converted to '/' during
@@ -293,8 +327,8 @@ func TestEdgeCases_SpecialPatterns(t *testing.T) {
 		{"double star only", "**", "a/b/c", true, false},
 		{"triple star", "***", "file", true, false}, // treated as wildcard
 
-		// Consecutive slashes in pattern (normalized)
-		{"double slash pattern", "a//b", "a/b", true, false},
+		// Consecutive slashes in pattern (never match, as in git)
+		{"double slash pattern", "a//b", "a/b", false, false},
 
 		// Pattern with dots
 		{"extension dots", "*.tar.gz", "archive.tar.gz", true, true},
@@ -305,6 +339,13 @@ func TestEdgeCases_SpecialPatterns(t *testing.T) {
 		{"wildcard suffix", "test_*", "test_foo", true, false},
 		{"wildcard both", "*test*", "mytestfile", true, false},
 		{"wildcard middle", "a*b", "aXXXb", true, false},
+
+		// A floating pattern matches any component, so a directory named
+		// like a file pattern is excluded with its contents, as in git
+		{"extension dir", "*.log", "a.log", true, true},
+		{"extension dir contents", "*.log", "a.log/inner.txt", true, false},
+		{"extension dir nested contents", "*.log", "src/a.log/deep/x.go", true, false},
+		{"extension no dir match", "*.log", "a.logs/inner.txt", false, false},
 	}
 
 	for _, tt := range tests {
@@ -375,6 +416,24 @@ func TestEdgeCases_Negation(t *testing.T) {
 			"build",
 			false,
 		},
+		{
+			"directory negation re-includes contents",
+			"build/\n!build/",
+			"build/out.js",
+			false,
+		},
+		{
+			"directory negation keeps contents ignored by other rules",
+			"*.o\n!debug/",
+			"debug/a.o",
+			true,
+		},
+		{
+			"directory negation does not apply to a file",
+			"build\n!build/",
+			"src/build",
+			true,
+		},
 		// Nested negation
 		{
 			// Spec: parent dir excluded blocks re-include via negation.
@@ -392,6 +451,32 @@ func TestEdgeCases_Negation(t *testing.T) {
 			"temp/important",
 			true,
 		},
+		// Re-including a root directory after a broad ignore: "!/logs/"
+		// re-includes the directory, but "*" still matches its contents.
+		{
+			"anchored negated dir, contents still matched by star",
+			"*\n!/logs/",
+			"logs/a.txt",
+			true,
+		},
+		{
+			"anchored negated dir after root star",
+			"/*\n!/logs/",
+			"logs/a.txt",
+			false,
+		},
+		{
+			"anchored negated dir and contents",
+			"*\n!/logs/\n!/logs/**",
+			"logs/sub/b.txt",
+			false,
+		},
+		{
+			"negated contents blocked by ignored dir",
+			"/*\n!/logs/**",
+			"logs/a.txt",
+			true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -568,6 +653,35 @@ node_modules/
 	}
 }
 
+// TestEdgeCases_DeepPath_MaxFloatStarts checks that MaxFloatStarts bounds
+// the ancestor positions a floating literal tries: with a cap of 8, an
+// ignored directory at depth 8 or less is found and one deeper is not.
+func TestEdgeCases_DeepPath_MaxFloatStarts(t *testing.T) {
+	capped := NewWithOptions(MatcherOptions{MaxFloatStarts: 8})
+	capped.AddPatterns("", []byte("target\n"))
+	unlimited := New()
+	unlimited.AddPatterns("", []byte("target\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool // with the cap
+	}{
+		{buildDeepPath(7, "target/x.go"), false, true},  // 8th start position
+		{buildDeepPath(8, "target/x.go"), false, false}, // 9th: past the cap
+		{buildDeepPath(1000, "target/x.go"), false, false},
+		{buildDeepPath(1000, "target"), true, true}, // the path itself is always checked
+	}
+	for _, tt := range tests {
+		if got := capped.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("capped Match(%d segments) = %v, want %v", strings.Count(tt.path, "/")+1, got, tt.want)
+		}
+		if !unlimited.Match(tt.path, tt.isDir) {
+			t.Errorf("unlimited Match(%d segments) = false, want true", strings.Count(tt.path, "/")+1)
+		}
+	}
+}
+
 func buildDeepPath(n int, leaf string) string {
 	var b strings.Builder
 	b.Grow(n*2 + len(leaf))
@@ -0,0 +1,100 @@
+package ignore
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// EncodingNote records what was unusual about the encoding of one ignore
+// file loaded by a file-loading helper, to find the file saved by an editor
+// with unexpected settings. See EncodingNotes.
+type EncodingNote struct {
+	// Source is the path the file was read from.
+	Source string
+
+	// BasePath is the normalized basePath the patterns were loaded under.
+	BasePath string
+
+	// BOM reports that a UTF-8 byte-order mark was stripped.
+	BOM bool
+
+	// CRLF reports that CRLF line endings were normalized to LF.
+	CRLF bool
+
+	// CR reports that CR-only line endings were normalized to LF.
+	CR bool
+
+	// UTF16 reports a UTF-16 byte-order mark or NUL bytes: the file was
+	// most likely saved as UTF-16, and its patterns match nothing.
+	UTF16 bool
+
+	// InvalidUTF8 reports bytes that are not valid UTF-8, as in a file
+	// saved in a legacy code page. Patterns with such bytes only match
+	// paths spelled with the same bytes.
+	InvalidUTF8 bool
+}
+
+// EncodingNotes returns a note for every file loaded into m whose encoding
+// was not plain UTF-8 with LF line endings, in load order. Notes are taken
+// by AddPatternsFromFile, AddPatternsFileReport, AddGlobalPatterns,
+// AddSystemPatterns, AddExcludePatterns and LoadRepo; content passed to
+// AddPatterns directly, and files found by the walkers, are not noted.
+// Normalized files still load correctly, so a note is not a warning, but
+// the UTF16 and InvalidUTF8 kinds usually explain a file that seems to
+// have no effect. Returns nil if there is nothing to note.
+//
+// The returned slice is a copy; mutating it does not affect the matcher.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) EncodingNotes() []EncodingNote {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.encodingNotes) == 0 {
+		return nil
+	}
+	return append([]EncodingNote(nil), m.encodingNotes...)
+}
+
+// loadFile is loadPatterns for content read from the file at path by one
+// of the file-loading helpers: it also records the file's EncodingNote, if
+// there is anything to note.
+func (m *Matcher) loadFile(basePath string, content []byte, path string) (int, []ParseWarning) {
+	if note, ok := inspectEncoding(content); ok {
+		note.Source = path
+		note.BasePath = normalizePath(basePath)
+		if m.prefix != "" {
+			note.BasePath = m.scope(basePath)
+		}
+		m.mu.Lock()
+		m.encodingNotes = append(m.encodingNotes, note)
+		m.mu.Unlock()
+	}
+	return m.loadPatterns("", basePath, content, path)
+}
+
+// inspectEncoding reports what normalizeContent would change in content
+// and whether it looks like anything but UTF-8. A UTF-16 file is only
+// noted as such, since its bytes say nothing about BOM or line endings in
+// UTF-8 terms. ok is false when there is nothing to note.
+func inspectEncoding(content []byte) (note EncodingNote, ok bool) {
+	if bytes.HasPrefix(content, []byte{0xFF, 0xFE}) || bytes.HasPrefix(content, []byte{0xFE, 0xFF}) ||
+		bytes.IndexByte(content, 0) >= 0 {
+		note.UTF16 = true
+		return note, true
+	}
+	note.BOM = bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF})
+	note.InvalidUTF8 = !utf8.Valid(content)
+	for rest := content; !(note.CRLF && note.CR); {
+		i := bytes.IndexByte(rest, '\r')
+		if i < 0 {
+			break
+		}
+		if i+1 < len(rest) && rest[i+1] == '\n' {
+			note.CRLF = true
+		} else {
+			note.CR = true
+		}
+		rest = rest[i+1:]
+	}
+	return note, note.BOM || note.CRLF || note.CR || note.InvalidUTF8
+}
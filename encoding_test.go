@@ -0,0 +1,81 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodingNotes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"clean":  "*.log\nbuild/\n",
+		"bom":    "\xEF\xBB\xBF*.log\n",
+		"crlf":   "*.log\r\nbuild/\r\n",
+		"mixed":  "*.log\r\nbuild/\rtmp/\n",
+		"utf16":  "\xFF\xFE*\x00.\x00l\x00o\x00g\x00\n\x00",
+		"latin1": "caf\xE9.txt\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := New()
+	for _, name := range []string{"clean", "bom", "crlf", "mixed", "utf16", "latin1"} {
+		base := ""
+		if name == "crlf" {
+			base = "src/"
+		}
+		if err := m.AddPatternsFromFile(base, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.AddPatterns("", []byte("\xEF\xBB\xBFinline\r\n"))
+
+	want := []EncodingNote{
+		{Source: filepath.Join(dir, "bom"), BOM: true},
+		{Source: filepath.Join(dir, "crlf"), BasePath: "src", CRLF: true},
+		{Source: filepath.Join(dir, "mixed"), CRLF: true, CR: true},
+		{Source: filepath.Join(dir, "utf16"), UTF16: true},
+		{Source: filepath.Join(dir, "latin1"), InvalidUTF8: true},
+	}
+	got := m.EncodingNotes()
+	if len(got) != len(want) {
+		t.Fatalf("EncodingNotes() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("EncodingNotes()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Normalized files still load as usual.
+	if !m.Match("debug.log", false) || !m.Match("src/a.log", false) || !m.Match("tmp", true) {
+		t.Error("rules from the BOM, CRLF and CR files should match")
+	}
+
+	got[0].BOM = false
+	if !m.EncodingNotes()[0].BOM {
+		t.Error("EncodingNotes() should return a copy")
+	}
+	if notes := New().EncodingNotes(); notes != nil {
+		t.Errorf("EncodingNotes() = %+v, want nil for a new matcher", notes)
+	}
+}
+
+func TestEncodingNotes_FileReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	if err := os.WriteFile(path, []byte("\xEF\xBB\xBF*.tmp\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	if _, err := m.AddPatternsFileReport("", path); err != nil {
+		t.Fatal(err)
+	}
+	want := []EncodingNote{{Source: path, BOM: true, CRLF: true}}
+	if got := m.EncodingNotes(); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("EncodingNotes() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,237 @@
+package ignore
+
+import (
+	"strings"
+)
+
+// GlobSpan records which bytes of a name a single wildcard consumed.
+type GlobSpan struct {
+	// PatternOffset is the byte offset of the wildcard (* or ?) in the
+	// pattern. For a run of consecutive stars it is the offset of the first.
+	PatternOffset int
+
+	// Start and End delimit the consumed bytes of the name: name[Start:End].
+	// A * that matched nothing has Start == End.
+	Start, End int
+}
+
+// ExplainGlob matches a single-segment glob pattern against name and, on a
+// match, reports the span of name each * and ? wildcard covered, in pattern
+// order. For example, ExplainGlob("*.log", "debug.log") reports one span
+// covering "debug".
+//
+// pattern must be a single path segment (no '/'); name is usually the final
+// segment of the path being highlighted. Matching follows the same rules as
+// Match — escapes, character classes, and the lazy * expansion order — so
+// the spans reflect the assignment the matcher itself would find. Character
+// classes and literal bytes do not produce spans.
+//
+// ExplainGlob is intended for debugging and UI highlighting. It allocates and
+// is deliberately kept off the Match hot path. Matching is bounded by
+// DefaultMaxBacktrackIterations; if the budget runs out, ExplainGlob reports
+// no match.
+func ExplainGlob(pattern, name string) ([]GlobSpan, bool) {
+	ctx := newMatchContext(DefaultMaxBacktrackIterations)
+	spans, ok := explainGlob(pattern, 0, name, 0, nil, &ctx)
+	if !ok {
+		return nil, false
+	}
+	return spans, true
+}
+
+// explainGlob is the span-capturing twin of matchGlobRecursive. pi and si
+// are the current offsets into pattern and s; spans accumulates the
+// wildcard assignments found so far.
+func explainGlob(pattern string, pi int, s string, si int, spans []GlobSpan, ctx *matchContext) ([]GlobSpan, bool) {
+	for pi < len(pattern) {
+		if ctx.exhausted() {
+			return nil, false
+		}
+
+		switch pattern[pi] {
+		case '*':
+			star := pi
+			for pi < len(pattern) && pattern[pi] == '*' {
+				pi++
+			}
+			// Trailing * matches the rest of the string
+			if pi == len(pattern) {
+				return append(spans, GlobSpan{PatternOffset: star, Start: si, End: len(s)}), true
+			}
+			for j := si; j <= len(s); j++ {
+				span := GlobSpan{PatternOffset: star, Start: si, End: j}
+				if out, ok := explainGlob(pattern, pi, s, j, append(spans, span), ctx); ok {
+					return out, true
+				}
+				if !ctx.tick() {
+					return nil, false
+				}
+			}
+			return nil, false
+
+		case '?':
+			if si >= len(s) {
+				return nil, false
+			}
+			spans = append(spans, GlobSpan{PatternOffset: pi, Start: si, End: si + 1})
+			pi++
+			si++
+			continue
+
+		case '[':
+			if si >= len(s) || s[si] == '/' {
+				return nil, false
+			}
+			matched, newPos, valid := matchCharClass(pattern, pi, s[si])
+			if valid {
+				if !matched {
+					return nil, false
+				}
+				pi = newPos
+				si++
+				continue
+			}
+			// Invalid (unclosed bracket) — treat '[' as literal, fall through
+
+		case '\\':
+			if pi+1 < len(pattern) {
+				pi++ // skip the backslash, compare the escaped byte literally
+			}
+		}
+
+		if si >= len(s) || pattern[pi] != s[si] {
+			return nil, false
+		}
+		pi++
+		si++
+	}
+
+	if si != len(s) {
+		return nil, false
+	}
+	return spans, true
+}
+
+// PatternExplanation is a plain-English account of how a single .gitignore
+// line will be interpreted. See ExplainPattern.
+type PatternExplanation struct {
+	// Pattern is the input line as given.
+	Pattern string
+
+	// Valid is false for lines that produce no rule: blank lines, comments,
+	// and malformed patterns. Summary then says why.
+	Valid bool
+
+	// Negate, DirOnly, and Anchored mirror the parsed rule's flags.
+	Negate   bool
+	DirOnly  bool
+	Anchored bool
+
+	// Summary is a one-sentence description of what the pattern matches.
+	Summary string
+
+	// Gotchas lists behaviors that commonly surprise users of this pattern,
+	// such as a floating match or a ** that may match zero directories.
+	// Empty when nothing about the pattern is likely to surprise.
+	Gotchas []string
+}
+
+// ExplainPattern describes, in plain English, how a single .gitignore line
+// will match: whether it ignores or re-includes, whether it is anchored or
+// floats to any depth, whether it only matches directories, and what any **
+// means in its position. It is intended for educational tooling and editor
+// hovers; the explanation is derived from the same parser Match uses, with
+// git's default dialect.
+func ExplainPattern(pattern string) PatternExplanation {
+	exp := PatternExplanation{Pattern: pattern}
+
+	r, warning := parseLine(pattern, 1, "", "")
+	if r == nil {
+		switch {
+		case warning != nil:
+			exp.Summary = "Matches nothing: " + warning.Message + "."
+		case trimTrailingWhitespace(pattern) == "":
+			exp.Summary = "Blank line; matches nothing."
+		default:
+			exp.Summary = "Comment; matches nothing."
+		}
+		return exp
+	}
+
+	exp.Valid = true
+	exp.Negate = r.negate
+	exp.DirOnly = r.dirOnly
+	exp.Anchored = r.anchored
+
+	verb := "Ignores"
+	if r.negate {
+		verb = "Re-includes"
+	}
+
+	// The parser drops a redundant leading "**/"; explain the pattern as
+	// written.
+	body := strings.Repeat("**/", r.stripped) + patternBody(r)
+	target := "files and directories matching " + quote(body)
+	if r.dirOnly {
+		target = "directories matching " + quote(body) + " and everything inside them"
+	}
+	if n := len(r.segments); n > 1 && r.segments[n-1].doubleStar {
+		target = "everything inside " + quote(strings.TrimSuffix(body, "/**")) + " (but not the directory itself)"
+	}
+
+	where := "at any depth"
+	if r.anchored {
+		where = "relative to the .gitignore's directory only"
+	}
+	exp.Summary = verb + " " + target + ", " + where + "."
+
+	// Gotchas, in rough order of how often they bite.
+	if r.dirOnly {
+		exp.Gotchas = append(exp.Gotchas,
+			"the trailing slash means this never matches a regular file of the same name")
+	}
+	if !r.anchored && !strings.HasPrefix(body, "**/") {
+		exp.Gotchas = append(exp.Gotchas,
+			"without a slash this pattern floats: it matches in every subdirectory, not just the top level")
+	}
+	if r.anchored && !strings.HasPrefix(strings.TrimPrefix(pattern, "!"), "/") {
+		exp.Gotchas = append(exp.Gotchas,
+			"the interior slash anchors this pattern to the .gitignore's directory even without a leading slash")
+	}
+	for i, seg := range r.segments {
+		if seg.doubleStar && i > 0 && i < len(r.segments)-1 {
+			exp.Gotchas = append(exp.Gotchas,
+				"the ** in the middle matches zero or more directories, so it also matches with nothing in between")
+			break
+		}
+	}
+	if len(r.segments) > 0 && r.segments[0].value == "." {
+		exp.Gotchas = append(exp.Gotchas,
+			"git does not strip a leading ./ from patterns; this is matched literally and likely matches nothing")
+	}
+	if r.negate {
+		exp.Gotchas = append(exp.Gotchas,
+			"a negation cannot re-include a path whose parent directory is excluded")
+	}
+
+	return exp
+}
+
+// patternBody reconstructs the pattern text without the negation prefix,
+// leading slash, and trailing slash, for use in explanations.
+func patternBody(r *rule) string {
+	parts := make([]string, len(r.segments))
+	for i, seg := range r.segments {
+		if seg.doubleStar {
+			parts[i] = "**"
+		} else {
+			parts[i] = seg.value
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// quote wraps s in double quotes for display.
+func quote(s string) string {
+	return `"` + s + `"`
+}
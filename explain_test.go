@@ -0,0 +1,114 @@
+package ignore
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplainGlob(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		input     string
+		wantOK    bool
+		wantSpans []GlobSpan
+	}{
+		{"suffix wildcard", "*.log", "debug.log", true, []GlobSpan{{0, 0, 5}}},
+		{"prefix wildcard", "debug.*", "debug.log", true, []GlobSpan{{6, 6, 9}}},
+		{"middle wildcard", "app-*.js", "app-main.js", true, []GlobSpan{{4, 4, 8}}},
+		{"empty star", "foo*", "foo", true, []GlobSpan{{3, 3, 3}}},
+		{"question marks", "?.t?t", "a.txt", true, []GlobSpan{{0, 0, 1}, {3, 3, 4}}},
+		{"star and question", "*_?.go", "foo_a.go", true, []GlobSpan{{0, 0, 3}, {2, 4, 5}}},
+		{"consecutive stars", "**.log", "x.log", true, []GlobSpan{{0, 0, 1}}},
+		{"class makes no span", "[abc]*", "bz", true, []GlobSpan{{5, 1, 2}}},
+		{"escaped star is literal", "\\*.log", "*.log", true, nil},
+		{"literal only", "Makefile", "Makefile", true, nil},
+		{"no match", "*.log", "debug.txt", false, nil},
+		{"too short for ?", "a?", "a", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans, ok := ExplainGlob(tt.pattern, tt.input)
+			if ok != tt.wantOK {
+				t.Fatalf("ExplainGlob(%q, %q) ok = %v, want %v", tt.pattern, tt.input, ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(spans, tt.wantSpans) {
+				t.Errorf("ExplainGlob(%q, %q) spans = %v, want %v", tt.pattern, tt.input, spans, tt.wantSpans)
+			}
+		})
+	}
+}
+
+func TestExplainGlob_AgreesWithMatcher(t *testing.T) {
+	patterns := []string{"*.log", "a*b*c", "?at", "[!a]*", "foo\\?", "*"}
+	inputs := []string{"x.log", "abc", "aXbYc", "cat", "bat", "zed", "foo?", "foox", ""}
+
+	for _, p := range patterns {
+		seg := parseSegments(p)[0]
+		for _, in := range inputs {
+			_, got := ExplainGlob(p, in)
+			want := matchSingleSegment(seg, in, testCtx(DefaultMaxBacktrackIterations))
+			if got != want {
+				t.Errorf("ExplainGlob(%q, %q) = %v, matchSingleSegment = %v", p, in, got, want)
+			}
+		}
+	}
+}
+
+func TestExplainPattern(t *testing.T) {
+	tests := []struct {
+		pattern     string
+		wantValid   bool
+		wantSummary string
+		wantGotchas int
+		wantGotcha  string // substring expected in one of the gotchas
+	}{
+		{"", false, "Blank line; matches nothing.", 0, ""},
+		{"# comment", false, "Comment; matches nothing.", 0, ""},
+		{"!", false, "Matches nothing: pattern is empty after processing.", 0, ""},
+		{"/build/", true, `Ignores directories matching "build" and everything inside them, relative to the .gitignore's directory only.`, 1, "never matches a regular file"},
+		{"*.log", true, `Ignores files and directories matching "*.log", at any depth.`, 1, "floats"},
+		{"!important.log", true, `Re-includes files and directories matching "important.log", at any depth.`, 2, "cannot re-include"},
+		{"a/**/b", true, `Ignores files and directories matching "a/**/b", relative to the .gitignore's directory only.`, 2, "zero or more directories"},
+		{"**/logs", true, `Ignores files and directories matching "**/logs", at any depth.`, 0, ""},
+		{"logs/**", true, `Ignores everything inside "logs" (but not the directory itself), relative to the .gitignore's directory only.`, 1, "interior slash"},
+		{"/dist", true, `Ignores files and directories matching "dist", relative to the .gitignore's directory only.`, 0, ""},
+		{"./foo", true, `Ignores files and directories matching "./foo", relative to the .gitignore's directory only.`, 2, "does not strip a leading ./"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			exp := ExplainPattern(tt.pattern)
+			if exp.Valid != tt.wantValid {
+				t.Errorf("Valid = %v, want %v", exp.Valid, tt.wantValid)
+			}
+			if exp.Summary != tt.wantSummary {
+				t.Errorf("Summary = %q\nwant      %q", exp.Summary, tt.wantSummary)
+			}
+			if len(exp.Gotchas) != tt.wantGotchas {
+				t.Errorf("Gotchas = %q, want %d entries", exp.Gotchas, tt.wantGotchas)
+			}
+			if tt.wantGotcha != "" {
+				found := false
+				for _, g := range exp.Gotchas {
+					if strings.Contains(g, tt.wantGotcha) {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("Gotchas = %q, want one containing %q", exp.Gotchas, tt.wantGotcha)
+				}
+			}
+		})
+	}
+}
+
+func TestExplainPattern_Flags(t *testing.T) {
+	exp := ExplainPattern("!/logs/")
+	if !exp.Negate || !exp.DirOnly || !exp.Anchored {
+		t.Errorf("ExplainPattern(!/logs/) flags = negate:%v dirOnly:%v anchored:%v, want all true",
+			exp.Negate, exp.DirOnly, exp.Anchored)
+	}
+}
@@ -0,0 +1,216 @@
+package ignore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Dialect identifies an ignore-file syntax that ExportDialect can write.
+type Dialect int
+
+const (
+	// DialectGitignore is git's own syntax. Export is an identity
+	// translation, except that rules loaded under a basePath are rewritten
+	// relative to the root (a floating "*.log" from src/.gitignore becomes
+	// "src/**/*.log") and non-git options are spelled out in git syntax.
+	DialectGitignore Dialect = iota
+
+	// DialectDockerignore is the .dockerignore syntax used by Docker build
+	// contexts. Its patterns are always anchored at the context root, it
+	// has no directory-only patterns, and it trims leading whitespace and
+	// cleans "." and ".." out of every line.
+	DialectDockerignore
+)
+
+// String returns the conventional file name of the dialect.
+func (d Dialect) String() string {
+	switch d {
+	case DialectGitignore:
+		return ".gitignore"
+	case DialectDockerignore:
+		return ".dockerignore"
+	}
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+// ErrUntranslatable is wrapped by the errors ExportDialect returns for rules
+// that the target dialect cannot express with the same meaning.
+var ErrUntranslatable = errors.New("rule cannot be translated faithfully")
+
+// ExportDialect serializes the loaded rules, in evaluation order, as an
+// ignore file in dialect d, one pattern per line. Comments, blank lines, and
+// skipped lines from the original input are not reproduced.
+//
+// If any rule cannot be expressed in d with the same meaning, ExportDialect
+// returns nil and an error that joins one error per such rule, each naming
+// its line and pattern and wrapping ErrUntranslatable. For
+// DialectDockerignore that covers directory-only patterns ("build/"), "."
+// and ".." segments, and leading or trailing whitespace.
+//
+// Some differences cannot be detected per rule and are not reported.
+// Docker lets a negation re-include a file inside an excluded directory,
+// which git never does, and CaseInsensitive has no counterpart in either
+// syntax; the exported patterns are written as loaded.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) ExportDialect(d Dialect) ([]byte, error) {
+	if d != DialectGitignore && d != DialectDockerignore {
+		return nil, fmt.Errorf("exporting rules: unknown dialect %v", d)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var b bytes.Buffer
+	var errs []error
+	for i := range m.rules {
+		r := &m.rules[i]
+		line, err := exportRule(r, d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d %q: %w", r.line, r.pattern, err))
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return b.Bytes(), nil
+}
+
+// exportRule renders one rule in dialect d.
+func exportRule(r *rule, d Dialect) (string, error) {
+	if len(r.segments) == 0 {
+		// A degenerate rule has no segments to translate.
+		if d == DialectGitignore {
+			return r.pattern, nil
+		}
+		return "", fmt.Errorf("%w: pattern has no path segments", ErrUntranslatable)
+	}
+	text, leadingSlash := patternText(r)
+
+	// Split the text the way parseSegments does, so parts[i] lines up with
+	// r.segments[i] but keeps the original spelling (and case). Components
+	// stripped from the front are not part of the rule: a rebase's are
+	// gone, and a floating rule's redundant "**" are written back below.
+	parts := strings.Split(text, "/")
+	kept := parts[:0]
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	parts = kept[r.stripped:]
+
+	for i, seg := range r.segments {
+		switch {
+		case seg.minOne:
+			// git has no one-or-more **; "*/**" says the same thing.
+			parts[i] = "*/**"
+		case seg.doubleStar:
+			parts[i] = "**" // possibly written as a DoubleStarToken
+		case parts[i] == "**":
+			// A literal "**" under a DoubleStarToken is a plain wildcard,
+			// which git would read as any depth.
+			parts[i] = "*"
+		}
+		if d == DialectDockerignore {
+			if seg.value == "." || seg.value == ".." {
+				return "", fmt.Errorf("%w: dockerignore cleans %q segments out of patterns", ErrUntranslatable, seg.value)
+			}
+			parts[i] = caretClasses(parts[i])
+		}
+	}
+	body := strings.Join(parts, "/")
+	if !r.anchored {
+		body = strings.Repeat("**/", r.stripped) + body
+	}
+
+	var b strings.Builder
+	if r.negate {
+		b.WriteByte('!')
+	}
+
+	switch d {
+	case DialectGitignore:
+		switch {
+		case r.basePath != "" && r.anchored:
+			b.WriteString(r.basePath + "/")
+		case r.basePath != "" && !strings.HasPrefix(body, "**/"):
+			b.WriteString(r.basePath + "/**/")
+		case r.basePath != "":
+			b.WriteString(r.basePath + "/")
+		case r.anchored && (leadingSlash || len(parts) == 1 || r.segments[0].doubleStar):
+			b.WriteByte('/')
+		case !r.negate && body[0] == '#':
+			b.WriteByte('\\') // a literal '#' from a non-'#' CommentChar dialect
+		}
+		b.WriteString(body)
+		if r.dirOnly {
+			b.WriteByte('/')
+		}
+
+	case DialectDockerignore:
+		if r.dirOnly {
+			return "", fmt.Errorf("%w: dockerignore has no directory-only patterns", ErrUntranslatable)
+		}
+		if body[0] == ' ' || body[0] == '\t' || strings.HasSuffix(body, "\\ ") || strings.HasSuffix(body, "\\\t") {
+			return "", fmt.Errorf("%w: dockerignore trims whitespace around patterns", ErrUntranslatable)
+		}
+		if r.basePath != "" {
+			b.WriteString(r.basePath + "/")
+		}
+		// Docker patterns are always anchored; float with a leading **.
+		if !r.anchored && !strings.HasPrefix(body, "**/") {
+			b.WriteString("**/")
+		}
+		b.WriteString(body)
+	}
+	return b.String(), nil
+}
+
+// patternText returns r's pattern as written, minus the negation "!", the
+// anchoring "/", and the directory-only "/", with escapes intact. A trailing
+// space or tab kept by an escape ("foo\ ") is re-escaped, since the parser
+// removes the backslash. leadingSlash reports whether the text began with
+// "/".
+func patternText(r *rule) (text string, leadingSlash bool) {
+	text = r.pattern
+	if r.negate {
+		text = text[1:]
+	}
+	if r.dirOnly {
+		text = text[:len(text)-1]
+	}
+	if strings.HasPrefix(text, "/") {
+		text, leadingSlash = text[1:], true
+	}
+	if n := len(text); n > 0 && (text[n-1] == ' ' || text[n-1] == '\t') {
+		text = text[:n-1] + "\\" + text[n-1:]
+	}
+	return text, leadingSlash
+}
+
+// caretClasses rewrites negated character classes from "[!...]" to
+// "[^...]", the only negation Go's filepath.Match (used by Docker)
+// understands. Escaped brackets are left alone.
+func caretClasses(part string) string {
+	if !strings.Contains(part, "[!") {
+		return part
+	}
+	b := []byte(part)
+	for i := 0; i < len(b)-1; i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '[':
+			if b[i+1] == '!' {
+				b[i+1] = '^'
+			}
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,204 @@
+package ignore
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExportDialect_Gitignore(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("# comment\n*.log\n/build/\na/b\n!keep.log\nfoo\\ \n\\#notes\n"))
+	m.AddPatterns("src", []byte("*.tmp\n/gen/\n**/cache\n"))
+
+	out, err := m.ExportDialect(DialectGitignore)
+	if err != nil {
+		t.Fatalf("ExportDialect() error = %v", err)
+	}
+	want := "*.log\n/build/\na/b\n!keep.log\nfoo\\ \n\\#notes\nsrc/**/*.tmp\nsrc/gen/\nsrc/**/cache\n"
+	if string(out) != want {
+		t.Errorf("ExportDialect() =\n%s\nwant\n%s", out, want)
+	}
+
+	// The export must behave exactly like the original when loaded back.
+	back := New()
+	back.AddPatterns("", out)
+	paths := []struct {
+		path  string
+		isDir bool
+	}{
+		{"debug.log", false}, {"keep.log", false}, {"build", true}, {"x/build", true},
+		{"a/b", false}, {"x/a/b", false}, {"foo ", false}, {"foo", false},
+		{"#notes", false}, {"src/x.tmp", false}, {"src/d/x.tmp", false}, {"x.tmp", false},
+		{"src/gen", true}, {"src/d/gen", true}, {"src/a/cache", false}, {"cache", false},
+	}
+	for _, p := range paths {
+		if got, want := back.Match(p.path, p.isDir), m.Match(p.path, p.isDir); got != want {
+			t.Errorf("round trip: Match(%q, %v) = %v, original %v", p.path, p.isDir, got, want)
+		}
+	}
+}
+
+func TestExportDialect_GitignoreOptions(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CommentChar: ';', DoubleStarMinOne: true, CaseInsensitive: true})
+	m.AddPatterns("", []byte("; comment\n#literal\na/**/B\n"))
+
+	out, err := m.ExportDialect(DialectGitignore)
+	if err != nil {
+		t.Fatalf("ExportDialect() error = %v", err)
+	}
+	if want := "\\#literal\na/*/**/B\n"; string(out) != want {
+		t.Errorf("ExportDialect() = %q, want %q", out, want)
+	}
+}
+
+func TestExportDialect_DoubleStarToken(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{DoubleStarToken: "..."})
+	m.AddPatterns("", []byte("a/.../b\nx/**/y\n.../z\n"))
+
+	for _, d := range []Dialect{DialectGitignore, DialectDockerignore} {
+		out, err := m.ExportDialect(d)
+		if err != nil {
+			t.Fatalf("ExportDialect(%v) error = %v", d, err)
+		}
+		if want := "a/**/b\nx/*/y\n**/z\n"; string(out) != want {
+			t.Errorf("ExportDialect(%v) = %q, want %q", d, out, want)
+		}
+	}
+
+	out, _ := m.ExportDialect(DialectGitignore)
+	back := New()
+	back.AddPatterns("", out)
+	for _, p := range []string{"a/b", "a/q/r/b", "x/q/y", "x/q/r/y", "x/y", "d/z", "z"} {
+		if got, want := back.Match(p, false), m.Match(p, false); got != want {
+			t.Errorf("round trip: Match(%q) = %v, original %v", p, got, want)
+		}
+	}
+}
+
+func TestExportDialect_Rebased(t *testing.T) {
+	m := New()
+	m.AddPatternsRebased("proj", "x", []byte("proj/build/\n/proj/*.o\n*.log\n"))
+
+	out, err := m.ExportDialect(DialectGitignore)
+	if err != nil {
+		t.Fatalf("ExportDialect() error = %v", err)
+	}
+	if want := "x/build/\nx/*.o\nx/**/*.log\n"; string(out) != want {
+		t.Errorf("ExportDialect() = %q, want %q", out, want)
+	}
+
+	back := New()
+	back.AddPatterns("", out)
+	for _, p := range []struct {
+		path  string
+		isDir bool
+	}{
+		{"x/build", true}, {"x/proj/build", true}, {"x/a.o", false}, {"x/proj/a.o", false},
+		{"x/d/a.log", false}, {"build", true},
+	} {
+		if got, want := back.Match(p.path, p.isDir), m.Match(p.path, p.isDir); got != want {
+			t.Errorf("round trip: Match(%q, %v) = %v, original %v", p.path, p.isDir, got, want)
+		}
+	}
+
+	// The stripped components survive a binary round trip.
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	cached := New()
+	if err := cached.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if again, _ := cached.ExportDialect(DialectGitignore); string(again) != string(out) {
+		t.Errorf("ExportDialect() after a binary round trip = %q, want %q", again, out)
+	}
+}
+
+func TestExportDialect_Dockerignore(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n/dist\nsrc/*.go\n**/tmp\n!keep.log\n[!a]*.txt\n\\[!b]\n"))
+	m.AddPatterns("web", []byte("node_modules\n/out\n"))
+
+	out, err := m.ExportDialect(DialectDockerignore)
+	if err != nil {
+		t.Fatalf("ExportDialect() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"**/*.log",
+		"dist",
+		"src/*.go",
+		"**/tmp",
+		"!**/keep.log",
+		"**/[^a]*.txt",
+		"**/\\[!b]",
+		"web/**/node_modules",
+		"web/out",
+	}, "\n") + "\n"
+	if string(out) != want {
+		t.Errorf("ExportDialect() =\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestExportDialect_DroppedDoubleStar(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("**/[!a]x\n**/**/*.log\n"))
+	tests := []struct {
+		d    Dialect
+		want string
+	}{
+		{DialectGitignore, "**/[!a]x\n**/**/*.log\n"},
+		{DialectDockerignore, "**/[^a]x\n**/**/*.log\n"},
+	}
+	for _, tt := range tests {
+		out, err := m.ExportDialect(tt.d)
+		if err != nil {
+			t.Fatalf("ExportDialect(%v) error = %v", tt.d, err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("ExportDialect(%v) = %q, want %q", tt.d, out, tt.want)
+		}
+	}
+}
+
+func TestExportDialect_NoSegments(t *testing.T) {
+	// The parser now rejects "///", but a rule without segments, which
+	// UnmarshalBinary still accepts, must not be indexed into.
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+	m.rules = append(m.rules, rule{pattern: "///", anchored: true})
+
+	if out, err := m.ExportDialect(DialectGitignore); err != nil || string(out) != "*.log\n///\n" {
+		t.Errorf("ExportDialect(gitignore) = %q, %v; want the pattern as written", out, err)
+	}
+	if _, err := m.ExportDialect(DialectDockerignore); !errors.Is(err, ErrUntranslatable) {
+		t.Errorf("ExportDialect(dockerignore) error = %v, want ErrUntranslatable", err)
+	}
+}
+
+func TestExportDialect_DockerignoreErrors(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\nbuild/\n./foo\n"))
+
+	out, err := m.ExportDialect(DialectDockerignore)
+	if out != nil {
+		t.Errorf("ExportDialect() output = %q, want nil on error", out)
+	}
+	if !errors.Is(err, ErrUntranslatable) {
+		t.Fatalf("ExportDialect() error = %v, want ErrUntranslatable", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{`line 2 "build/"`, `line 3 "./foo"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %s", msg, want)
+		}
+	}
+	if strings.Contains(msg, "*.log") {
+		t.Errorf("error %q mentions a translatable rule", msg)
+	}
+
+	if _, err := m.ExportDialect(Dialect(99)); err == nil {
+		t.Error("ExportDialect(unknown) error = nil, want error")
+	}
+}
@@ -0,0 +1,83 @@
+package ignore
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrFallbackCycle is returned by SetFallback when the fallback would lead
+// back to the matcher itself.
+var ErrFallbackCycle = errors.New("fallback chain would form a cycle")
+
+// fallbackMu serializes SetFallback calls, so two calls cannot each pass
+// the cycle check and together close a loop.
+var fallbackMu sync.Mutex
+
+// SetFallback makes other the matcher consulted for paths m's own rules
+// do not match, for layered configuration such as repository rules first,
+// then organization defaults. Unlike Merge, the rule sets stay separate, so
+// each can be reloaded on its own.
+//
+// When no rule of m matches a path, other decides it with its own rules,
+// options, and fallback, receiving the path relative to m's root. If it
+// matches, or ignores the path through DefaultIgnored, its result is
+// returned with Fallback set; otherwise m's result stands, including m's
+// own DefaultIgnored. Paths under a force-tracked directory of m are
+// decided by m alone. A path m case-folds reaches other lowercased.
+//
+// Every method that decides paths consults the fallback, with its own
+// backtracking budget. A nil other removes the fallback. Sub views share
+// the fallback m has when they are created. If other is m or already falls
+// back to m, SetFallback returns ErrFallbackCycle and leaves m unchanged.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) SetFallback(other *Matcher) error {
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+
+	for f := other; f != nil; {
+		if f == m {
+			return ErrFallbackCycle
+		}
+		f.mu.RLock()
+		next := f.fallback
+		f.mu.RUnlock()
+		f = next
+	}
+
+	m.mu.Lock()
+	m.fallback = other
+	m.mu.Unlock()
+	return nil
+}
+
+// consultFallback decides a path m's rules did not match with m's fallback.
+// ok is false when there is no fallback or it leaves the path undecided.
+// ctx is m's match context; the fallback inherits its cancellation and
+// negation handling but not its budget. Callers must hold m.mu.
+func (m *Matcher) consultFallback(path string, isDir bool, ctx *matchContext) (result MatchResult, ok bool) {
+	f := m.fallback
+	if f == nil {
+		return MatchResult{}, false
+	}
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := f.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return MatchResult{}, false
+	}
+	fctx := f.newContext()
+	fctx.done = ctx.done
+	fctx.skipNegations = ctx.skipNegations
+
+	f.mu.RLock()
+	result = f.resolve(len(f.rules), path, pathSegments, isDir, &fctx)
+	f.mu.RUnlock()
+	if fctx.stopped {
+		ctx.stopped = true
+	}
+	if !result.Matched && !result.Ignored {
+		return MatchResult{}, false
+	}
+	result.Fallback = true
+	return result, true
+}
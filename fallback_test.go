@@ -0,0 +1,188 @@
+package ignore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetFallback(t *testing.T) {
+	org := New()
+	org.AddPatternsWithSource("", "org.gitignore", []byte("*.log\n*.tmp\n"))
+	repo := New()
+	repo.AddPatterns("", []byte("build/\n!keep.tmp\n"))
+	if err := repo.SetFallback(org); err != nil {
+		t.Fatalf("SetFallback: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		ignored  bool
+		fallback bool
+	}{
+		{"debug.log", false, true, true},  // repo has no rule; org ignores it
+		{"build", true, true, false},      // repo decides
+		{"keep.tmp", false, false, false}, // repo's negation matches first
+		{"main.go", false, false, false},  // neither matches
+	}
+	for _, tt := range tests {
+		r := repo.MatchWithReason(tt.path, tt.isDir)
+		if r.Ignored != tt.ignored || r.Fallback != tt.fallback {
+			t.Errorf("MatchWithReason(%q) = %+v, want Ignored %v, Fallback %v", tt.path, r, tt.ignored, tt.fallback)
+		}
+		if got := repo.Match(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.ignored)
+		}
+	}
+
+	r := repo.MatchWithReason("debug.log", false)
+	if r.Rule != "*.log" || r.Source != "org.gitignore" || r.Line != 1 {
+		t.Errorf("fallback result = %+v, want rule *.log from org.gitignore line 1", r)
+	}
+	if got, want := r.Describe(), `ignored by rule "*.log" at line 1 of org.gitignore (fallback)`; got != want {
+		t.Errorf("Describe() = %q, want %q", got, want)
+	}
+	if got := repo.MatchMany([]string{"debug.log", "main.go"}, nil); !got[0] || got[1] {
+		t.Errorf("MatchMany = %v, want [true false]", got)
+	}
+
+	// The rule sets stay independent: reloading org changes the outcome.
+	org.AddPatterns("", []byte("!debug.log\n"))
+	if repo.Match("debug.log", false) {
+		t.Error("a negation added to the fallback should re-include debug.log")
+	}
+
+	// A nil fallback removes it.
+	if err := repo.SetFallback(nil); err != nil {
+		t.Fatalf("SetFallback(nil): %v", err)
+	}
+	if repo.Match("x.tmp", false) {
+		t.Error("x.tmp should not be ignored once the fallback is removed")
+	}
+}
+
+func TestSetFallback_DefaultIgnored(t *testing.T) {
+	fallback := New()
+	fallback.AddPatterns("", []byte("*.log\n"))
+	m := NewWithOptions(MatcherOptions{DefaultIgnored: true})
+	m.AddPatterns("", []byte("!*.go\n"))
+	if err := m.SetFallback(fallback); err != nil {
+		t.Fatalf("SetFallback: %v", err)
+	}
+
+	if r := m.MatchWithReason("a.log", false); !r.Ignored || !r.Matched || !r.Fallback {
+		t.Errorf("a.log = %+v, want ignored by the fallback", r)
+	}
+	// Undecided by the fallback: m's own DefaultIgnored applies.
+	if r := m.MatchWithReason("a.txt", false); !r.Ignored || r.Matched || r.Fallback {
+		t.Errorf("a.txt = %+v, want ignored by DefaultIgnored", r)
+	}
+	if m.Match("a.go", false) {
+		t.Error("a.go is re-included by m's own rule")
+	}
+}
+
+func TestSetFallback_Cycle(t *testing.T) {
+	a, b, c := New(), New(), New()
+	if err := a.SetFallback(a); !errors.Is(err, ErrFallbackCycle) {
+		t.Errorf("a.SetFallback(a) = %v, want ErrFallbackCycle", err)
+	}
+	if err := a.SetFallback(b); err != nil {
+		t.Fatalf("a.SetFallback(b): %v", err)
+	}
+	if err := b.SetFallback(c); err != nil {
+		t.Fatalf("b.SetFallback(c): %v", err)
+	}
+	if err := c.SetFallback(a); !errors.Is(err, ErrFallbackCycle) {
+		t.Errorf("c.SetFallback(a) = %v, want ErrFallbackCycle", err)
+	}
+
+	// The rejected call left c without a fallback, so matching terminates.
+	c.AddPatterns("", []byte("*.o\n"))
+	if r := a.MatchWithReason("x.o", false); !r.Ignored || !r.Fallback {
+		t.Errorf("a.MatchWithReason(x.o) = %+v, want ignored through b and c", r)
+	}
+}
+
+func TestSetFallback_SnapshotAndSub(t *testing.T) {
+	fallback := New()
+	fallback.AddPatterns("", []byte("vendor/\n"))
+	m := New()
+	m.AddPatterns("", []byte("*.tmp\n"))
+	if err := m.SetFallback(fallback); err != nil {
+		t.Fatalf("SetFallback: %v", err)
+	}
+
+	snap := m.Snapshot()
+	sub := m.Sub("pkg")
+	fallback.AddPatterns("", []byte("*.bak\n"))
+
+	if !snap.Match("vendor", true) || snap.Match("a.bak", false) {
+		t.Error("a snapshot should freeze the fallback as it was")
+	}
+	if !sub.Match("vendor", true) || !sub.Match("a.bak", false) {
+		t.Error("a Sub view should consult the live fallback")
+	}
+}
+
+func TestSetFallback_Classify(t *testing.T) {
+	fallback := New()
+	fallback.AddPatterns("", []byte("build/\n*.log\n"))
+	m := New()
+	m.AddPatterns("", []byte("*.tmp\n"))
+	if err := m.SetFallback(fallback); err != nil {
+		t.Fatalf("SetFallback: %v", err)
+	}
+
+	tree := m.Classify(FileTree{IsDir: true, Children: []FileTree{
+		{Name: "build", IsDir: true, Children: []FileTree{{Name: "x.o"}}},
+		{Name: "a.log"},
+		{Name: "b.tmp"},
+		{Name: "main.go"},
+	}})
+	check := func(n ClassifiedTree) {
+		if want := m.MatchWithReason(n.Path, n.IsDir); n.Result.Ignored != want.Ignored || n.Result.Fallback != want.Fallback {
+			t.Errorf("Classify(%q) = %+v, want %+v", n.Path, n.Result, want)
+		}
+	}
+	for _, n := range tree.Children {
+		check(n)
+		for _, c := range n.Children {
+			check(c)
+		}
+	}
+	if build := tree.Children[0]; !build.Result.Ignored || !build.Result.Fallback || !build.Children[0].Result.Ignored {
+		t.Errorf("build = %+v, want it and build/x.o ignored by the fallback", build)
+	}
+}
+
+func TestSetFallback_MatchPrefix(t *testing.T) {
+	fallback := New()
+	fallback.AddPatterns("", []byte("build/\nsrc/gen/\n"))
+	m := New()
+	m.AddPatterns("", []byte("*.tmp\n"))
+	if err := m.SetFallback(fallback); err != nil {
+		t.Fatalf("SetFallback: %v", err)
+	}
+
+	tests := []struct {
+		segments []string
+		isDir    bool
+		want     bool
+	}{
+		{[]string{"build"}, true, true},
+		{[]string{"build", "x.o"}, false, true},
+		{[]string{"x.tmp"}, false, true}, // m's own rule
+		{[]string{"src"}, true, false},
+		{[]string{"main.go"}, false, false},
+	}
+	for _, tt := range tests {
+		if got := m.MatchPrefix(tt.segments, tt.isDir); got != tt.want {
+			t.Errorf("MatchPrefix(%q, %v) = %v, want %v", tt.segments, tt.isDir, got, tt.want)
+		}
+	}
+	// A Sub view hands the fallback the prefix relative to m's root.
+	if !m.Sub("src").MatchPrefix([]string{"gen"}, true) {
+		t.Error(`Sub("src").MatchPrefix(["gen"], true) = false, want true`)
+	}
+}
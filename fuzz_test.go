@@ -1,7 +1,11 @@
 package ignore
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"sync"
 	"testing"
 )
 
@@ -383,3 +387,89 @@ func FuzzConcurrentAccess(f *testing.F) {
 		}
 	})
 }
+
+// FuzzMatcherEndToEnd fuzzes the full pipeline — parsing under a fuzzed
+// option set, then matching — and checks invariants that must hold for any
+// rules and path: Match agrees with MatchWithReason and MatchMany, repeated
+// calls are deterministic, and concurrent calls agree.
+func FuzzMatcherEndToEnd(f *testing.F) {
+	paths := []string{"debug.log", "build/out.js", "node_modules/x/index.js", "src/main.go", "a/b/c.txt", ".env"}
+	fixtures, _ := filepath.Glob("testdata/*.gitignore")
+	for i, name := range fixtures {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(content, paths[i%len(paths)], i%2 == 0, uint8(i))
+	}
+	f.Add([]byte("*\n!*/\n!*.go\n"), "src/main.go", false, uint8(0x04))
+	f.Add([]byte("a/**/b\n"), "a/b", false, uint8(0x02))
+	f.Add([]byte("*.LOG\n"), "Debug.log", false, uint8(0x01))
+	f.Add([]byte("src/\n"), "src%2Fmain.go", false, uint8(0x08))
+	f.Add([]byte("///"), "0", false, uint8(0x2f)) // rejected: consecutive slashes
+
+	f.Fuzz(func(t *testing.T, content []byte, path string, isDir bool, flags uint8) {
+		m := NewWithOptions(MatcherOptions{
+			CaseInsensitive:       flags&0x01 != 0,
+			DoubleStarMinOne:      flags&0x02 != 0,
+			DefaultIgnored:        flags&0x04 != 0,
+			URLDecodePaths:        flags&0x08 != 0,
+			TrimLeadingWhitespace: flags&0x10 != 0,
+			Canonicalize:          flags&0x20 != 0,
+		})
+		m.AddPatterns("", content)
+		m.AddPatterns("src", content)
+
+		want := m.MatchWithReason(path, isDir)
+		if got := m.Match(path, isDir); got != want.Ignored {
+			t.Fatalf("Match(%q, %v) = %v, MatchWithReason().Ignored = %v", path, isDir, got, want.Ignored)
+		}
+		if again := m.MatchWithReason(path, isDir); !reflect.DeepEqual(again, want) {
+			t.Fatalf("MatchWithReason(%q, %v) not deterministic: %+v then %+v", path, isDir, want, again)
+		}
+		if many := m.MatchManyWithReason([]string{path}, []bool{isDir}); !reflect.DeepEqual(many[0], want) {
+			t.Fatalf("MatchManyWithReason(%q, %v) = %+v, MatchWithReason = %+v", path, isDir, many[0], want)
+		}
+
+		var wg sync.WaitGroup
+		results := make([]MatchResult, 4)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = m.MatchWithReason(path, isDir)
+			}(i)
+		}
+		wg.Wait()
+		for i, r := range results {
+			if !reflect.DeepEqual(r, want) {
+				t.Fatalf("concurrent MatchWithReason #%d (%q, %v) = %+v, want %+v", i, path, isDir, r, want)
+			}
+		}
+	})
+}
+
+// FuzzBinaryRoundTrip checks that UnmarshalBinary never panics on arbitrary
+// input and that a MarshalBinary round trip preserves match results.
+func FuzzBinaryRoundTrip(f *testing.F) {
+	f.Add([]byte("*.log\nbuild/\n!keep.log\na/**/b\n"), "a/x/b/keep.log", false)
+	f.Add([]byte("gign\x01\x00\x00\x00\x01\x01"), "x", true)
+
+	f.Fuzz(func(t *testing.T, content []byte, path string, isDir bool) {
+		_ = New().UnmarshalBinary(content)
+
+		m := New()
+		m.AddPatterns("src", content)
+		data, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: %v", err)
+		}
+		got := New()
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary of MarshalBinary output: %v", err)
+		}
+		if g, w := got.MatchWithReason(path, isDir), m.MatchWithReason(path, isDir); !reflect.DeepEqual(g, w) {
+			t.Fatalf("MatchWithReason(%q, %v) after round trip = %+v, want %+v", path, isDir, g, w)
+		}
+	})
+}
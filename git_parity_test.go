@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -185,6 +186,12 @@ func TestGitParity_EdgeCases(t *testing.T) {
 			gitignore: "*.min.js\n*.test.go\ntest_*.py\n",
 			paths:     []string{"app.min.js", "lib.min.js", "foo_test.go", "test_bar.py", "main.go"},
 		},
+		{
+			// Git keeps leading whitespace as part of the pattern.
+			name:      "leading space in pattern",
+			gitignore: " leading.txt\n",
+			paths:     []string{" leading.txt", "leading.txt"},
+		},
 		{
 			name:       "spaces in names",
 			gitignore:  "my file.txt\nmy dir/\n",
@@ -241,8 +248,16 @@ func compareWithGit(t *testing.T, gitignoreContent string, paths []string, creat
 		}
 	}
 
-	// Create test files (need to exist for git check-ignore to work properly)
+	// Create test files (need to exist for git check-ignore to work properly).
+	// A path listed in createDirs is checked as the directory itself.
+	isCreatedDir := make(map[string]bool, len(createDirs))
+	for _, dir := range createDirs {
+		isCreatedDir[dir] = true
+	}
 	for _, path := range paths {
+		if isCreatedDir[path] {
+			continue
+		}
 		fullPath := filepath.Join(tmpDir, path)
 		dir := filepath.Dir(fullPath)
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -424,3 +439,548 @@ func gitCheckIgnoreVerbose(repoDir, path string) gitCheckResult {
 	ignored := !strings.HasPrefix(rule, "!")
 	return gitCheckResult{ignored: ignored, rule: rule}
 }
+
+// TestGitParity_DoubleStarWithWildcards covers patterns that combine ** with
+// segment wildcards, at path depths 1–4.
+func TestGitParity_DoubleStarWithWildcards(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	tests := []struct {
+		name       string
+		gitignore  string
+		paths      []string
+		createDirs []string
+	}{
+		{
+			name:      "src/**/*.go",
+			gitignore: "src/**/*.go\n",
+			paths: []string{
+				"main.go", "src/main.go", "src/a/b.go", "src/a/b/c.go", "src/a/b/c/d.go",
+				"src/a/b/c.txt", "lib/src/a.go",
+			},
+		},
+		{
+			name:      "**/*_test.go",
+			gitignore: "**/*_test.go\n",
+			paths: []string{
+				"x_test.go", "a/x_test.go", "a/b/x_test.go", "a/b/c/x_test.go", "a/b/c/x.go",
+			},
+		},
+		{
+			name:      "a/**/b*/**/c",
+			gitignore: "a/**/b*/**/c\n",
+			paths: []string{
+				"a/b/c", "a/bx/c", "a/x/b/c", "a/x/b1/y/c", "a/x/y/b2/z/w/c", "a/x/c", "z/a/b/c",
+			},
+		},
+		{
+			name:      "**/test_*/**",
+			gitignore: "**/test_*/**\n",
+			paths: []string{
+				"test_a/f", "x/test_a/f", "x/y/test_a/z/f", "x/testa/f",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, tt.paths, tt.createDirs)
+		})
+	}
+}
+
+// TestGitParity_NegatedAnchored covers negations whose pattern itself starts
+// with a slash ("!/build"): the re-include must stay anchored to the
+// .gitignore's directory rather than floating.
+func TestGitParity_NegatedAnchored(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	tests := []struct {
+		name       string
+		gitignore  string
+		paths      []string
+		createDirs []string
+	}{
+		{
+			name:      "!/build after floating build",
+			gitignore: "build\n!/build\n",
+			paths:     []string{"build", "sub/build", "a/b/build"},
+		},
+		{
+			name:      "!/build/ after floating build/",
+			gitignore: "build/\n!/build/\n",
+			paths:     []string{"build/out.js", "sub/build/out.js"},
+		},
+		{
+			name:      "!/build/ does not re-include a file named build",
+			gitignore: "build\n!/build/\n",
+			paths:     []string{"build", "sub/build"},
+		},
+		{
+			name:      "!/src/keep.log",
+			gitignore: "*.log\n!/src/keep.log\n",
+			paths:     []string{"src/keep.log", "lib/src/keep.log", "keep.log", "src/other.log"},
+		},
+		{
+			name:      "!/build under ignored parent",
+			gitignore: "/*\n!/build\n",
+			paths:     []string{"build/out.js", "README"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, tt.paths, tt.createDirs)
+		})
+	}
+}
+
+// TestGitParity_DirectoryNegation covers directory patterns combined with
+// negation: a directory pattern ignores a directory's contents through the
+// directory itself, so a negation can only help if it re-includes that
+// directory, and a directory-only negation never re-includes files.
+func TestGitParity_DirectoryNegation(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	tests := []struct {
+		name       string
+		gitignore  string
+		paths      []string
+		createDirs []string
+	}{
+		{
+			name:      "negated file under ignored dir",
+			gitignore: "foo/\n!foo/bar\n",
+			paths:     []string{"foo/bar", "foo/baz", "x/foo/bar"},
+		},
+		{
+			name:      "negated dir before dir",
+			gitignore: "!foo/\nfoo/\n",
+			paths:     []string{"foo/x", "a/foo/x"},
+		},
+		{
+			name:      "dir then negated dir",
+			gitignore: "foo/\n!foo/\n",
+			paths:     []string{"foo/x", "a/foo/x"},
+		},
+		{
+			name:      "dir-only negation does not re-include files",
+			gitignore: "*.txt\n!a/\n",
+			paths:     []string{"a/x.txt", "b.txt", "a/b/y.txt"},
+		},
+		{
+			name:      "dir-only negation of ignored dir",
+			gitignore: "docs/\n!docs/\n",
+			paths:     []string{"docs/guide.md", "sub/docs/guide.md"},
+		},
+		{
+			name:      "dir-only then anchored plain negation",
+			gitignore: "build/\n!/build\n",
+			paths:     []string{"build/out.js", "sub/build/out.js"},
+		},
+		{
+			name:      "plain name ignores contents",
+			gitignore: "build\n",
+			paths:     []string{"build/out.js", "src/build/x/y.js"},
+		},
+		{
+			name:      "plain name negated file inside",
+			gitignore: "foo\n!foo/bar\n",
+			paths:     []string{"foo/bar", "foo/baz"},
+		},
+		{
+			name:      "anchored star ignores top-level dirs",
+			gitignore: "/*\n!/src/\n/src/*\n!/src/keep/\n",
+			paths:     []string{"README", "lib/a.go", "src/keep/a.go", "src/other/b.go", "src/c.go"},
+		},
+		{
+			name:      "allow-list by extension",
+			gitignore: "*\n!*/\n!*.go\n",
+			paths:     []string{"c.go", "c.txt", "a/b.go", "a/b.txt", "a/b/c.go"},
+		},
+		{
+			name:      "trailing double star with negated dir",
+			gitignore: "logs/**\n!logs/keep/\n",
+			paths:     []string{"logs/x", "logs/keep/a", "logs/keep/b/c"},
+		},
+		{
+			name:      "trailing double star dir-only",
+			gitignore: "logs/**/\n",
+			paths:     []string{"logs/a", "logs/d/a", "logs/d/e/a"},
+		},
+		{
+			name:      "middle double star dir with negation",
+			gitignore: "a/**/b/\n!a/x/b/\n",
+			paths:     []string{"a/x/b/f", "a/y/b/f", "a/b/f"},
+		},
+		{
+			name:      "negation of dir under ignored parent",
+			gitignore: "out/\n!out/keep/\n",
+			paths:     []string{"out/keep/a", "out/b"},
+		},
+		{
+			name:      "wildcard dir negated by name",
+			gitignore: "*.d/\n!keep.d\n",
+			paths:     []string{"keep.d/x", "other.d/x", "sub/keep.d/x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, tt.paths, tt.createDirs)
+		})
+	}
+}
+
+// TestGitParity_NegatedDirOnly compares negated directory-only patterns
+// ("!build/") with git: they re-include directories, and through them the
+// files no other rule ignores, but never a file of the same name.
+func TestGitParity_NegatedDirOnly(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	tests := []struct {
+		name      string
+		gitignore string
+		paths     []string
+	}{
+		{
+			name:      "dir then negated dir",
+			gitignore: "build/\n!build/\n",
+			paths:     []string{"build/out.js", "build/sub/a.o", "src/build/x"},
+		},
+		{
+			name:      "extension then negated dir",
+			gitignore: "*.o\n!debug/\n",
+			paths:     []string{"debug/a.o", "debug/a.c", "a.o", "src/debug/b.o"},
+		},
+		{
+			name:      "negated dir does not re-include a file of that name",
+			gitignore: "build\n!build/\n",
+			paths:     []string{"build", "src/build", "out/build/x"},
+		},
+		{
+			name:      "anchored negated dir",
+			gitignore: "build/\n!/build/\n",
+			paths:     []string{"build/a", "src/build/a"},
+		},
+		{
+			name:      "negated nested dir",
+			gitignore: "a/b/\n!a/b/\nc/\n",
+			paths:     []string{"a/b/x", "a/b/c/y", "x/a/b/z"},
+		},
+		{
+			name:      "wildcard negated dir",
+			gitignore: "tmp*/\n!tmp-keep*/\n",
+			paths:     []string{"tmp1/a", "tmp-keep/a", "src/tmp-keep2/a"},
+		},
+		{
+			name:      "negated double star dir",
+			gitignore: "**/cache/\n!src/**/cache/\n",
+			paths:     []string{"cache/a", "lib/cache/a", "src/cache/a", "src/x/y/cache/a"},
+		},
+		{
+			name:      "re-included dir ignored again below",
+			gitignore: "out/\n!out/\nout/*.log\n",
+			paths:     []string{"out/a.log", "out/a.txt", "out/sub/b.log"},
+		},
+		{
+			name:      "negated dir under ignored parent",
+			gitignore: "vendor/\n!vendor/keep/\n",
+			paths:     []string{"vendor/keep/a", "vendor/b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, tt.paths, nil)
+		})
+	}
+}
+
+// TestGitParity_NegatedAnchoredDirOnly covers the re-include-a-directory
+// idiom: a broad "*" or "/*" ignore followed by "!/logs/", "!logs/" or
+// "!/logs/**". Directories are checked as well as their contents, since the
+// negations re-include the directory but not necessarily what is inside it.
+func TestGitParity_NegatedAnchoredDirOnly(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	tests := []struct {
+		name      string
+		gitignore string
+		paths     []string
+		dirs      []string
+	}{
+		{
+			name:      "star then anchored negated dir",
+			gitignore: "*\n!/logs/\n",
+			paths:     []string{"logs", "logs/a.txt", "logs/sub", "logs/sub/b.txt", "src/logs", "src/logs/c.txt", "a.txt"},
+			dirs:      []string{"logs", "logs/sub", "src/logs"},
+		},
+		{
+			name:      "root star then anchored negated dir",
+			gitignore: "/*\n!/logs/\n",
+			paths:     []string{"logs", "logs/a.txt", "logs/sub", "logs/sub/b.txt", "src/logs", "src/logs/c.txt", "a.txt"},
+			dirs:      []string{"logs", "logs/sub", "src/logs"},
+		},
+		{
+			name:      "star then floating negated dir",
+			gitignore: "*\n!logs/\n",
+			paths:     []string{"logs", "logs/a.txt", "src/logs", "src/logs/c.txt"},
+			dirs:      []string{"logs", "src/logs"},
+		},
+		{
+			name:      "root star then floating negated dir",
+			gitignore: "/*\n!logs/\n",
+			paths:     []string{"logs", "logs/a.txt", "logs/logs", "logs/logs/b.txt", "src/logs", "src/logs/c.txt"},
+			dirs:      []string{"logs", "logs/logs", "src/logs"},
+		},
+		{
+			name:      "star then negated contents only",
+			gitignore: "*\n!/logs/**\n",
+			paths:     []string{"logs", "logs/a.txt", "logs/sub/b.txt"},
+			dirs:      []string{"logs"},
+		},
+		{
+			name:      "root star then negated contents only",
+			gitignore: "/*\n!/logs/**\n",
+			paths:     []string{"logs", "logs/a.txt", "logs/sub/b.txt"},
+			dirs:      []string{"logs"},
+		},
+		{
+			name:      "star then negated dir and contents",
+			gitignore: "*\n!/logs/\n!/logs/**\n",
+			paths:     []string{"logs", "logs/a.txt", "logs/sub", "logs/sub/b.txt", "src/logs/c.txt"},
+			dirs:      []string{"logs", "logs/sub"},
+		},
+		{
+			name:      "anchored negated dir does not re-include a file",
+			gitignore: "/*\n!/logs/\n",
+			paths:     []string{"logs", "src/logs"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, tt.paths, tt.dirs)
+		})
+	}
+}
+
+// TestGitParity_TrailingBackslash pins git's handling of a pattern ending in
+// a lone backslash: git check-ignore treats it as a malformed escape that
+// matches nothing — neither "foo" nor a file literally named "foo\" — and
+// says nothing about it. We skip such rules with a ParseWarning, so the
+// outcome matches git while the mistake is still surfaced. A trailing "\\"
+// is an escaped backslash and matches literally; a trailing "/" is removed
+// first, so "foo\/" is malformed too, and a negation is dropped the same way.
+func TestGitParity_TrailingBackslash(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("backslash is a path separator on Windows; literal-backslash filenames are not representable")
+	}
+
+	tests := []struct {
+		name       string
+		gitignore  string
+		paths      []string
+		createDirs []string
+	}{
+		{
+			name:      "lone trailing backslash",
+			gitignore: "foo\\\n",
+			paths:     []string{"foo", "foo\\"},
+		},
+		{
+			name:      "escaped trailing backslash",
+			gitignore: "bar\\\\\n",
+			paths:     []string{"bar", "bar\\"},
+		},
+		{
+			name:       "backslash before trailing slash",
+			gitignore:  "dir\\/\n",
+			paths:      []string{"dir\\/a", "dir/a"},
+			createDirs: []string{"dir\\", "dir"},
+		},
+		{
+			name:      "negation with trailing backslash",
+			gitignore: "*\n!x\\\n",
+			paths:     []string{"x", "x\\"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, tt.paths, tt.createDirs)
+		})
+	}
+}
+
+// TestGitParity_ConsecutiveSlashes covers patterns with an empty path
+// component, which git never matches, and query paths with repeated
+// slashes, which git collapses.
+func TestGitParity_ConsecutiveSlashes(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	paths := []string{"a/f", "a/b/f", "x/a/b/f", "a/c/b/f"}
+	createDirs := []string{"a/b", "x/a/b", "a/c/b"}
+	tests := []struct {
+		name      string
+		gitignore string
+		paths     []string
+	}{
+		{"double slash", "a//b\n", paths},
+		{"triple slash", "a///b\n", paths},
+		{"leading double slash", "//a\n", paths},
+		{"anchored double slash", "/a//b\n", paths},
+		{"trailing double slash", "a//\n", paths},
+		{"double star then double slash", "**//b\n", paths},
+		{"negated double slash", "a/\n!a//b\n", paths},
+		{"query path with double slashes", "a/b\n", []string{"a//b/f", "x//a//b/f", "a/b//f"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, tt.paths, createDirs)
+		})
+	}
+}
+
+func TestGitParity_LeadingDoubleStar(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	// A leading **/ is redundant before a single name, which floats anyway;
+	// each pattern must behave exactly as the plain name does.
+	paths := []string{"c/foo", "a/foo", "a/b/foo", "foo/x", "x.log", "a/x.log", "a/b/x.log", "build", "a/build", "a/build/out"}
+	createDirs := []string{"a/b", "a/build"}
+	tests := []struct {
+		name      string
+		gitignore string
+	}{
+		{"literal", "**/foo\n"},
+		{"plain literal", "foo\n"},
+		{"wildcard", "**/*.log\n"},
+		{"plain wildcard", "*.log\n"},
+		{"repeated", "**/**/foo\n"},
+		{"dir only", "**/build/\n"},
+		{"plain dir only", "build/\n"},
+		{"negated", "*.log\n!**/x.log\n"},
+		{"anchored", "/**/foo\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, paths, createDirs)
+		})
+	}
+}
+
+func TestGitParity_FloatingPatternMatchesDirectory(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	// A pattern without a slash matches any path component, so a directory
+	// named like a file pattern is excluded, and everything inside it with
+	// it. Git never descends into a.log, so a.log/inner.txt is ignored even
+	// though "inner.txt" itself does not match *.log.
+	paths := []string{"a.log", "a.log/inner.txt", "src/b.log/inner.txt", "src/b.log/deep/x.go", "src/c.txt", "d.log"}
+	createDirs := []string{"a.log", "src/b.log/deep"}
+	tests := []struct {
+		name      string
+		gitignore string
+	}{
+		{"wildcard", "*.log\n"},
+		{"literal", "a.log\n"},
+		{"dir only", "*.log/\n"},
+		{"negated leaf", "*.log\n!inner.txt\n"},
+		{"negated dir", "*.log\n!b.log\n"},
+		{"leading double star", "**/*.log\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, paths, createDirs)
+		})
+	}
+}
+
+func TestGitParity_Dotfiles(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	paths := []string{
+		".env", "src/.env", "a/b/.env", ".env.local", "src/.env.production",
+		".envrc", "env", "x.env", ".cache", ".cache/data", "src/.cache", "src/.cache/data", ".config/app/.env",
+	}
+	createDirs := []string{".cache", "src/.cache", ".config/app"}
+	tests := []struct {
+		name      string
+		gitignore string
+	}{
+		{"floating", ".env\n"},
+		{"anchored", "/.env\n"},
+		{"leading double star", "**/.env\n"},
+		{"suffix wildcard", ".env.*\n"},
+		{"prefix wildcard", ".env*\n"},
+		{"star dot name", "*.env\n"},
+		{"all dotfiles", ".*\n"},
+		{"all dotfiles but env", ".*\n!.env\n"},
+		{"dir only", ".cache/\n"},
+		{"anchored dir only", "/.cache/\n"},
+		{"nested anchored", "src/.env\n"},
+		{"dotfile inside ignored dot dir", ".config/\n!.config/app/.env\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, paths, createDirs)
+		})
+	}
+}
+
+func TestGitParity_FileAndDirPatterns(t *testing.T) {
+	if !gitAvailable() {
+		t.Skip("git not available")
+	}
+
+	// "foo" matches both files and directories, "foo/" only directories,
+	// so which rule wins depends on whether the path is a directory. A
+	// tree cannot hold foo as both, so the file sits in f/ and the
+	// directory in d/.
+	paths := []string{"f/foo", "d/foo", "d/foo/x", "foo"}
+	createDirs := []string{"d/foo"}
+	tests := []struct {
+		name      string
+		gitignore string
+	}{
+		{"both", "foo\nfoo/\n"},
+		{"both reversed", "foo/\nfoo\n"},
+		{"dir re-included", "foo\n!foo/\n"},
+		{"name re-included", "foo/\n!foo\n"},
+		{"file re-included after dir rule", "foo\nfoo/\n!foo\n"},
+		{"dir re-ignored", "foo\n!foo\nfoo/\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compareWithGit(t, tt.gitignore, paths, createDirs)
+		})
+	}
+}
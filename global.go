@@ -61,7 +61,7 @@ func LoadRepo(repoRoot string, opts MatcherOptions) (*Matcher, error) {
 		}
 		return nil, fmt.Errorf("reading %s: %w", rootIgnore, err)
 	}
-	m.addPatternsFromSource("", content, rootIgnore)
+	m.loadFile("", content, rootIgnore)
 	return m, nil
 }
 
@@ -104,7 +104,7 @@ func (m *Matcher) AddGlobalPatterns() error {
 		return fmt.Errorf("reading global gitignore %s: %w", path, err)
 	}
 
-	m.addPatternsFromSource("", content, path)
+	m.loadFile("", content, path)
 	return nil
 }
 
@@ -142,7 +142,7 @@ func (m *Matcher) AddSystemPatterns() error {
 		return fmt.Errorf("reading system gitignore %s: %w", path, err)
 	}
 
-	m.addPatternsFromSource("", content, path)
+	m.loadFile("", content, path)
 	return nil
 }
 
@@ -157,7 +157,8 @@ func (m *Matcher) AddSystemPatterns() error {
 // identifies it for any rule that originated here.
 //
 // If path does not exist or cannot be read, the error is returned wrapped.
-// Empty files add no rules.
+// Empty files add no rules; AddPatternsFileReport tells them apart from
+// files that add none for other reasons.
 //
 // Thread-safe: can be called concurrently with Match.
 func (m *Matcher) AddPatternsFromFile(basePath, path string) error {
@@ -165,7 +166,7 @@ func (m *Matcher) AddPatternsFromFile(basePath, path string) error {
 	if err != nil {
 		return fmt.Errorf("reading %s: %w", path, err)
 	}
-	m.addPatternsFromSource(basePath, content, path)
+	m.loadFile(basePath, content, path)
 	return nil
 }
 
@@ -199,7 +200,7 @@ func (m *Matcher) AddExcludePatterns(gitDir string) error {
 		return fmt.Errorf("reading %s: %w", path, err)
 	}
 
-	m.addPatternsFromSource("", content, path)
+	m.loadFile("", content, path)
 	return nil
 }
 
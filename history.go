@@ -0,0 +1,46 @@
+package ignore
+
+// HistoryEntry records one call that changed a Matcher's rules, as kept by
+// a Matcher created with MatcherOptions.RecordHistory.
+type HistoryEntry struct {
+	// BasePath is the normalized basePath the patterns were loaded under.
+	BasePath string
+
+	// Source is the source label or file path (see MatchResult.Source).
+	// Empty for AddPatterns and other calls without a source.
+	Source string
+
+	// Delta is the change in RuleCount made by the call. It is zero when
+	// every pattern was a comment, invalid, or over MaxPatterns.
+	Delta int
+
+	// FirstIndex is the RuleInfo.Index of the first rule the call added;
+	// the call contributed the rules FirstIndex through FirstIndex+Delta-1.
+	FirstIndex int
+
+	// Total is RuleCount after the call.
+	Total int
+}
+
+// History returns the log of every AddPatterns-family call made on m, in
+// the order the calls took effect, when the matcher was created with
+// MatcherOptions.RecordHistory. Each entry names the basePath and source of
+// one call and the range of rules it contributed, so the entry covering a
+// RuleInfo.Index identifies the call that added that rule. Returns nil if
+// the option is off or nothing has been loaded.
+//
+// The log only ever records additions: a Matcher has no call that removes
+// rules, so Delta is never negative. UnmarshalBinary, which replaces the
+// rules wholesale, is not recorded.
+//
+// The returned slice is a copy; mutating it does not affect the matcher.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) History() []HistoryEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.history) == 0 {
+		return nil
+	}
+	return append([]HistoryEntry(nil), m.history...)
+}
@@ -0,0 +1,67 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{RecordHistory: true})
+	m.AddPatterns("", []byte("*.log\nbuild/\n"))
+	m.AddPatternsWithSource("./src/", "src/.gitignore", []byte("gen/\n"))
+	m.AddPatterns("docs", []byte("# only a comment\n"))
+
+	path := filepath.Join(t.TempDir(), "exclude")
+	if err := os.WriteFile(path, []byte("*.tmp\n!keep.tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddPatternsFromFile("", path); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []HistoryEntry{
+		{BasePath: "", Source: "", Delta: 2, FirstIndex: 0, Total: 2},
+		{BasePath: "src", Source: "src/.gitignore", Delta: 1, FirstIndex: 2, Total: 3},
+		{BasePath: "docs", Source: "", Delta: 0, FirstIndex: 3, Total: 3},
+		{BasePath: "", Source: path, Delta: 2, FirstIndex: 3, Total: 5},
+	}
+	got := m.History()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("History() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// The entry covering a rule's index names the call that added it.
+	for _, info := range m.MatchingRules("keep.tmp", false) {
+		if e := got[3]; info.Index < e.FirstIndex || info.Index >= e.FirstIndex+e.Delta {
+			t.Errorf("rule %q (index %d) not covered by the file's entry %+v", info.Pattern, info.Index, e)
+		}
+	}
+
+	got[0].Delta = 99
+	if m.History()[0].Delta != 2 {
+		t.Error("History() should return a copy")
+	}
+}
+
+func TestHistory_AlwaysIgnore(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{RecordHistory: true, AlwaysIgnore: []string{".git/", "*.swp"}})
+	m.AddPatterns("", []byte("*.log\n"))
+
+	want := []HistoryEntry{
+		{BasePath: "", Source: "always-ignore", Delta: 2, FirstIndex: 0, Total: 2},
+		{BasePath: "", Source: "", Delta: 1, FirstIndex: 2, Total: 3},
+	}
+	if got := m.History(); !reflect.DeepEqual(got, want) {
+		t.Errorf("History() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+	if h := m.History(); h != nil {
+		t.Errorf("History() = %v, want nil without RecordHistory", h)
+	}
+}
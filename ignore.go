@@ -1,8 +1,12 @@
 package ignore
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -13,7 +17,9 @@ import (
 // it is a derived value (Matched && !Ignored) rather than stored state.
 type MatchResult struct {
 	// Rule is the pattern string of the last matching rule (empty if Matched == false).
-	// If multiple rules matched, this is the final decisive rule.
+	// If multiple rules matched, this is the final decisive rule. For a path
+	// inside an ignored directory it is the rule that ignored the outermost
+	// such directory, as git check-ignore reports it.
 	Rule string
 
 	// Source identifies which file or stream supplied the matching rule. It is
@@ -33,6 +39,12 @@ type MatchResult struct {
 	// Zero if Matched == false.
 	Line int
 
+	// RawLine is the text of that line exactly as written, before the
+	// trailing whitespace and any escapes are processed, for editors that
+	// highlight the bytes on the line. Only the line terminator and a
+	// leading byte order mark are gone. Empty if Matched == false.
+	RawLine string
+
 	// Ignored indicates the final decision: true if the path should be ignored.
 	// This accounts for negation rules.
 	Ignored bool
@@ -41,6 +53,30 @@ type MatchResult struct {
 	// If false, no rules matched and the path is not ignored (default behavior).
 	// If true, at least one rule matched (including negation rules); check Ignored for the final result.
 	Matched bool
+
+	// PathDepth is the number of segments in the normalized query path
+	// ("a/b/c.txt" has depth 3). It is populated whether or not a rule
+	// matched, so decisions can be histogrammed by depth. Zero for paths
+	// that normalize to empty or exceed MaxPathDepth.
+	PathDepth int
+
+	// Fallback reports that the decision came from the fallback matcher
+	// (see SetFallback) because no rule of this matcher matched the path.
+	// The other fields then describe the fallback's decision.
+	Fallback bool
+
+	// ContributingBasePaths lists, once each and in evaluation order, the
+	// basePaths of the rules that matched while the decision was made. With
+	// nested .gitignore files loaded parent first, the outermost scope comes
+	// first: a root "*.tmp" re-included by src's "!keep.tmp" gives
+	// ["", "src"] for src/keep.tmp. It is meant for debugging views that show
+	// every ignore file with a say in a path; BasePath names the one that
+	// decided it. For a path inside an ignored directory it lists the scopes
+	// that ignored that directory. Nil if Matched == false.
+	//
+	// The slice makes MatchResult non-comparable; compare results field by
+	// field or with reflect.DeepEqual.
+	ContributingBasePaths []string
 }
 
 // Negated reports whether the final matching rule was a negation rule (i.e.,
@@ -48,6 +84,62 @@ type MatchResult struct {
 // documents the derivation so callers do not have to compute it themselves.
 func (r MatchResult) Negated() bool { return r.Matched && !r.Ignored }
 
+// String returns a compact, stable representation for logs and test
+// failures, e.g. `ignored=true matched=true rule="*.log" line=2 base="src"`.
+// The rule and line appear only when a rule matched; base and source only
+// when non-empty. PathDepth is omitted.
+func (r MatchResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ignored=%t matched=%t", r.Ignored, r.Matched)
+	if r.Matched {
+		fmt.Fprintf(&b, " rule=%q line=%d", r.Rule, r.Line)
+	}
+	if r.BasePath != "" {
+		fmt.Fprintf(&b, " base=%q", r.BasePath)
+	}
+	if r.Source != "" {
+		fmt.Fprintf(&b, " source=%q", r.Source)
+	}
+	if r.Fallback {
+		b.WriteString(" fallback=true")
+	}
+	return b.String()
+}
+
+// Describe returns a one-line, human-readable account of the decision, for
+// tools that show why a path is or is not ignored without combining the
+// fields themselves:
+//
+//	ignored by rule "*.log" at line 2 of src/.gitignore
+//	re-included by negation "!keep.log" at line 5
+//	no rule matched
+//
+// The source is named only when known. A path ignored with no matching
+// rule (DefaultIgnored, or a predicate in MatchInfo) is described as
+// "ignored, but no rule matched". A decision made by the fallback matcher
+// ends in " (fallback)".
+func (r MatchResult) Describe() string {
+	var suffix string
+	if r.Fallback {
+		suffix = " (fallback)"
+	}
+	if !r.Matched {
+		if r.Ignored {
+			return "ignored, but no rule matched" + suffix
+		}
+		return "no rule matched"
+	}
+	verb := "ignored by rule "
+	if !r.Ignored {
+		verb = "re-included by negation "
+	}
+	where := " at line " + strconv.Itoa(r.Line)
+	if r.Source != "" {
+		where += " of " + r.Source
+	}
+	return verb + strconv.Quote(r.Rule) + where + suffix
+}
+
 // WarningHandler is called for each parse warning if set.
 // The warning includes BasePath; no separate basePath argument is provided.
 type WarningHandler func(warning ParseWarning)
@@ -96,9 +188,26 @@ type MatcherOptions struct {
 	//                    whose evaluation would exceed it.
 	MaxBacktrackIterations int
 
+	// MaxFloatStarts caps how many start positions a floating rule (one
+	// with no slash before its end, such as "build" or "*.d") tries along a
+	// path when checking whether it matches a directory above the path.
+	// Positions are tried outermost first; those beyond the cap are treated
+	// as no match, so a deep path whose only ignored ancestor lies past the
+	// cap is not ignored through it. The path itself is always checked.
+	// This bounds the work per rule for very deep, adversarial paths with
+	// negligible effect on real trees. 0 (the default) means unlimited.
+	MaxFloatStarts int
+
 	// CaseInsensitive enables case-insensitive matching.
 	// Default: false (case-sensitive, matching Git's default behavior).
 	// Note: This affects pattern matching only, not filesystem behavior.
+	//
+	// Patterns and their basePaths are lowercased once when added, and
+	// each query path once per call, so matching itself compares
+	// already-folded strings; RuleInfo and MatchResult report the basePath
+	// lowercased. Callers that can supply lowercase paths (for example to
+	// MatchMany) skip the path folding cost entirely: an all-lowercase ASCII
+	// path is not copied.
 	CaseInsensitive bool
 
 	// MaxPatterns limits the total number of rules a Matcher can hold.
@@ -109,6 +218,235 @@ type MatcherOptions struct {
 	// Lines exceeding this limit are skipped with a parse warning.
 	// Default: DefaultMaxPatternLength (4096). Set to -1 for unlimited.
 	MaxPatternLength int
+
+	// CommentChar is the byte that starts a comment line. It also becomes the
+	// escapable character: with CommentChar ';', a line starting with ";" is
+	// a comment and "\;foo" matches the literal name ";foo". When CommentChar
+	// is not '#', a leading "#" is an ordinary literal character.
+	// Default (0): '#', matching Git.
+	CommentChar byte
+
+	// TrimLeadingWhitespace strips leading spaces and tabs from every pattern
+	// line, for dialects where indentation is not significant. Git keeps
+	// leading whitespace as part of the pattern (" foo" only matches a name
+	// that starts with a space), so the default preserves it.
+	// Default: false (git-compatible).
+	TrimLeadingWhitespace bool
+
+	// AllowInlineComments strips a trailing comment from each pattern line:
+	// whitespace followed by CommentChar ends the pattern, so
+	// "*.log # debug logs" is the pattern "*.log". The comment character
+	// must follow a space or tab, so "foo#bar" is unchanged; escape it
+	// ("foo \#bar") to keep it in a pattern. Git has no inline comments and
+	// reads such a line as a pattern containing " # debug logs".
+	// Default: false (git-compatible).
+	AllowInlineComments bool
+
+	// Canonicalize records a canonical spelling of every pattern, exposed as
+	// RuleInfo.Canonical, so tools can detect rules that are written
+	// differently but mean the same thing ("a/**/**/b" and "a/**/b", "/a/b"
+	// and "a/b", "**/foo" and "foo"). The original text is still reported as
+	// the pattern. Matching is unaffected.
+	// Default: false (no canonical form is computed).
+	Canonicalize bool
+
+	// DoubleStarMinOne makes a "**" in the middle of a pattern match one or
+	// more directories instead of zero or more, so "a/**/b" matches "a/x/b"
+	// but not "a/b". This is NOT git behavior; it exists for dialects with
+	// stricter globbing. Leading ("**/foo") and trailing ("foo/**") double
+	// stars are unaffected.
+	// Default: false (git-compatible zero-or-more).
+	DoubleStarMinOne bool
+
+	// DoubleStarToken replaces "**" as the segment that spans any number of
+	// directories, for migrating configs from dialects that spell it
+	// differently: with "...", "a/.../b" means what "a/**/b" means in git,
+	// and "**" is then an ordinary wildcard segment, like "*". Only a whole
+	// segment equal to the token counts ("x..." is literal). A token
+	// containing "/" is ignored. RuleInfo.Canonical is written with "**".
+	// This is NOT git behavior.
+	// Default: "" ("**", git-compatible).
+	DoubleStarToken string
+
+	// AnchoredOnly treats every pattern as anchored to its basePath, as if
+	// it began with "/", overriding per-pattern anchoring: "foo" then
+	// matches only "foo" directly under the basePath, not "a/foo". It is
+	// meant for generated configs such as manifests that list exact paths,
+	// where it spares floating patterns the search for a match at every
+	// depth. A leading "**/" still matches at any depth. The rules from
+	// AlwaysIgnore are anchored too. This is NOT git behavior.
+	// Default: false (patterns without a slash float, as in git).
+	AnchoredOnly bool
+
+	// URLDecodePaths percent-decodes every query path once before it is
+	// normalized, for callers that receive URL-encoded paths: with it set,
+	// Match("src%2Fmain.go", false) is evaluated as "src/main.go". Decoding
+	// happens exactly once, so "%252F" yields a literal "%2F" and is never
+	// treated as a separator. Paths with malformed escapes are used as-is.
+	//
+	// Only string paths are decoded (Match, MatchWithReason, the MatchMany
+	// methods, MatchingRules, MatchPrefix); MatchComponents takes components
+	// verbatim. Patterns and basePaths are never decoded: a pattern "a%20b"
+	// still matches the literal name "a%20b", which no decoded path contains.
+	// Default: false.
+	URLDecodePaths bool
+
+	// Splitter, if set, replaces the built-in split of query paths on "/",
+	// for hierarchies other than file systems: with a Splitter that splits
+	// on ".", Match("com.example.Foo", false) is evaluated as the path
+	// "com/example/Foo". Patterns and basePaths are still written with "/",
+	// so the pattern "com/example/" ignores everything under com.example.
+	//
+	// Splitter is called with the normalized path (see SplitRawPaths) and
+	// must return its segments in order. Segments must not be empty; empty
+	// ones are dropped, and a "/" inside a segment splits it further. A
+	// segment containing a NUL byte makes the path match nothing. It
+	// applies wherever URLDecodePaths does, after decoding. Splitter must
+	// be safe for concurrent use. Default: nil (split on "/").
+	Splitter func(path string) []string
+
+	// SplitRawPaths passes query paths to Splitter exactly as given (after
+	// URLDecodePaths) instead of normalized first, for splitters whose
+	// hierarchy gives "\", "." or ".." a meaning of their own. The
+	// trailing "/" directory marker is still honored. Ignored without a
+	// Splitter. Default: false.
+	SplitRawPaths bool
+
+	// DefaultIgnored inverts the outcome for paths no rule matches: they are
+	// reported as ignored (Matched false, Ignored true) instead of kept, so
+	// the rule set acts as an allow-list. Negation rules are the allow
+	// entries ("!*.go" keeps Go files); ordinary rules can still ignore
+	// paths that a broader negation allowed.
+	//
+	// The default applies only to the final decision. It does not make
+	// unmatched directories count as ignored parents, so "!*.go" alone keeps
+	// src/main.go even though src itself is default-ignored. Walkers prune
+	// directories that Match reports as ignored, though, so allow the
+	// directories to descend into as well (e.g., "!*/" or "!src/").
+	// Paths that never match (empty, or resolving above the root) are still
+	// reported as not ignored.
+	// Default: false (gitignore semantics: unmatched paths are kept).
+	DefaultIgnored bool
+
+	// AlwaysIgnore lists directory names that NewWithOptions ignores before
+	// any pattern is added, such as the version-control directories in
+	// DefaultAlwaysIgnore, which git never tracks but gitignore files
+	// rarely mention. Each name becomes a directory-only rule at the root
+	// ("name/") that matches at any depth; a name with a "/" is anchored to
+	// the root instead. Names match literally, without globbing. The rules
+	// come first, so later negations can re-include a directory, and they
+	// report the source "always-ignore".
+	// Default: nil (nothing is ignored unless a pattern says so, as in git).
+	AlwaysIgnore []string
+
+	// AutoIgnoreGitDir ignores every directory named ".git", at any depth,
+	// and everything inside it, as git does: tools that scan what the
+	// matcher keeps then never descend into a repository's metadata, even
+	// under a broad pattern such as "*" followed by "!*/". Unlike a ".git/"
+	// rule or AlwaysIgnore, the decision is final: no negation re-includes
+	// a .git directory, since git never tracks one. Such paths are reported
+	// with Rule ".git/", Source "auto-ignore-git-dir", and Line 0; no rule
+	// is added, so RuleCount and Rules are unchanged. A file named ".git",
+	// such as a submodule's gitfile, is left to the patterns.
+	// Default: false (.git is only ignored if a pattern says so).
+	AutoIgnoreGitDir bool
+
+	// ReturnFirstNegationWins makes a matching negation final: once a "!"
+	// rule matches a path, later ignore rules can no longer re-ignore it, so
+	// "!keep.log" followed by "*.log" keeps keep.log. This is NOT git
+	// behavior (git's last match wins) and is meant for allow-list
+	// workflows where an allow entry must be absolute. Rules before the
+	// negation are still overridden by it as usual, and a path inside an
+	// ignored directory stays ignored: the lock applies to the path's own
+	// rules, not to its parents.
+	// Default: false (last match wins).
+	ReturnFirstNegationWins bool
+
+	// RejectLegacyLineEndings reports content that uses CRLF or CR-only
+	// line endings with a ParseWarning (one per kind, naming the first line
+	// that has it), for tooling that enforces LF. The content is still
+	// normalized and parsed as usual; only the warning is added.
+	// Default: false (legacy endings are normalized silently).
+	RejectLegacyLineEndings bool
+
+	// PreserveRawContent retains an exact copy of the bytes passed to every
+	// AddPatterns-family call, before BOM stripping and line-ending
+	// normalization, so tools that rewrite ignore files can reproduce their
+	// input byte-for-byte via RawPatterns. Matching is unaffected: rules are
+	// always parsed from the normalized content.
+	// Default: false (no copy is kept).
+	PreserveRawContent bool
+
+	// RecordHistory keeps a chronological log of every AddPatterns-family
+	// call (basePath, source, and the rules it added), for diagnosing
+	// matchers assembled from many sources. See History. The AlwaysIgnore
+	// rules, which NewWithOptions adds, appear as the first entry, with the
+	// source "always-ignore".
+	// Default: false (no log is kept).
+	RecordHistory bool
+
+	// StopAfterRules is a diagnostic knob: when positive, match decisions
+	// consult only the first StopAfterRules rules in load order, as if the
+	// rest had never been added. Bisecting on it isolates the rule that
+	// makes matching slow or decides a surprising path. It changes results
+	// and must not be set in production. MatchingRules, Lint, and
+	// ExportDialect still cover every rule.
+	// Default: 0 (all rules are consulted).
+	StopAfterRules int
+
+	// OnMatch, if set, is called with the result of every match decision made
+	// through Match, MatchWithReason, MatchContext, MatchComponents, the
+	// MatchMany batch methods, or Classify (and therefore by
+	// WalkDir and friends, which call Match). It is intended for metrics such
+	// as counting ignored vs kept paths or histogramming deciding rules.
+	//
+	// The hook runs after the decision is made, outside any matcher lock, on
+	// the calling goroutine. It may be invoked concurrently and must be safe
+	// for concurrent use; keep it cheap, since it sits on the match hot path.
+	OnMatch func(result MatchResult)
+
+	// TraceHistory, when positive, keeps the last TraceHistory match
+	// decisions, each with its path and full MatchResult, for RecentMatches
+	// to return: a flight recorder for explaining a surprising decision
+	// after the fact. Decisions are recorded wherever OnMatch would be
+	// called, under a lock of their own. Like OnMatch, tracing makes Match
+	// compute the full result rather than stopping at the first settled
+	// rule, and each decision costs a copy into the buffer.
+	// Default: 0 (no history is kept).
+	TraceHistory int
+}
+
+// RawContent is one unmodified pattern blob retained by a Matcher created
+// with MatcherOptions.PreserveRawContent.
+type RawContent struct {
+	BasePath string // normalized basePath the content was loaded under
+	Source   string // source label or file path (may be empty)
+	Content  []byte // exact bytes as supplied, including any BOM or CR
+}
+
+// parseOptions extracts the parsing-related settings from o.
+func (o *MatcherOptions) parseOptions() parseOptions {
+	return parseOptions{
+		maxPatternLength: o.MaxPatternLength,
+		commentChar:      o.CommentChar,
+		trimLeadingSpace: o.TrimLeadingWhitespace,
+		inlineComments:   o.AllowInlineComments,
+		canonicalize:     o.Canonicalize,
+		doubleStarMinOne: o.DoubleStarMinOne,
+		doubleStarToken:  o.doubleStarToken(),
+		anchoredOnly:     o.AnchoredOnly,
+		rejectLegacyEOL:  o.RejectLegacyLineEndings,
+		finalNegation:    o.ReturnFirstNegationWins,
+	}
+}
+
+// doubleStarToken returns DoubleStarToken when it differs from git's "**"
+// and can be a segment, or "" when patterns are parsed as in git.
+func (o *MatcherOptions) doubleStarToken() string {
+	if t := o.DoubleStarToken; t != "**" && !strings.Contains(t, "/") {
+		return t
+	}
+	return ""
 }
 
 // Matcher holds compiled gitignore rules.
@@ -123,7 +461,38 @@ type Matcher struct {
 	mu       sync.RWMutex
 	rules    []rule
 	warnings []ParseWarning
+	raw      []RawContent   // only populated with opts.PreserveRawContent
+	history  []HistoryEntry // only populated with opts.RecordHistory
 	opts     MatcherOptions
+	prefix   string // normalized basePath of a Sub view, prepended to every path
+
+	// encodingNotes holds the EncodingNotes of files loaded by the
+	// file-loading helpers.
+	encodingNotes []EncodingNote
+
+	// namespaces holds the rule sets added by AddPatternsNS, consulted
+	// only by MatchNS.
+	namespaces map[string][]rule
+
+	// forceTracked holds the directories added by AddForceTrackedDir, in
+	// the root matcher's namespace.
+	forceTracked []string
+
+	// predicates holds the functions added by AddPredicate, consulted by
+	// MatchInfo.
+	predicates []func(path string, info fs.FileInfo) bool
+
+	// fallback is the matcher set by SetFallback, consulted for paths no
+	// rule matches.
+	fallback *Matcher
+
+	// negateEnd is the index just past the last negation rule (0 if there
+	// are none). An ignoring match at or after it can never be overturned.
+	negateEnd int
+
+	// trace is the MatcherOptions.TraceHistory ring buffer, shared with the
+	// views of m; nil when no history is kept.
+	trace *matchTrace
 }
 
 // New creates an empty Matcher with default options.
@@ -133,6 +502,7 @@ func New() *Matcher {
 			MaxBacktrackIterations: DefaultMaxBacktrackIterations,
 			MaxPatterns:            DefaultMaxPatterns,
 			MaxPatternLength:       DefaultMaxPatternLength,
+			CommentChar:            '#',
 		},
 	}
 }
@@ -148,9 +518,93 @@ func NewWithOptions(opts MatcherOptions) *Matcher {
 	if opts.MaxPatternLength == 0 {
 		opts.MaxPatternLength = DefaultMaxPatternLength
 	}
+	if opts.CommentChar == 0 {
+		opts.CommentChar = '#'
+	}
+	m := &Matcher{
+		opts:  opts,
+		trace: newMatchTrace(opts.TraceHistory),
+	}
+	if len(opts.AlwaysIgnore) > 0 {
+		m.addPatternsFromSource("", alwaysIgnorePatterns(opts.AlwaysIgnore, opts.CommentChar), "always-ignore")
+	}
+	return m
+}
+
+// DefaultAlwaysIgnore returns the directories of common version-control
+// systems (.git, .hg, .svn), for MatcherOptions.AlwaysIgnore.
+func DefaultAlwaysIgnore() []string {
+	return []string{".git", ".hg", ".svn"}
+}
+
+// alwaysIgnorePatterns renders MatcherOptions.AlwaysIgnore as gitignore
+// content: one literal, directory-only pattern per name.
+func alwaysIgnorePatterns(names []string, commentChar byte) []byte {
+	var b strings.Builder
+	for _, name := range names {
+		p := SuggestPattern(name, true)
+		if p == "" {
+			continue
+		}
+		if p[0] == '/' && strings.Count(p, "/") == 2 {
+			// A single name: drop the anchor so it matches at any depth,
+			// escaping a first character that would start a negation or
+			// comment.
+			if p = p[1:]; p[0] == '!' || p[0] == commentChar {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteString(p)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// Sub returns a view of m scoped to basePath: paths passed to the view's
+// Match-family methods are taken as relative to basePath and evaluated
+// against m's rules as if basePath had been prepended, so
+// m.Sub("src").Match("main.go", false) equals m.Match("src/main.go", false).
+// Sub of a view nests ("src" then "lib" scopes to "src/lib").
+//
+// The view shares the rules m holds at the time of the call without copying
+// them; neither matcher modifies them. Patterns added to the view afterward
+// are scoped under basePath and stay in the view, and patterns later added
+// to m are not visible to it. Results such as MatchResult.BasePath and
+// PathDepth are reported in m's terms (relative to m's root).
+//
+// An empty basePath (after normalization) returns a view equivalent to m.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) Sub(basePath string) *Matcher {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Clip capacity so an append on either side never writes into storage
+	// the other can see.
+	rules := m.rules[:len(m.rules):len(m.rules)]
 	return &Matcher{
-		opts: opts,
+		rules:        rules,
+		opts:         m.opts,
+		prefix:       m.scope(basePath),
+		negateEnd:    m.negateEnd,
+		forceTracked: m.forceTracked[:len(m.forceTracked):len(m.forceTracked)],
+		predicates:   m.predicates[:len(m.predicates):len(m.predicates)],
+		fallback:     m.fallback,
+		trace:        m.trace,
+	}
+}
+
+// scope normalizes a caller-supplied basePath and places it under m's
+// prefix, yielding a basePath in the root matcher's namespace.
+func (m *Matcher) scope(basePath string) string {
+	basePath = strings.TrimPrefix(normalizePath(basePath), "/")
+	switch {
+	case m.prefix == "":
+		return basePath
+	case basePath == "":
+		return m.prefix
 	}
+	return m.prefix + "/" + basePath
 }
 
 // AddPatterns parses gitignore content and adds rules.
@@ -164,6 +618,11 @@ func NewWithOptions(opts MatcherOptions) *Matcher {
 // Both nil and empty content produce no rules. Nil content returns immediately
 // without acquiring locks; empty content goes through parsing (which yields nothing).
 //
+// Precedence is load order alone: when rules from different basePaths match
+// the same path, the one added last wins, however deep its basePath. Git
+// instead lets a deeper .gitignore override a shallower one, so add parent
+// directories before their children (as WalkDir does) to get git's result.
+//
 // Parse warnings are delivered through the configured WarningHandler (set via
 // MatcherOptions); if no handler is configured, warnings are appended to an
 // internal buffer accessible via Warnings().
@@ -192,60 +651,101 @@ func (m *Matcher) AddPatternsWithSource(basePath, source string, content []byte)
 	m.addPatternsFromSource(basePath, content, source)
 }
 
+// AddPatternsRebased adds content that was written for the directory
+// fromBase as if it lived at toBase instead — for example a subproject's
+// ignore rules vendored into a monorepo. Both are relative to the matcher's
+// root; fromBase "" means the content was a root .gitignore.
+//
+// Floating patterns ("*.log", "build/") keep matching at any depth, now
+// under toBase. Anchored patterns are relative to the root the content was
+// written for, so fromBase's components are stripped from their front: with
+// fromBase "proj", both "proj/build/" and "/*/build/" become "build/" anchored
+// at toBase. An anchored pattern that cannot match
+// anything inside fromBase — it names another directory, fromBase itself,
+// or one of its ancestors, or has a "**" among the stripped components — is
+// skipped with a parse warning. MatchResult.Rule still reports each
+// pattern as written.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) AddPatternsRebased(fromBase, toBase string, content []byte) {
+	m.loadPatterns(fromBase, toBase, content, "")
+}
+
 // addPatternsFromSource is the internal worker behind AddPatterns and
-// AddPatternsWithSource; helpers that know the originating file
-// (AddGlobalPatterns, AddExcludePatterns, AddSystemPatterns,
-// AddPatternsFromFile, LoadRepo, and the nested-gitignore discovery inside
-// WalkDir) call this directly so MatchResult.Source can identify which file
-// produced a rule.
+// AddPatternsWithSource; the nested-gitignore discovery inside WalkDir
+// calls this directly so MatchResult.Source can identify which file
+// produced a rule. The file-loading helpers (AddGlobalPatterns,
+// AddExcludePatterns, AddSystemPatterns, AddPatternsFromFile, LoadRepo) go
+// through loadFile, which also takes an EncodingNote.
 func (m *Matcher) addPatternsFromSource(basePath string, content []byte, source string) {
+	m.loadPatterns("", basePath, content, source)
+}
+
+// loadPatterns parses content and appends its rules scoped to basePath,
+// first rebasing anchored rules from fromBase when it is non-empty (see
+// AddPatternsRebased). It returns the number of rules added and the
+// warnings produced, which have also been delivered as usual.
+func (m *Matcher) loadPatterns(fromBase, basePath string, content []byte, source string) (int, []ParseWarning) {
 	if content == nil {
-		return
+		return 0, nil
 	}
 
 	// Normalize basePath once for consistent rule scoping and warning reporting.
 	normalizedBase := normalizePath(basePath)
+	if m.prefix != "" {
+		normalizedBase = m.scope(basePath)
+	}
 
 	// Parse rules (this doesn't need the lock)
-	newRules, parseWarnings := parseLines(normalizedBase, content, m.opts.MaxPatternLength, source)
+	newRules, parseWarnings := parseLines(normalizedBase, content, source, m.opts.parseOptions())
 
+	if fromBase = strings.TrimPrefix(normalizePath(fromBase), "/"); fromBase != "" {
+		newRules, parseWarnings = m.rebaseRules(newRules, parseWarnings, splitPath(fromBase), normalizedBase)
+	}
+
+	return m.commitRules(normalizedBase, source, newRules, parseWarnings, content)
+}
+
+// commitRules appends parsed rules scoped to normalizedBase, enforcing
+// MaxPatterns and recording history and raw content, then delivers
+// parseWarnings with any limit warning added. content is the raw input for
+// PreserveRawContent. It returns the number of rules added and the
+// warnings.
+func (m *Matcher) commitRules(normalizedBase, source string, newRules []rule, parseWarnings []ParseWarning, content []byte) (int, []ParseWarning) {
 	// Pre-lowercase pattern segment values for case-insensitive matching.
 	// This avoids calling strings.ToLower on every match call.
 	if m.opts.CaseInsensitive {
-		for i := range newRules {
-			for j := range newRules[i].segments {
-				seg := &newRules[i].segments[j]
-				if !seg.doubleStar {
-					seg.value = strings.ToLower(seg.value)
-				}
-			}
-		}
+		foldRules(newRules)
 	}
 
 	// Acquire write lock to add rules and capture handler ref
 	m.mu.Lock()
 
 	// Enforce max patterns limit
-	if m.opts.MaxPatterns >= 0 {
-		remaining := m.opts.MaxPatterns - len(m.rules)
-		if remaining <= 0 {
-			parseWarnings = append(parseWarnings, ParseWarning{
-				Pattern:  "",
-				Message:  "maximum pattern count reached, new patterns skipped",
-				BasePath: normalizedBase,
-			})
-			newRules = nil
-		} else if len(newRules) > remaining {
-			parseWarnings = append(parseWarnings, ParseWarning{
-				Pattern:  "",
-				Message:  "maximum pattern count reached, excess patterns truncated",
-				BasePath: normalizedBase,
-			})
-			newRules = newRules[:remaining]
+	newRules, parseWarnings = m.limitRules(len(m.rules), newRules, parseWarnings, normalizedBase, source)
+
+	for i := range newRules {
+		if newRules[i].negate {
+			m.negateEnd = len(m.rules) + i + 1
 		}
 	}
-
+	if m.opts.RecordHistory {
+		m.history = append(m.history, HistoryEntry{
+			BasePath:   normalizedBase,
+			Source:     source,
+			Delta:      len(newRules),
+			FirstIndex: len(m.rules),
+			Total:      len(m.rules) + len(newRules),
+		})
+	}
 	m.rules = append(m.rules, newRules...)
+	if m.opts.PreserveRawContent {
+		m.raw = append(m.raw, RawContent{
+			BasePath: normalizedBase,
+			Source:   source,
+			Content:  append([]byte(nil), content...),
+		})
+	}
 	handler := m.opts.WarningHandler
 	if handler == nil {
 		m.warnings = append(m.warnings, parseWarnings...)
@@ -261,6 +761,110 @@ func (m *Matcher) addPatternsFromSource(basePath string, content []byte, source
 			handler(w)
 		}
 	}
+	return len(newRules), parseWarnings
+}
+
+// limitRules enforces MaxPatterns on newRules about to join a rule set
+// holding have rules, truncating them and appending a warning to
+// parseWarnings if the limit is reached.
+func (m *Matcher) limitRules(have int, newRules []rule, parseWarnings []ParseWarning, normalizedBase, source string) ([]rule, []ParseWarning) {
+	if m.opts.MaxPatterns < 0 {
+		return newRules, parseWarnings
+	}
+	remaining := m.opts.MaxPatterns - have
+	if remaining <= 0 {
+		parseWarnings = append(parseWarnings, ParseWarning{
+			Pattern:  "",
+			Message:  "maximum pattern count reached, new patterns skipped",
+			BasePath: normalizedBase,
+			Source:   source,
+		})
+		newRules = nil
+	} else if len(newRules) > remaining {
+		parseWarnings = append(parseWarnings, ParseWarning{
+			Pattern:  "",
+			Message:  "maximum pattern count reached, excess patterns truncated",
+			BasePath: normalizedBase,
+			Source:   source,
+		})
+		newRules = newRules[:remaining]
+	}
+	return newRules, parseWarnings
+}
+
+// foldRules lowercases the segment values and basePaths of rules in place
+// for a case-insensitive matcher, which lowercases query paths.
+func foldRules(rules []rule) {
+	for i := range rules {
+		if base := rules[i].basePath; base != "" {
+			rules[i].basePath = strings.ToLower(base)
+			rules[i].basePathSlash = rules[i].basePath + "/"
+		}
+		for j := range rules[i].segments {
+			seg := &rules[i].segments[j]
+			if !seg.doubleStar {
+				seg.value = strings.ToLower(seg.value)
+			}
+		}
+	}
+}
+
+// rebaseRules strips the fromSegs components off the anchored rules in
+// rules (see AddPatternsRebased), dropping those that cannot match inside
+// them with a warning appended to warnings. It runs before case folding, so
+// it folds the compared segments itself.
+func (m *Matcher) rebaseRules(rules []rule, warnings []ParseWarning, fromSegs []string, basePath string) ([]rule, []ParseWarning) {
+	ctx := m.newContext()
+	kept := rules[:0]
+	for _, r := range rules {
+		if !r.anchored {
+			kept = append(kept, r)
+			continue
+		}
+
+		problem := ""
+		for k, comp := range fromSegs {
+			var seg segment
+			if k < len(r.segments) {
+				seg = r.segments[k]
+			}
+			if m.opts.CaseInsensitive {
+				seg.value, comp = strings.ToLower(seg.value), strings.ToLower(comp)
+			}
+			switch {
+			case k >= len(r.segments):
+				problem = "pattern matches the rebased directory or one of its ancestors, skipped"
+			case seg.doubleStar:
+				problem = "** cannot be rebased past the stripped directory, skipped"
+			case !matchSingleSegment(seg, comp, &ctx):
+				problem = "pattern does not apply under the rebased directory, skipped"
+			}
+			if problem != "" {
+				break
+			}
+		}
+		if problem == "" && len(r.segments) == len(fromSegs) {
+			problem = "pattern matches the rebased directory or one of its ancestors, skipped"
+		}
+		if problem != "" {
+			warnings = append(warnings, ParseWarning{
+				Line:     r.line,
+				Pattern:  r.pattern,
+				Message:  problem,
+				BasePath: basePath,
+				Source:   r.source,
+			})
+			continue
+		}
+
+		r.segments = r.segments[len(fromSegs):]
+		r.stripped += len(fromSegs)
+		if m.opts.Canonicalize {
+			r.canonical = canonicalPattern(&r, m.opts.CommentChar)
+		}
+		kept = append(kept, r)
+	}
+	return kept, warnings
 }
 
 // AddPatternsReader reads gitignore content from r and calls AddPatterns.
@@ -298,16 +902,56 @@ func (m *Matcher) Warnings() []ParseWarning {
 	return result
 }
 
+// RawPatterns returns the unmodified content of every AddPatterns-family
+// call, in load order, when the matcher was created with
+// MatcherOptions.PreserveRawContent. Writing the Content fields back out
+// reproduces the original input byte-for-byte, BOM and line endings
+// included. Returns nil if the option is off or nothing has been loaded.
+//
+// The returned slices are copies; mutating them does not affect the matcher.
+func (m *Matcher) RawPatterns() []RawContent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.raw) == 0 {
+		return nil
+	}
+	result := make([]RawContent, len(m.raw))
+	for i, rc := range m.raw {
+		rc.Content = append([]byte(nil), rc.Content...)
+		result[i] = rc
+	}
+	return result
+}
+
 // Match returns true if the path should be ignored.
 // path should be relative to repository root using forward slashes.
 // On Windows, backslashes are automatically normalized to forward slashes.
 // On Linux/macOS, backslashes are treated as literal filename characters
 // (matching Git's behavior).
-// isDir indicates whether the path is a directory.
+// isDir indicates whether the path is a directory. A path ending in a
+// separator ("build/") is always treated as a directory, whatever isDir says;
+// this applies to every method that takes a path string. isDir describes
+// the last segment only: the segments before it are always directories, so
+// "build/" ignores "build/app.o" even when isDir is wrong, but not a file
+// named "build" (see MatchEither).
+//
+// Because only the decision is returned, Match stops at the first ignoring
+// rule that no later negation could overturn, which makes it cheaper than
+// MatchWithReason on large rule sets whose negations come early.
 // Thread-safe: can be called concurrently.
 func (m *Matcher) Match(path string, isDir bool) bool {
-	result := m.MatchWithReason(path, isDir)
-	return result.Ignored
+	if m.observing() {
+		return m.MatchWithReason(path, isDir).Ignored
+	}
+	// Only the decision is needed, so evaluation may stop at the first
+	// ignoring rule past the last negation (see evaluateRules).
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return false
+	}
+	return m.evaluate(path, pathSegments, isDir, true).Ignored
 }
 
 // MatchWithReason returns detailed information about why a path matches.
@@ -319,57 +963,657 @@ func (m *Matcher) Match(path string, isDir bool) bool {
 //   - Matched == true, Ignored == true: Path is ignored by Rule
 //   - Matched == true, Ignored == false: Path was ignored but re-included by negation Rule
 func (m *Matcher) MatchWithReason(path string, isDir bool) MatchResult {
-	// Normalize path
-	path = normalizePath(path)
-	if path == "" {
-		return MatchResult{Ignored: false, Matched: false}
+	result := m.matchWithReason(path, isDir)
+	m.report(path, isDir, result)
+	return result
+}
+
+// MatchContext is MatchWithReason with a wall-clock bound: it returns
+// ctx.Err() if ctx is canceled or its deadline passes before the decision is
+// made. The context is checked before matching starts and then every few
+// dozen rules and backtracking steps, so a canceled match returns promptly
+// even on large rule sets; a zero MatchResult accompanies the error.
+//
+// MaxBacktrackIterations still applies and bounds the work done; the context
+// additionally bounds the time, for callers that must not stall under GC
+// pauses or scheduling delays. OnMatch is only called when a result is
+// returned.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchContext(ctx context.Context, path string, isDir bool) (MatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return MatchResult{}, err
 	}
 
+	var result MatchResult
+	var segBuf [32]string
+	if p, pathSegments, dir, ok := m.preparePath(path, isDir, segBuf[:0]); ok {
+		mc := m.newContext()
+		mc.done = ctx.Done()
+
+		m.mu.RLock()
+		result = m.resolve(len(m.rules), p, pathSegments, dir, &mc)
+		m.mu.RUnlock()
+
+		if mc.stopped {
+			return MatchResult{}, ctx.Err()
+		}
+	}
+
+	m.report(path, isDir, result)
+	return result, nil
+}
+
+// matchWithReason is MatchWithReason without the OnMatch hook.
+func (m *Matcher) matchWithReason(path string, isDir bool) MatchResult {
 	// opts is fixed at construction (see Matcher.opts) and safe to read
 	// without holding mu. Doing the case-insensitive lowering and the
 	// backtrack-context setup outside the read lock keeps the critical
 	// section as tight as possible.
 	var segBuf [32]string
-	pathSegments := splitPathBuf(path, segBuf[:0])
-
-	// Defensive: paths past MaxPathDepth short-circuit. The parent-excluded
-	// negation walk is inherently O(M·N²), so without this cap a fuzzer or
-	// malicious caller can construct a path that pegs CPU for minutes.
-	// Realistic paths are nowhere near this limit; see MaxPathDepth's docs.
-	if len(pathSegments) > MaxPathDepth {
+	path, pathSegments, isDir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
 		return MatchResult{Ignored: false, Matched: false}
 	}
 
-	// Pre-lowercase path and segments once for case-insensitive matching,
-	// instead of lowering per-segment per-rule in matchSingleSegment.
-	// Re-split after lowering so segments point into the lowered string (1 alloc vs N+1).
+	return m.evaluate(path, pathSegments, isDir, false)
+}
+
+// MatchIgnoringNegations is MatchWithReason with every negation rule left
+// out: it reports whether path would be ignored if no "!" rule re-included
+// anything, and by which rule. Comparing it with MatchWithReason explains a
+// re-inclusion — with "*.log" and "!important.log", important.log is
+// ignored here by "*.log" but kept by MatchWithReason.
+//
+// Everything else is as in MatchWithReason, including directory
+// exclusion, force-tracked directories, and DefaultIgnored. OnMatch is not
+// called, since this is not the matcher's decision.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchIgnoringNegations(path string, isDir bool) MatchResult {
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return MatchResult{}
+	}
+	ctx := m.newContext()
+	ctx.skipNegations = true
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.resolve(len(m.rules), path, pathSegments, isDir, &ctx)
+}
+
+// MatchWithoutRule is MatchWithReason as if the rule at ruleIndex (its
+// RuleInfo.Index) had never been added, so an editor can show what deleting
+// a line would change: with "*.log" and "!keep.log" at indexes 0 and 1,
+// MatchWithoutRule("keep.log", false, 1) reports keep.log ignored by
+// "*.log". An index out of range removes nothing.
+//
+// Everything else is as in MatchWithReason. OnMatch is not called, since
+// this is not the matcher's decision.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchWithoutRule(path string, isDir bool, ruleIndex int) MatchResult {
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return MatchResult{}
+	}
+	ctx := m.newContext()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ruleIndex < 0 || ruleIndex >= len(m.rules) {
+		return m.resolve(len(m.rules), path, pathSegments, isDir, &ctx)
+	}
+	rules := make([]rule, 0, len(m.rules)-1)
+	rules = append(rules, m.rules[:ruleIndex]...)
+	rules = append(rules, m.rules[ruleIndex+1:]...)
+	without := &Matcher{
+		rules:        rules,
+		opts:         m.opts,
+		prefix:       m.prefix,
+		forceTracked: m.forceTracked,
+		fallback:     m.fallback,
+	}
+	return without.resolve(len(without.rules), path, pathSegments, isDir, &ctx)
+}
+
+// MatchSafe is MatchWithReason for untrusted paths that must stay inside
+// the tree. ok is false, with a zero MatchResult, when path climbs above the
+// root once "." and ".." are resolved ("../outside/x", "a/../../x", and also
+// "/../x", which Match would resolve to "/x"), or when it names nothing
+// inside the tree (empty, or the root itself). Otherwise ok is true and the
+// result is that of MatchWithReason on the resolved path, so "a/../b" is
+// decided by b's rules. For a Sub view, the root is the view's basePath.
+//
+// OnMatch is called only when ok is true.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchSafe(path string, isDir bool) (result MatchResult, ok bool) {
+	decoded := path
+	if m.opts.URLDecodePaths {
+		decoded = decodePath(path)
+	}
+	if escapesRoot(decoded) {
+		return MatchResult{}, false
+	}
+
+	var segBuf [32]string
+	p, pathSegments, dir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return MatchResult{}, false
+	}
+	result = m.evaluate(p, pathSegments, dir, false)
+	m.report(path, isDir, result)
+	return result, true
+}
+
+// MatchNative is MatchSafe for a native, possibly absolute, path: it makes
+// path relative to root, the directory the matcher's rules are rooted at,
+// before matching. On Windows it accepts the forms the OS and its APIs
+// produce — drive paths ("C:\\proj\\src\\x.log"), extended-length paths
+// ("\\\\?\\C:\\proj\\..."), and UNC shares ("\\\\server\\share\\proj\\...",
+// "\\\\?\\UNC\\server\\share\\...") — comparing drive letters, share names
+// and root case-insensitively. Elsewhere paths are slash-separated and
+// compared exactly.
+//
+// A relative path is taken as already relative to root. ok is false, with
+// a zero MatchResult, when an absolute path is not under root (or root is
+// empty), or for any path MatchSafe rejects. A trailing separator marks
+// path as a directory, as in Match.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchNative(root, path string, isDir bool) (result MatchResult, ok bool) {
+	windows := runtime.GOOS == "windows"
+	if hasTrailingSeparator(path) {
+		isDir = true
+	}
+	rel, ok := nativeRelPath(root, path, windows)
+	if !ok {
+		return MatchResult{}, false
+	}
+	return m.MatchSafe(rel, isDir)
+}
+
+// MatchEither is MatchWithReason for callers that cannot tell whether path
+// is a file or a directory: path is reported as ignored if it would be
+// ignored as either one, so "build" is ignored by "build/". This errs toward
+// ignoring, which suits pruning without a stat call per path.
+//
+// The result is the file decision when that ignores the path, otherwise
+// the directory decision when that does, and otherwise the file decision
+// (not ignored). A trailing separator still marks path as a directory.
+// OnMatch, if configured, is called once with the result.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchEither(path string) MatchResult {
+	var result MatchResult
+	var segBuf [32]string
+	p, pathSegments, isDir, ok := m.preparePath(path, false, segBuf[:0])
+	if ok {
+		ctx := m.newContext()
+		m.mu.RLock()
+		result = m.resolve(len(m.rules), p, pathSegments, isDir, &ctx)
+		if !result.Ignored && !isDir {
+			ctx = m.newContext()
+			if dir := m.resolve(len(m.rules), p, pathSegments, true, &ctx); dir.Ignored {
+				result = dir
+			}
+		}
+		m.mu.RUnlock()
+	}
+	m.report(path, false, result)
+	return result
+}
+
+// MatchCase is MatchWithReason with MatcherOptions.CaseInsensitive
+// overridden for this call, for interactive tools that check a user-typed
+// name case-insensitively against an otherwise case-sensitive matcher.
+// Everything else, including force-tracked directories, the fallback and
+// OnMatch, behaves as in MatchWithReason.
+//
+// A case-sensitive matcher folds a copy of its rules for each
+// case-insensitive call, so prefer a CaseInsensitive matcher for many such
+// queries. A CaseInsensitive matcher stores its rules lowercased and cannot
+// unfold them, so for it caseInsensitive false has no effect.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchCase(path string, isDir bool, caseInsensitive bool) MatchResult {
+	if !caseInsensitive || m.opts.CaseInsensitive {
+		return m.MatchWithReason(path, isDir)
+	}
+	return m.folded().MatchWithReason(path, isDir)
+}
+
+// folded returns a CaseInsensitive copy of a case-sensitive m, with its
+// rules and force-tracked directories lowercased, for MatchCase.
+func (m *Matcher) folded() *Matcher {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rules := append([]rule(nil), m.rules...)
+	for i := range rules {
+		rules[i].segments = append([]segment(nil), rules[i].segments...)
+	}
+	foldRules(rules)
+	forceTracked := make([]string, len(m.forceTracked))
+	for i, dir := range m.forceTracked {
+		forceTracked[i] = strings.ToLower(dir)
+	}
+	f := &Matcher{
+		rules:        rules,
+		opts:         m.opts,
+		prefix:       m.prefix,
+		negateEnd:    m.negateEnd,
+		forceTracked: forceTracked,
+		predicates:   m.predicates[:len(m.predicates):len(m.predicates)],
+		fallback:     m.fallback,
+		trace:        m.trace,
+	}
+	f.opts.CaseInsensitive = true
+	return f
+}
+
+// MatchComponents is the lowest-level match entry point for walkers that
+// already hold a path as separate components (for example, one name per
+// directory level). It reports whether the path should be ignored, exactly
+// as Match would for the components joined with "/", without the caller
+// having to build that string first.
+//
+// Components are cleaned the way Match normalizes a path: empty and "."
+// components are dropped, ".." removes the preceding component (a path that
+// climbs above the root never matches). A component that itself contains
+// a separator — '/' everywhere, or '\\' on Windows — is split, so
+// MatchComponents([]string{"a", "b/c"}, false) equals Match("a/b/c", false).
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchComponents(components []string, isDir bool) bool {
+	var segBuf [32]string
+	var result MatchResult
+	if path, pathSegments, ok := m.prepareComponents(components, segBuf[:0]); ok {
+		result = m.evaluate(path, pathSegments, isDir, !m.observing())
+	}
+	if m.observing() {
+		m.report(strings.Join(components, "/"), isDir, result)
+	}
+	return result.Ignored
+}
+
+// MatchMany reports, for each paths[i], whether it should be ignored. It is
+// equivalent to calling Match on each path in order but takes the read lock
+// once for the whole batch. isDirs[i] gives the directory flag for paths[i];
+// a nil or shorter isDirs treats the remaining paths as files.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchMany(paths []string, isDirs []bool) []bool {
+	results := m.matchMany(paths, isDirs, !m.observing(), nil)
+	out := make([]bool, len(results))
+	for i, r := range results {
+		out[i] = r.Ignored
+	}
+	return out
+}
+
+// MatchManyWithReason is the batch form of MatchWithReason: results[i] is
+// the MatchResult for paths[i], identical to what MatchWithReason would
+// return. The read lock is taken once for the whole batch, so every result
+// reflects the same rule set even if AddPatterns runs concurrently. isDirs
+// follows the same convention as MatchMany.
+//
+// OnMatch, if configured, is called once per path in input order after the
+// lock has been released.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchManyWithReason(paths []string, isDirs []bool) []MatchResult {
+	return m.matchMany(paths, isDirs, false, nil)
+}
+
+// DirEntry is one entry of a directory listing passed to TopLevelIgnored:
+// a name and whether it is a directory. Unlike fs.DirEntry it needs no
+// file system behind it.
+type DirEntry struct {
+	Name  string
+	IsDir bool
+}
+
+// TopLevelIgnored decides the entries of the root directory (the view's
+// basePath for a Sub view), for an "ignore summary" of what a repository
+// ignores at the top level. results[i] is the MatchResult for entries[i],
+// as MatchManyWithReason would return it; the ignored entries are those
+// with Ignored set. An ignored directory stands for its whole subtree,
+// since nothing inside it can be re-included, while an ignored file is
+// just the file. The read lock is taken once for the whole listing, and
+// OnMatch, if configured, is called once per entry in input order.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) TopLevelIgnored(entries []DirEntry) []MatchResult {
+	paths := make([]string, len(entries))
+	isDirs := make([]bool, len(entries))
+	for i, e := range entries {
+		paths[i], isDirs[i] = e.Name, e.IsDir
+	}
+	return m.matchMany(paths, isDirs, false, nil)
+}
+
+// Partition splits paths into those kept and those ignored, as MatchMany
+// would decide them, in one pass under a single read lock. Each slice
+// preserves the input order, and the paths are returned exactly as given.
+// isDirs follows the same convention as MatchMany.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) Partition(paths []string, isDirs []bool) (kept, ignored []string) {
+	for i, r := range m.matchMany(paths, isDirs, !m.observing(), nil) {
+		if r.Ignored {
+			ignored = append(ignored, paths[i])
+		} else {
+			kept = append(kept, paths[i])
+		}
+	}
+	return kept, ignored
+}
+
+// BatchStats aggregates the cost of a batch match, for profiling a scan.
+type BatchStats struct {
+	// Iterations is the total number of backtrack iterations consumed.
+	// Each path has its own budget of MaxBacktrackIterations.
+	Iterations int
+
+	// LimitExceeded counts the paths whose match used up its backtrack
+	// budget; rules left unevaluated were treated as not matching.
+	LimitExceeded int
+
+	// Negated counts the paths whose decisive rule was a negation (see
+	// MatchResult.Negated).
+	Negated int
+}
+
+// MatchManyStats is MatchMany that also reports aggregate statistics for
+// the batch. A high Iterations total or any LimitExceeded means some
+// patterns are expensive for these paths; MatchWithReason on the costliest
+// paths names the rules involved.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchManyStats(paths []string, isDirs []bool) ([]bool, BatchStats) {
+	var stats BatchStats
+	results := m.matchMany(paths, isDirs, !m.observing(), &stats)
+	out := make([]bool, len(results))
+	for i, r := range results {
+		out[i] = r.Ignored
+	}
+	return out, stats
+}
+
+// LimitHit reports a path whose match used up its backtrack budget (see
+// ProbeLimits).
+type LimitHit struct {
+	// Path is the path as passed to ProbeLimits.
+	Path string
+
+	// Pattern and Line identify the rule being evaluated when the budget
+	// ran out. Pattern is empty if no single rule could be blamed.
+	Pattern string
+	Line    int
+}
+
+// ProbeLimits matches each path as MatchWithReason does and reports those
+// whose match ran out of MaxBacktrackIterations, in input order. Rules
+// left unevaluated after the budget runs out are treated as not matching,
+// so such paths may be decided wrongly without any sign; auditing a config
+// against a sample of deep paths surfaces that risk before it matters.
+// Each path is treated as a file unless it ends in a separator. Returns
+// nil if no path hits the limit.
+//
+// The rule reported is the one that used up the budget, which is usually
+// the expensive pattern to rewrite, though earlier rules may have spent
+// most of it. The fallback matcher, if any, is not probed. OnMatch is not
+// called.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) ProbeLimits(paths []string) []LimitHit {
+	var hits []LimitHit
+	var segBuf [32]string
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range paths {
+		path, pathSegments, isDir, ok := m.preparePath(p, false, segBuf[:0])
+		if !ok {
+			continue
+		}
+		ctx := m.newContext()
+		ctx.trackLimit = true
+		m.resolve(len(m.rules), path, pathSegments, isDir, &ctx)
+		if !ctx.exhausted() {
+			continue
+		}
+		hit := LimitHit{Path: p}
+		if r := ctx.limitRule; r != nil {
+			hit.Pattern, hit.Line = r.pattern, r.line
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+// matchMany is the shared body of MatchMany, MatchManyWithReason, Partition
+// and MatchManyStats. With settle set, results carry a correct
+// Ignored/Matched decision but Rule and its provenance may name an earlier
+// ignoring rule than the last one. A non-nil stats accumulates the batch's
+// BatchStats.
+func (m *Matcher) matchMany(paths []string, isDirs []bool, settle bool, stats *BatchStats) []MatchResult {
+	results := make([]MatchResult, len(paths))
+
+	var segBuf [32]string
+	m.mu.RLock()
+	settleAt := m.settleAt(settle)
+	for i, p := range paths {
+		isDir := i < len(isDirs) && isDirs[i]
+		path, pathSegments, isDir, ok := m.preparePath(p, isDir, segBuf[:0])
+		if !ok {
+			continue
+		}
+		ctx := m.newContext()
+		results[i] = m.resolve(settleAt, path, pathSegments, isDir, &ctx)
+		if stats != nil {
+			stats.Iterations += ctx.iterations
+			if ctx.exhausted() {
+				stats.LimitExceeded++
+			}
+			if results[i].Negated() {
+				stats.Negated++
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	if m.observing() {
+		for i, r := range results {
+			m.report(paths[i], i < len(isDirs) && isDirs[i], r)
+		}
+	}
+	return results
+}
+
+// prepareComponents is the component-slice counterpart to preparePath: it
+// cleans components into buf, joins them once to build the path string the
+// rule engine needs for basePath scoping, and applies case folding.
+func (m *Matcher) prepareComponents(components []string, buf []string) (string, []string, bool) {
+	seps := "/"
+	if runtime.GOOS == "windows" {
+		seps = "/\\"
+	}
+	segs := buf
+	for _, c := range components {
+		if strings.ContainsAny(c, seps) {
+			// Defensive: callers sometimes pass a multi-level component.
+			for _, part := range strings.FieldsFunc(c, func(r rune) bool { return strings.ContainsRune(seps, r) }) {
+				var ok bool
+				if segs, ok = appendComponent(segs, part); !ok {
+					return "", nil, false
+				}
+			}
+			continue
+		}
+		var ok bool
+		if segs, ok = appendComponent(segs, c); !ok {
+			return "", nil, false
+		}
+	}
+	if len(segs) == 0 || len(segs) > MaxPathDepth {
+		return "", nil, false
+	}
+
+	path := strings.Join(segs, "/")
+	if m.prefix != "" {
+		path = m.prefix + "/" + path
+		if segs = splitPathBuf(path, buf[:0]); len(segs) > MaxPathDepth {
+			return "", nil, false
+		}
+	}
 	if m.opts.CaseInsensitive {
 		lowered := strings.ToLower(path)
 		if lowered != path {
 			path = lowered
-			pathSegments = splitPathBuf(path, segBuf[:0])
+			segs = splitPathBuf(path, buf[:0])
+		}
+	}
+	return path, segs, true
+}
+
+// appendComponent appends one cleaned path component to segs, applying the
+// same rules normalizePath does for "", ".", "..", and NUL bytes. ok is false
+// if the component makes the whole path invalid.
+func appendComponent(segs []string, c string) ([]string, bool) {
+	switch {
+	case c == "" || c == ".":
+		return segs, true
+	case c == "..":
+		if len(segs) == 0 {
+			return nil, false // climbs above the repository root
 		}
+		return segs[:len(segs)-1], true
+	case strings.IndexByte(c, 0) >= 0:
+		return nil, false
 	}
+	return append(segs, c), true
+}
 
+// evaluate runs the rule set against an already prepared path (see
+// preparePath) under the read lock. It is the common back end of every
+// Match-family entry point. settle is for callers that only need the
+// decision; see settleAt.
+func (m *Matcher) evaluate(path string, pathSegments []string, isDir bool, settle bool) MatchResult {
 	// Single shared backtrack budget for the entire Match call.
 	// This prevents pathological patterns across many rules from causing
 	// excessive CPU usage — previously each rule got a fresh budget.
-	ctx := newMatchContext(m.opts.MaxBacktrackIterations)
+	ctx := m.newContext()
 
 	m.mu.RLock()
+	result := m.resolve(m.settleAt(settle), path, pathSegments, isDir, &ctx)
+	m.mu.RUnlock()
+	return result
+}
+
+// resolve decides a prepared path against m's rules, honouring force-tracked
+// directories, the fallback, and DefaultIgnored. Callers must hold mu.
+func (m *Matcher) resolve(settleAt int, path string, pathSegments []string, isDir bool, ctx *matchContext) MatchResult {
+	if result, ok := m.gitDirResult(pathSegments, isDir); ok {
+		return result
+	}
+	if len(m.forceTracked) > 0 {
+		if dir := m.trackedDir(path); dir != "" {
+			// Only rules scoped inside the tracked directory can ignore
+			// anything under it.
+			within := m.rulesWithin(dir)
+			return decide(within, len(within), path, pathSegments, isDir, ctx)
+		}
+	}
+	result := decide(m.consulted(), settleAt, path, pathSegments, isDir, ctx)
+	if !result.Matched && m.fallback != nil {
+		if fb, ok := m.consultFallback(path, isDir, ctx); ok {
+			return fb
+		}
+	}
+	return m.applyDefault(result)
+}
+
+// consulted returns the rules a match decision may consult: all of them,
+// or only the first MatcherOptions.StopAfterRules. Callers must hold mu.
+func (m *Matcher) consulted() []rule {
+	if n := m.opts.StopAfterRules; n > 0 && n < len(m.rules) {
+		return m.rules[:n]
+	}
+	return m.rules
+}
+
+// applyDefault applies MatcherOptions.DefaultIgnored to a decided result.
+func (m *Matcher) applyDefault(result MatchResult) MatchResult {
+	if !result.Matched && m.opts.DefaultIgnored {
+		result.Ignored = true
+	}
+	return result
+}
+
+// gitDirResult applies MatcherOptions.AutoIgnoreGitDir to a prepared path:
+// ok is true, with the result to report, when the path is a directory
+// named ".git" or lies inside one.
+func (m *Matcher) gitDirResult(pathSegments []string, isDir bool) (MatchResult, bool) {
+	if !m.opts.AutoIgnoreGitDir {
+		return MatchResult{}, false
+	}
+	dirs := pathSegments
+	if !isDir && len(dirs) > 0 {
+		dirs = dirs[:len(dirs)-1]
+	}
+	for _, seg := range dirs {
+		if seg == ".git" {
+			return MatchResult{
+				Rule:      ".git/",
+				Source:    "auto-ignore-git-dir",
+				Ignored:   true,
+				Matched:   true,
+				PathDepth: len(pathSegments),
+			}, true
+		}
+	}
+	return MatchResult{}, false
+}
+
+// settleAt returns the rule index from which evaluateRules may stop at the
+// first ignoring match: negateEnd when only the decision is needed, or
+// len(m.rules) (never) when the caller reports the deciding rule, which
+// last-match-wins defines as the last match. Callers must hold mu.
+func (m *Matcher) settleAt(settle bool) int {
+	if settle {
+		return m.negateEnd
+	}
+	return len(m.rules)
+}
 
-	result := evaluateRules(m.rules, path, pathSegments, isDir, &ctx)
+// decide applies rules to a prepared path the way git does. Each ancestor
+// directory, outermost first, is evaluated on its own with last-match-wins;
+// the first one that ends up ignored decides the path, since nothing inside
+// an ignored directory can be re-included. Otherwise the path's own last
+// match decides. "build/" thus ignores build/out.js through its ancestor,
+// while "*.txt" followed by "!a/" still ignores a/x.txt. settleAt is passed
+// through to evaluateRules. The caller must keep rules stable for the
+// duration of the call.
+func decide(rules []rule, settleAt int, path string, pathSegments []string, isDir bool, ctx *matchContext) MatchResult {
+	result, ancestorHit := evaluateRules(rules, settleAt, path, pathSegments, isDir, true, ctx)
+	result.PathDepth = len(pathSegments)
 
-	// Spec: a file cannot be re-included if a parent directory is excluded.
-	// Only walk ancestors when negation tried to re-include the path —
-	// otherwise the result is already correct and we'd waste budget.
+	// Only walk ancestors when some ignore rule matched one of them —
+	// otherwise no ancestor can be ignored and we'd waste budget. A settled
+	// ignore already fixes the decision, so the walk would only change the
+	// reported rule, which settling callers do not use.
 	//
 	// We slice the original `path` at internal slash positions rather than
 	// strings.Join'ing slices of pathSegments. Joining was O(i) per ancestor
 	// and O(N²) overall; a 100k-segment path with a matching negation took
 	// tens of seconds before this fix (observed via FuzzMatch timeout in CI).
 	// Slicing is O(N) total and zero-alloc on the hot path.
-	if result.Matched && !result.Ignored && len(pathSegments) > 1 {
+	if ancestorHit && !(result.Ignored && settleAt < len(rules)) {
 		// Skip any leading slash so ancestor[:j] is a name, not "/" prefix.
 		start := 0
 		if len(path) > 0 && path[0] == '/' {
@@ -382,9 +1626,9 @@ func (m *Matcher) MatchWithReason(path string, isDir bool) MatchResult {
 			}
 			segCount++
 			ancestor := path[start:j]
-			ancRes := evaluateRules(m.rules, ancestor, pathSegments[:segCount], true, &ctx)
+			ancRes, _ := evaluateRules(rules, settleAt, ancestor, pathSegments[:segCount], true, false, ctx)
 			if ancRes.Matched && ancRes.Ignored {
-				m.mu.RUnlock()
+				ancRes.PathDepth = len(pathSegments)
 				return ancRes
 			}
 			// Budget exhaustion can happen mid-walk on deep paths; bail
@@ -395,25 +1639,242 @@ func (m *Matcher) MatchWithReason(path string, isDir bool) MatchResult {
 		}
 	}
 
-	m.mu.RUnlock()
 	return result
 }
 
+// MatchPrefix is a conservative, fast pre-check for walkers deciding whether
+// a directory needs closer inspection. segments is the leading part of a
+// path (e.g., ["build"] or ["src", "vendor"]); every segment except the last
+// is treated as a directory, and isDir applies to the last one. A segment
+// containing separators ("src/vendor") is split as Match would split it.
+//
+// MatchPrefix reports whether any non-negated rule matches the prefix or one
+// of its ancestors. Negations are deliberately not consulted, so the answer
+// errs toward true:
+//
+//   - false means no ignore rule touches this prefix — it is safe to skip
+//     any further ignore checks for it.
+//   - true means some rule may ignore it — the caller must inspect further
+//     (typically with Match or MatchWithReason) before deciding.
+//
+// When no rule of m touches the prefix, the fallback's MatchPrefix (see
+// SetFallback) decides.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchPrefix(segments []string, isDir bool) bool {
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := m.preparePath(strings.Join(segments, "/"), isDir, segBuf[:0])
+	if !ok {
+		return false
+	}
+	if m.opts.DefaultIgnored {
+		return true // every path is ignored unless a negation allows it
+	}
+	if _, ok := m.gitDirResult(pathSegments, isDir); ok {
+		return true
+	}
+	ctx := m.newContext()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Walk the prefix from its outermost ancestor to the full path, slicing
+	// path at slash positions as MatchWithReason's ancestor walk does.
+	start := 0
+	if path[0] == '/' {
+		start = 1
+	}
+	segCount := 0
+	for j := start; j <= len(path); j++ {
+		if j < len(path) && path[j] != '/' {
+			continue
+		}
+		segCount++
+		ancestor, ancestorIsDir := path[start:j], true
+		if j == len(path) {
+			ancestor, ancestorIsDir = path, isDir
+		}
+		rules := m.consulted()
+		for i := range rules {
+			r := &rules[i]
+			if !r.negate && matchRule(r, ancestor, pathSegments[:segCount], ancestorIsDir, &ctx) {
+				return true
+			}
+		}
+	}
+	// No rule of m touches the prefix, so the fallback may decide it. It
+	// receives the prefix relative to m's root, as consultFallback passes it.
+	if m.fallback != nil {
+		return m.fallback.MatchPrefix([]string{path}, isDir)
+	}
+	return false
+}
+
+// IgnoreDepth reports where the ignore decision for path is first forced:
+// the 0-based index of the shallowest segment of path — an ancestor
+// directory or the path itself — that is ignored, or -1 if path is not
+// ignored. For "a/b/c/d.log" ignored by "*.log" it is 3, the file itself;
+// for "x/node_modules/y" ignored by "node_modules/" it is 1. A tree view can
+// collapse at that segment, since everything below it is ignored as well.
+//
+// As in MatchWithReason's ancestor walk, an ancestor counts only when a
+// rule ignores it, so under DefaultIgnored an unmatched path is reported at
+// its own index. Ancestors above a force-tracked directory never count. For
+// a Sub view the index is into path as given, and 0 when the view's base
+// directory is itself ignored.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) IgnoreDepth(path string, isDir bool) int {
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return -1
+	}
+	skip := 0 // segments of a Sub view's prefix
+	if m.prefix != "" {
+		skip = strings.Count(m.prefix, "/") + 1
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ctx := m.newContext()
+	if !m.resolve(m.settleAt(true), path, pathSegments, isDir, &ctx).Ignored {
+		return -1
+	}
+
+	// Walk the ancestors outermost first, slicing path at slash positions as
+	// decide does. Above a force-tracked directory nothing is forced.
+	minSegs := 1
+	if dir := m.trackedDir(path); dir != "" {
+		minSegs = strings.Count(dir, "/") + 1
+	}
+	start := 0
+	if path[0] == '/' {
+		start = 1
+	}
+	segCount := 0
+	for j := start; j < len(path); j++ {
+		if path[j] != '/' {
+			continue
+		}
+		if segCount++; segCount < minSegs {
+			continue
+		}
+		ctx = m.newContext()
+		if r := m.resolve(len(m.rules), path[start:j], pathSegments[:segCount], true, &ctx); r.Matched && r.Ignored {
+			return max(segCount-1-skip, 0)
+		}
+	}
+	return max(len(pathSegments)-1-skip, 0)
+}
+
+// preparePath normalizes path and splits it into segments (using buf as
+// backing storage), applying the matcher's case folding. A trailing
+// separator marks the path as a directory, so isDir is returned as given or
+// forced true ("build/" is the directory build). ok is false when the path
+// can never match: empty after normalization, the root itself ("."), or
+// deeper than MaxPathDepth.
+func (m *Matcher) preparePath(path string, isDir bool, buf []string) (string, []string, bool, bool) {
+	if m.opts.URLDecodePaths {
+		path = decodePath(path)
+	}
+	isDir = isDir || hasTrailingSeparator(path)
+	if split := m.opts.Splitter; split == nil {
+		path = normalizePath(path)
+	} else {
+		if !m.opts.SplitRawPaths {
+			path = normalizePath(path)
+		}
+		if path != "" {
+			path = joinSegments(split(path))
+		}
+	}
+	// A walker starting at "." passes the root itself, which git never
+	// ignores; it is not a file literally named ".".
+	if path == "" || path == "." {
+		return "", nil, false, false
+	}
+	if m.prefix != "" {
+		path = m.prefix + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	pathSegments := splitPathBuf(path, buf)
+
+	// Defensive: paths past MaxPathDepth short-circuit. The parent-excluded
+	// negation walk is inherently O(M·N²), so without this cap a fuzzer or
+	// malicious caller can construct a path that pegs CPU for minutes.
+	// Realistic paths are nowhere near this limit; see MaxPathDepth's docs.
+	if len(pathSegments) > MaxPathDepth {
+		return "", nil, false, false
+	}
+
+	// Pre-lowercase path and segments once for case-insensitive matching,
+	// instead of lowering per-segment per-rule in matchSingleSegment.
+	// Re-split after lowering so segments point into the lowered string (1 alloc vs N+1).
+	if m.opts.CaseInsensitive {
+		lowered := strings.ToLower(path)
+		if lowered != path {
+			path = lowered
+			pathSegments = splitPathBuf(path, buf[:0])
+		}
+	}
+	return path, pathSegments, isDir, true
+}
+
 // evaluateRules runs all rules against a single path with last-match-wins semantics.
-func evaluateRules(rules []rule, path string, pathSegments []string, isDir bool, ctx *matchContext) MatchResult {
-	var result MatchResult
+//
+// An ignoring match at index settleAt or later is final — no negation
+// follows it — so evaluation stops there rather than scanning the remaining
+// rules. The decision is unchanged, but the reported rule is then the first
+// such match instead of the last; pass len(rules) when the deciding rule
+// matters. A matching final negation (ReturnFirstNegationWins) likewise
+// decides the path, and later rules are only checked for ancestorHit.
+//
+// With ancestors set, ancestorHit reports whether any ignore rule evaluated
+// also matches a directory containing path (see matchRuleAncestor), which
+// tells decide whether the ancestor walk is needed.
+func evaluateRules(rules []rule, settleAt int, path string, pathSegments []string, isDir, ancestors bool, ctx *matchContext) (result MatchResult, ancestorHit bool) {
+	ancestors = ancestors && len(pathSegments) > 1
+	locked := false // a final negation has decided the path itself
 	for i := range rules {
+		if ctx.done != nil && ctx.interrupted() {
+			break
+		}
 		r := &rules[i]
-		if matchRule(r, path, pathSegments, isDir, ctx) {
+		if r.negate && ctx.skipNegations {
+			continue
+		}
+		if !locked && matchRule(r, path, pathSegments, isDir, ctx) {
 			result.Matched = true
 			result.Rule = r.pattern
 			result.Source = r.source
 			result.BasePath = r.basePath
 			result.Line = r.line
+			result.RawLine = r.raw
 			result.Ignored = !r.negate
+			if settleAt == len(rules) { // settling callers only need Ignored
+				result.ContributingBasePaths = appendUnique(result.ContributingBasePaths, r.basePath)
+			}
+			if i >= settleAt && result.Ignored {
+				break
+			}
+			locked = r.final
+		}
+		if ancestors && !ancestorHit && !r.negate {
+			ancestorHit = matchRuleAncestor(r, path, pathSegments, ctx)
+		}
+		if ctx.trackLimit && ctx.limitRule == nil && ctx.exhausted() {
+			ctx.limitRule = r
+		}
+		if locked && (!ancestors || ancestorHit) {
+			break // nothing left to learn from later rules
 		}
 	}
-	return result
+	// Clip so results copied between nodes cannot append into each other.
+	bases := result.ContributingBasePaths
+	result.ContributingBasePaths = bases[:len(bases):len(bases)]
+	return result, ancestorHit
 }
 
 // RuleCount returns the number of rules currently loaded.
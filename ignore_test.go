@@ -2,12 +2,15 @@ package ignore
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -282,99 +285,1692 @@ func TestWarningHandler(t *testing.T) {
 	}
 }
 
+func TestRawPatterns_BOMRoundTrip(t *testing.T) {
+	content := []byte("\xEF\xBB\xBF# comment\r\n*.log\r\nbuild/\r\n")
+
+	m := NewWithOptions(MatcherOptions{PreserveRawContent: true})
+	m.AddPatternsWithSource("src", "embedded", content)
+
+	raw := m.RawPatterns()
+	if len(raw) != 1 {
+		t.Fatalf("RawPatterns() len = %d, want 1", len(raw))
+	}
+	if !bytes.Equal(raw[0].Content, content) {
+		t.Errorf("RawPatterns()[0].Content = %q, want %q", raw[0].Content, content)
+	}
+	if raw[0].BasePath != "src" || raw[0].Source != "embedded" {
+		t.Errorf("RawPatterns()[0] = {%q, %q}, want {\"src\", \"embedded\"}", raw[0].BasePath, raw[0].Source)
+	}
+
+	// Matching still sees the normalized content.
+	if !m.Match("src/debug.log", false) {
+		t.Error("src/debug.log should be ignored despite BOM and CRLF")
+	}
+	if !m.Match("src/build", true) {
+		t.Error("src/build/ should be ignored despite BOM and CRLF")
+	}
+
+	// The returned content is a copy.
+	raw[0].Content[0] = 'x'
+	if m.RawPatterns()[0].Content[0] != 0xEF {
+		t.Error("mutating RawPatterns() result leaked into the matcher")
+	}
+}
+
+func TestRawPatterns_DisabledByDefault(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+	if raw := m.RawPatterns(); raw != nil {
+		t.Errorf("RawPatterns() = %v, want nil without PreserveRawContent", raw)
+	}
+}
+
 func TestMatch_Basic(t *testing.T) {
 	m := New()
-	m.AddPatterns("", []byte("*.log\nbuild/\n!important.log\n"))
+	m.AddPatterns("", []byte("*.log\nbuild/\n!important.log\n"))
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"test.log file", "test.log", false, true},
+		{"debug.log file", "debug.log", false, true},
+		{"important.log negated", "important.log", false, false}, // negated
+		{"main.go no match", "main.go", false, false},
+		{"build dir", "build", true, true},
+		{"build not a dir", "build", false, false}, // not a dir
+		{"src/test.log nested", "src/test.log", false, true},
+		{"src/build nested dir", "src/build", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Match(tt.path, tt.isDir)
+			if got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch_WindowsPaths(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("backslash-to-slash conversion only applies on Windows")
+	}
+
+	m := New()
+	m.AddPatterns("", []byte("*.log\nsrc/build/\n"))
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"src\\test.log file", "src\\test.log", false, true},
+		{"src\\build dir", "src\\build", true, true},
+		{"src\\build\\ trailing separator", "src\\build\\", false, true},
+		{"src\\lib\\debug.log nested", "src\\lib\\debug.log", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Match(tt.path, tt.isDir)
+			if got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch_EmptyPath(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+
+	if m.Match("", false) {
+		t.Error("Match('') should return false")
+	}
+}
+
+func TestMatch_RootDot(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{DefaultIgnored: true})
+	m.AddPatterns("", []byte("*\n.\n"))
+
+	for _, p := range []string{".", "./", "./.", ".//"} {
+		for _, isDir := range []bool{false, true} {
+			if r := m.MatchWithReason(p, isDir); r.Ignored || r.Matched {
+				t.Errorf("MatchWithReason(%q, %v) = %+v, want the root not ignored", p, isDir, r)
+			}
+		}
+	}
+	if !m.Match("a", false) {
+		t.Error("Match(\"a\") should still be ignored by *")
+	}
+}
+
+// TestMatch_DirOnlyIsDirContract pins down what isDir means for
+// directory-only rules: it describes the last segment only. Ancestors are
+// always directories, so a wrong isDir cannot hide a path inside an ignored
+// directory, but the directory node itself follows isDir.
+func TestMatch_DirOnlyIsDirContract(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n/out/\nlogs/**/\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		// Inside the directory: isDir is irrelevant.
+		{"build/app.o", false, true},
+		{"build/app.o", true, true},
+		{"build/a/b/c", false, true},
+		{"src/build/x", false, true},
+		{"out/x", false, true},
+		{"logs/a/b", false, true},
+
+		// The directory node itself follows isDir.
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", false, false},
+		{"out", false, false},
+		{"logs/a", false, false},
+		{"logs/a", true, true},
+
+		// A trailing slash marks the node as a directory.
+		{"build/", false, true},
+		{"out/", false, true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	// MatchEither covers callers that cannot tell.
+	if !m.MatchEither("build").Ignored {
+		t.Error(`MatchEither("build") should be ignored by build/`)
+	}
+}
+
+func TestMatch_FileAndDirRules(t *testing.T) {
+	// With both "foo" and "foo/", last match wins among the rules that
+	// apply: the directory-only rule is skipped for a file.
+	tests := []struct {
+		rules   string
+		isDir   bool
+		ignored bool
+		line    int // deciding rule
+	}{
+		{"foo\nfoo/\n", false, true, 1},
+		{"foo\nfoo/\n", true, true, 2},
+		{"foo/\nfoo\n", false, true, 2},
+		{"foo/\nfoo\n", true, true, 2},
+		{"foo\n!foo/\n", false, true, 1},
+		{"foo\n!foo/\n", true, false, 2},
+		{"foo\n!foo\nfoo/\n", false, false, 2},
+		{"foo\n!foo\nfoo/\n", true, true, 3},
+	}
+	for _, tt := range tests {
+		m := New()
+		m.AddPatterns("", []byte(tt.rules))
+		r := m.MatchWithReason("foo", tt.isDir)
+		if r.Ignored != tt.ignored || r.Line != tt.line {
+			t.Errorf("rules %q: MatchWithReason(foo, %v) = %+v, want ignored=%v by line %d",
+				tt.rules, tt.isDir, r, tt.ignored, tt.line)
+		}
+	}
+}
+
+func TestMatch_TrailingSlashIsDir(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n/out/\nlogs/**/\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"build", false, false},
+		{"build/", false, true},
+		{"build//", false, true},
+		{"./build/", false, true},
+		{"src/build/", false, true},
+		{"out/", false, true},
+		{"src/out/", false, false},
+		{"logs/a/", false, true},
+		{"logs/a", false, false},
+		{"/", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+		if got := m.MatchWithReason(tt.path, tt.isDir).Ignored; got != tt.want {
+			t.Errorf("MatchWithReason(%q, %v).Ignored = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+		if got := m.Snapshot().Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Snapshot().Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	paths := []string{"build/", "build", "src/build/"}
+	got := m.MatchMany(paths, []bool{false, false, false})
+	if want := []bool{true, false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchMany(%q) = %v, want %v", paths, got, want)
+	}
+	if rules := m.MatchingRules("build/", false); len(rules) != 1 {
+		t.Errorf("MatchingRules(\"build/\") returned %d rules, want 1", len(rules))
+	}
+}
+
+func TestMatch_CaseInsensitive(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	m.AddPatterns("", []byte("BUILD/\n*.LOG\n"))
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"BUILD uppercase dir", "BUILD", true, true},
+		{"build lowercase dir", "build", true, true},
+		{"Build mixed dir", "Build", true, true},
+		{"test.LOG uppercase ext", "test.LOG", false, true},
+		{"test.log lowercase ext", "test.log", false, true},
+		{"test.Log mixed ext", "test.Log", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Match(tt.path, tt.isDir)
+			if got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch_CaseInsensitiveFoldsPatternsOnce(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	m.AddPatterns("", []byte("*.LOG\nBUILD/\n"))
+
+	// The folded form is used for matching; the rule keeps the original.
+	r := m.MatchWithReason("Debug.Log", false)
+	if !r.Ignored || r.Rule != "*.LOG" {
+		t.Errorf("MatchWithReason(Debug.Log) = %+v, want ignored by %q", r, "*.LOG")
+	}
+
+	// Patterns are lowered at load, so lowercase input needs no folding.
+	allocs := testing.AllocsPerRun(100, func() {
+		m.Match("src/debug.log", false)
+		m.Match("build", true)
+	})
+	if allocs != 0 {
+		t.Errorf("case-insensitive Match of lowercase input allocated %v times, want 0", allocs)
+	}
+}
+
+func TestMatch_CaseInsensitiveBasePath(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	m.AddPatterns("Src/Gen", []byte("*.o\n/Out/\n"))
+
+	for _, p := range []string{"Src/Gen/a.o", "src/gen/a.o", "SRC/GEN/x/A.O", "src/gen/out/b"} {
+		if !m.Match(p, false) {
+			t.Errorf("Match(%q) = false, want true: a mixed-case basePath should be folded too", p)
+		}
+	}
+	if m.Match("src/a.o", false) {
+		t.Error("Match(src/a.o) = true, want false outside the basePath")
+	}
+}
+
+func TestMatchCase(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("README.md\n*.LOG\n!Keep.log\n"))
+	m.AddPatterns("Docs", []byte("Draft/\n"))
+	m.AddForceTrackedDir("Docs/Draft/Final")
+
+	tests := []struct {
+		path        string
+		isDir       bool
+		sensitive   bool
+		insensitive bool
+	}{
+		{"readme.md", false, false, true},
+		{"README.md", false, true, true},
+		{"debug.log", false, false, true},
+		{"keep.log", false, false, false}, // *.LOG, then !Keep.log
+		{"docs/draft", true, false, true},
+		{"DOCS/DRAFT/x.md", false, false, true},
+		{"docs/draft/final/x.md", false, false, false}, // force-tracked
+		{"main.go", false, false, false},
+	}
+	for _, tt := range tests {
+		if got := m.MatchCase(tt.path, tt.isDir, false).Ignored; got != tt.sensitive {
+			t.Errorf("MatchCase(%q, %v, false) = %v, want %v", tt.path, tt.isDir, got, tt.sensitive)
+		}
+		if got := m.MatchCase(tt.path, tt.isDir, true).Ignored; got != tt.insensitive {
+			t.Errorf("MatchCase(%q, %v, true) = %v, want %v", tt.path, tt.isDir, got, tt.insensitive)
+		}
+		if got, want := m.MatchCase(tt.path, tt.isDir, false), m.MatchWithReason(tt.path, tt.isDir); !reflect.DeepEqual(got, want) {
+			t.Errorf("MatchCase(%q, %v, false) = %+v, want MatchWithReason's %+v", tt.path, tt.isDir, got, want)
+		}
+	}
+
+	// The override does not change the matcher.
+	if m.Match("readme.md", false) {
+		t.Error("Match(readme.md) = true after MatchCase, want false")
+	}
+	if r := m.MatchCase("Debug.Log", false, true); r.Rule != "*.LOG" || r.Line != 2 {
+		t.Errorf("MatchCase(Debug.Log, true) = %+v, want rule *.LOG on line 2", r)
+	}
+
+	// A case-insensitive matcher cannot match case-sensitively.
+	ci := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	ci.AddPatterns("", []byte("README.md\n"))
+	if !ci.MatchCase("readme.md", false, false).Ignored {
+		t.Error("MatchCase on a CaseInsensitive matcher should ignore the override")
+	}
+}
+
+func TestMatchPrefix(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n/dist\n*.log\n!keep/\n"))
+	m.AddPatterns("src", []byte("vendor/\n"))
+
+	tests := []struct {
+		segments []string
+		isDir    bool
+		want     bool
+	}{
+		{[]string{"build"}, true, true},
+		{[]string{"build"}, false, false}, // dir-only rule, prefix is a file
+		{[]string{"build", "out"}, true, true},
+		{[]string{"dist"}, true, true},
+		{[]string{"a", "dist"}, true, false}, // /dist is anchored
+		{[]string{"src"}, true, false},
+		{[]string{"src", "vendor"}, true, true},
+		{[]string{"lib", "vendor"}, true, false}, // vendor/ scoped to src
+		{[]string{"app.log"}, false, true},
+		{[]string{"keep"}, true, false}, // negations are not consulted
+		{nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.segments, "/"), func(t *testing.T) {
+			if got := m.MatchPrefix(tt.segments, tt.isDir); got != tt.want {
+				t.Errorf("MatchPrefix(%q, %v) = %v, want %v", tt.segments, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPrefix_Conservative(t *testing.T) {
+	// A re-included directory still reports true: the caller must inspect
+	// further rather than trusting the pre-check.
+	m := New()
+	m.AddPatterns("", []byte("*\n!src/\n"))
+	if !m.MatchPrefix([]string{"src"}, true) {
+		t.Error("MatchPrefix should be conservative and report true when any ignore rule matches")
+	}
+	if m.Match("src", true) {
+		t.Error("Match(src) should be re-included")
+	}
+}
+
+func TestMatchComponents(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\nbuild/\n/dist\n"))
+	m.AddPatterns("src", []byte("gen/\n"))
+
+	tests := []struct {
+		name       string
+		components []string
+		isDir      bool
+		want       bool
+	}{
+		{"simple file", []string{"debug.log"}, false, true},
+		{"nested file", []string{"a", "b", "debug.log"}, false, true},
+		{"dir-only", []string{"build"}, true, true},
+		{"inside dir", []string{"build", "out.js"}, false, true},
+		{"anchored", []string{"dist"}, true, true},
+		{"anchored nested", []string{"a", "dist"}, true, false},
+		{"basePath scoped", []string{"src", "gen", "x.go"}, false, true},
+		{"empty components filtered", []string{"", "a", "", "debug.log"}, false, true},
+		{"dot components filtered", []string{".", "src", ".", "gen"}, true, true},
+		{"dotdot resolved", []string{"src", "..", "dist"}, true, true},
+		{"dotdot escapes root", []string{"..", "debug.log"}, false, false},
+		{"all empty", []string{"", "."}, false, false},
+		{"nil", nil, false, false},
+		{"not ignored", []string{"src", "main.go"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.MatchComponents(tt.components, tt.isDir); got != tt.want {
+				t.Errorf("MatchComponents(%q, %v) = %v, want %v", tt.components, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchComponents_AgreesWithMatch(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	m.AddPatterns("", []byte("*.LOG\nnode_modules/\n!keep.log\n"))
+	m.AddPatterns("Src", []byte("Gen/\n"))
+
+	paths := []string{"a/B.log", "keep.log", "NODE_MODULES/x.js", "src/gen/y", "src/main.go", "logs/keep.log"}
+	for _, p := range paths {
+		for _, isDir := range []bool{false, true} {
+			want := m.Match(p, isDir)
+			if got := m.MatchComponents(strings.Split(p, "/"), isDir); got != want {
+				t.Errorf("MatchComponents(%q, %v) = %v, Match = %v", p, isDir, got, want)
+			}
+		}
+	}
+}
+
+func TestMatchComponents_EmbeddedSeparators(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("/a/b/c\nbuild/\n!build/keep\n"))
+	m.AddPatterns("src/gen", []byte("*.go\n"))
+
+	tests := []struct {
+		components []string
+		isDir      bool
+	}{
+		{[]string{"a", "b/c"}, false},
+		{[]string{"a/b", "c"}, false},
+		{[]string{"a/b/c"}, false},
+		{[]string{"a//b/", "/c"}, false},
+		{[]string{"x", "build/out.js"}, false},
+		{[]string{"build/keep"}, true},
+		{[]string{"src/gen", "main.go"}, false},
+		{[]string{"src", "gen/../main.go"}, false},
+		{[]string{"src/..", "..", "a"}, false},
+	}
+	for _, tt := range tests {
+		path := strings.Join(tt.components, "/")
+		want := m.MatchWithReason(path, tt.isDir)
+		if got := m.MatchComponents(tt.components, tt.isDir); got != want.Ignored {
+			t.Errorf("MatchComponents(%q, %v) = %v, Match(%q) = %v", tt.components, tt.isDir, got, path, want.Ignored)
+		}
+	}
+	if !m.MatchComponents([]string{"a", "b/c"}, false) {
+		t.Error(`MatchComponents(["a", "b/c"]) = false, want true`)
+	}
+}
+
+func TestMatchPrefix_EmbeddedSeparators(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("/src/vendor/\n"))
+
+	if !m.MatchPrefix([]string{"src/vendor"}, true) {
+		t.Error(`MatchPrefix(["src/vendor"], true) = false, want true`)
+	}
+	if !m.MatchPrefix([]string{"src", "vendor/lib"}, false) {
+		t.Error(`MatchPrefix(["src", "vendor/lib"], false) = false, want true`)
+	}
+	if m.MatchPrefix([]string{"lib/src", "vendor"}, true) {
+		t.Error(`MatchPrefix(["lib/src", "vendor"], true) = true, want false`)
+	}
+}
+
+func TestMatchWithReason_RawLine(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{AllowInlineComments: true})
+	m.AddPatterns("src", []byte("\xEF\xBB\xBF*.log   \r\n!keep.log\t\nname\\  \n*.tmp # scratch files\n"))
+
+	tests := []struct {
+		path    string
+		rule    string
+		rawLine string
+	}{
+		{"src/debug.log", "*.log", "*.log   "},
+		{"src/keep.log", "!keep.log", "!keep.log\t"},
+		{"src/name ", "name ", "name\\  "},
+		{"src/a.tmp", "*.tmp", "*.tmp # scratch files"},
+	}
+	for _, tt := range tests {
+		r := m.MatchWithReason(tt.path, false)
+		if !r.Matched || r.Rule != tt.rule || r.RawLine != tt.rawLine {
+			t.Errorf("MatchWithReason(%q): Rule %q, RawLine %q; want %q, %q", tt.path, r.Rule, r.RawLine, tt.rule, tt.rawLine)
+		}
+	}
+	if r := m.MatchWithReason("src/main.go", false); r.RawLine != "" {
+		t.Errorf("unmatched RawLine = %q, want empty", r.RawLine)
+	}
+}
+
+func TestOnMatch_FiresOncePerCall(t *testing.T) {
+	var mu sync.Mutex
+	var got []MatchResult
+	m := NewWithOptions(MatcherOptions{
+		OnMatch: func(r MatchResult) {
+			mu.Lock()
+			got = append(got, r)
+			mu.Unlock()
+		},
+	})
+	m.AddPatterns("", []byte("*.log\n!keep.log\n"))
+
+	m.Match("debug.log", false)
+	m.MatchWithReason("keep.log", false)
+	m.Match("main.go", false)
+	m.Match("", false)
+	m.MatchComponents([]string{"a", "b.log"}, false)
+
+	want := []MatchResult{
+		{Rule: "*.log", RawLine: "*.log", Line: 1, Matched: true, Ignored: true, PathDepth: 1, ContributingBasePaths: []string{""}},
+		{Rule: "!keep.log", RawLine: "!keep.log", Line: 2, Matched: true, PathDepth: 1, ContributingBasePaths: []string{""}},
+		{PathDepth: 1},
+		{},
+		{Rule: "*.log", RawLine: "*.log", Line: 1, Matched: true, Ignored: true, PathDepth: 2, ContributingBasePaths: []string{""}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("OnMatch fired %d times, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("OnMatch call %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOnMatch_Concurrent(t *testing.T) {
+	var ignored, kept atomic.Int64
+	m := NewWithOptions(MatcherOptions{
+		OnMatch: func(r MatchResult) {
+			if r.Ignored {
+				ignored.Add(1)
+			} else {
+				kept.Add(1)
+			}
+		},
+	})
+	m.AddPatterns("", []byte("*.log\n"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Match("a.log", false)
+				m.Match("a.go", false)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ignored.Load() != 800 || kept.Load() != 800 {
+		t.Errorf("ignored=%d kept=%d, want 800 each", ignored.Load(), kept.Load())
+	}
+}
+
+func TestMatchManyWithReason_AgreesWithMatchWithReason(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n!keep.log\nbuild/\n"))
+	m.AddPatternsWithSource("src", "src/.gitignore", []byte("gen/\n!gen/keep.go\n"))
+
+	paths := []string{"a.log", "keep.log", "build", "build/x.js", "src/gen/a.go", "src/gen/keep.go", "main.go", "", "../x.log"}
+	isDirs := []bool{false, false, true, false, false, false, false, false, false}
+
+	got := m.MatchManyWithReason(paths, isDirs)
+	if len(got) != len(paths) {
+		t.Fatalf("MatchManyWithReason returned %d results, want %d", len(got), len(paths))
+	}
+	bools := m.MatchMany(paths, isDirs)
+	for i, p := range paths {
+		want := m.MatchWithReason(p, isDirs[i])
+		if !reflect.DeepEqual(got[i], want) {
+			t.Errorf("MatchManyWithReason[%d] (%q) = %+v, want %+v", i, p, got[i], want)
+		}
+		if bools[i] != want.Ignored {
+			t.Errorf("MatchMany[%d] (%q) = %v, want %v", i, p, bools[i], want.Ignored)
+		}
+	}
+}
+
+func TestTopLevelIgnored(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("node_modules/\n*.log\n!keep.log\n"))
+
+	entries := []DirEntry{
+		{Name: "node_modules", IsDir: true},
+		{Name: "debug.log"},
+		{Name: "src", IsDir: true},
+		{Name: "keep.log"},
+		{Name: "node_modules"}, // a file: "node_modules/" is dir-only
+	}
+	got := m.TopLevelIgnored(entries)
+	if len(got) != len(entries) {
+		t.Fatalf("TopLevelIgnored returned %d results, want %d", len(got), len(entries))
+	}
+	for i, e := range entries {
+		if want := m.MatchWithReason(e.Name, e.IsDir); !reflect.DeepEqual(got[i], want) {
+			t.Errorf("TopLevelIgnored[%d] (%q) = %+v, want %+v", i, e.Name, got[i], want)
+		}
+	}
+
+	var ignored []string
+	for i, r := range got {
+		if r.Ignored {
+			ignored = append(ignored, entries[i].Name)
+		}
+	}
+	if want := []string{"node_modules", "debug.log"}; !equalStrings(ignored, want) {
+		t.Errorf("ignored entries = %q, want %q", ignored, want)
+	}
+	if got[0].Rule != "node_modules/" || got[1].Rule != "*.log" || got[3].Rule != "!keep.log" {
+		t.Errorf("deciding rules = %q, %q, %q", got[0].Rule, got[1].Rule, got[3].Rule)
+	}
+
+	// A Sub view lists the entries of its own root.
+	if sub := m.Sub("src").TopLevelIgnored([]DirEntry{{Name: "trace.log"}}); !sub[0].Ignored || sub[0].PathDepth != 2 {
+		t.Errorf("Sub(src).TopLevelIgnored = %+v, want src/trace.log ignored", sub[0])
+	}
+	if got := m.TopLevelIgnored(nil); len(got) != 0 {
+		t.Errorf("TopLevelIgnored(nil) = %+v, want empty", got)
+	}
+}
+
+func TestMatchMany_ShortIsDirs(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n"))
+
+	got := m.MatchMany([]string{"build", "build"}, []bool{true})
+	if !got[0] || got[1] {
+		t.Errorf("MatchMany = %v, want [true false] (missing isDirs treated as files)", got)
+	}
+	if got := m.MatchMany(nil, nil); len(got) != 0 {
+		t.Errorf("MatchMany(nil) = %v, want empty", got)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\nbuild/\n!keep.log\n"))
+
+	paths := []string{"main.go", "debug.log", "build", "src/keep.log", "build/out.js", "build", "README.md"}
+	kept, ignored := m.Partition(paths, []bool{false, false, true, false, false})
+	if want := []string{"main.go", "src/keep.log", "build", "README.md"}; !equalStrings(kept, want) {
+		t.Errorf("kept = %q, want %q", kept, want)
+	}
+	if want := []string{"debug.log", "build", "build/out.js"}; !equalStrings(ignored, want) {
+		t.Errorf("ignored = %q, want %q", ignored, want)
+	}
+
+	if kept, ignored := m.Partition(nil, nil); kept != nil || ignored != nil {
+		t.Errorf("Partition(nil) = %q, %q, want nil slices", kept, ignored)
+	}
+}
+
+func TestMatchManyStats(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{MaxBacktrackIterations: 20})
+	m.AddPatterns("", []byte("a/**/b/**/c/**/d\n*.log\n!keep.log\n"))
+
+	paths := []string{"main.go", "keep.log", "src/keep.log", "x.log", "a/b/c/d", "a/x/y/z/b/q/c/w/e"}
+	got, stats := m.MatchManyStats(paths, nil)
+	if want := m.MatchMany(paths, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchManyStats results = %v, MatchMany = %v", got, want)
+	}
+
+	var iterations int
+	for _, p := range paths {
+		_, s := m.MatchManyStats([]string{p}, nil)
+		iterations += s.Iterations
+	}
+	if stats.Iterations == 0 || stats.Iterations != iterations {
+		t.Errorf("Iterations = %d, want the per-path sum %d (> 0)", stats.Iterations, iterations)
+	}
+	if stats.LimitExceeded != 1 {
+		t.Errorf("LimitExceeded = %d, want 1 (only the deep path exhausts the budget)", stats.LimitExceeded)
+	}
+	if stats.Negated != 2 {
+		t.Errorf("Negated = %d, want 2", stats.Negated)
+	}
+
+	if _, stats := m.MatchManyStats(nil, nil); stats != (BatchStats{}) {
+		t.Errorf("MatchManyStats(nil) stats = %+v, want zero", stats)
+	}
+}
+
+func TestProbeLimits(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{MaxBacktrackIterations: 20})
+	m.AddPatterns("", []byte("*.log\na/**/b/**/c/**/d\n!keep.log\n"))
+
+	deep := "a/x/y/z/b/q/c/w/e"
+	hits := m.ProbeLimits([]string{"main.go", "keep.log", deep, "a/b/c/d", ""})
+	want := []LimitHit{{Path: deep, Pattern: "a/**/b/**/c/**/d", Line: 2}}
+	if !reflect.DeepEqual(hits, want) {
+		t.Errorf("ProbeLimits = %+v, want %+v", hits, want)
+	}
+
+	// The same config is fine with the default budget.
+	d := New()
+	d.AddPatterns("", []byte("*.log\na/**/b/**/c/**/d\n"))
+	if hits := d.ProbeLimits([]string{deep}); hits != nil {
+		t.Errorf("ProbeLimits with the default budget = %+v, want nil", hits)
+	}
+}
+
+func TestMatchWithReason_PathDepth(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n!build/keep.txt\n*.log\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  int
+	}{
+		{"main.go", false, 1},         // no match still reports depth
+		{"a/b/c/debug.log", false, 4}, // matched at the leaf
+		{"./src//lib/x.go", false, 3}, // normalized before counting
+		{"build/keep.txt", false, 2},  // decided by the ancestor, depth is the query's
+		{"a/../b.log", false, 1},      // ".." resolved first
+		{"", false, 0},                // empty path
+		{"../outside.log", false, 0},  // escapes root
+		{"build", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := m.MatchWithReason(tt.path, tt.isDir).PathDepth; got != tt.want {
+				t.Errorf("MatchWithReason(%q).PathDepth = %d, want %d", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch_CommentChar(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CommentChar: ';'})
+	m.AddPatterns("", []byte("; build outputs\n*.o\n#tmp\n\\;semi\n"))
+
+	if got := m.RuleCount(); got != 3 {
+		t.Fatalf("RuleCount() = %d, want 3", got)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"main.o", true},
+		{"#tmp", true},
+		{";semi", true},
+		{"; build outputs", false},
+		{"main.c", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, false); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// Default dialect still treats # as the comment character.
+	d := New()
+	d.AddPatterns("", []byte("#tmp\n;semi\n"))
+	if d.Match("#tmp", false) {
+		t.Error("default matcher: #tmp should be a comment")
+	}
+	if !d.Match(";semi", false) {
+		t.Error("default matcher: ;semi should be a literal pattern")
+	}
+}
+
+func TestMatch_AllowInlineComments(t *testing.T) {
+	content := []byte("*.log # debug logs\nfoo#bar\nbuild/\t# outputs\nkeep \\#me\n")
+	m := NewWithOptions(MatcherOptions{AllowInlineComments: true})
+	m.AddPatterns("", content)
+
+	var patterns []string
+	for _, r := range m.MatchingRules("x.log", false) {
+		patterns = append(patterns, r.Pattern)
+	}
+	if want := []string{"*.log"}; !equalStrings(patterns, want) {
+		t.Errorf("rule patterns for x.log = %q, want %q", patterns, want)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"debug.log", false, true},
+		{"foo#bar", false, true},
+		{"build", true, true},
+		{"keep #me", false, true},
+		{"keep", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// Git parity by default: the comment is part of the pattern.
+	d := New()
+	d.AddPatterns("", content)
+	if d.Match("debug.log", false) || !d.Match("debug.log # debug logs", false) {
+		t.Error("default matcher: \" # debug logs\" should be part of the pattern")
+	}
+
+	// The comment character follows CommentChar.
+	semi := NewWithOptions(MatcherOptions{AllowInlineComments: true, CommentChar: ';'})
+	semi.AddPatterns("", []byte("*.o ; objects\n*.a # archives\n"))
+	if !semi.Match("x.o", false) || semi.Match("x.a", false) {
+		t.Error("CommentChar ';': only \" ;\" should start an inline comment")
+	}
+}
+
+func TestMatch_DoubleStarMinOne(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{DoubleStarMinOne: true})
+	m.AddPatterns("", []byte("a/**/b\n**/logs\ncache/**\nx/**/y/\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a/b", false, false},
+		{"a/x/b", false, true},
+		{"a/x/y/b", false, true},
+		{"a/x/b/c", false, true}, // inside a matched directory
+		{"logs", true, true},     // leading ** still matches zero directories
+		{"cache", true, false},   // trailing ** unchanged: contents only
+		{"cache/f", false, true}, // trailing ** unchanged
+		{"x/y", true, false},     // dir-only form needs one directory too
+		{"x/y/f", false, false},  // ...including for files inside it
+		{"x/m/y/f", false, true}, // prefix match with one intermediate
+		{"x/m/y", true, true},    // the directory itself
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	// Default (git) semantics: ** may match zero directories.
+	d := New()
+	d.AddPatterns("", []byte("a/**/b\n"))
+	if !d.Match("a/b", false) {
+		t.Error("default matcher: a/**/b should match a/b")
+	}
+}
+
+func TestMatch_DoubleStarToken(t *testing.T) {
+	dots := NewWithOptions(MatcherOptions{DoubleStarToken: "...", Canonicalize: true})
+	dots.AddPatterns("", []byte("a/.../b\n.../logs\ncache/...\nx...\np/**/q\n"))
+	git := New()
+	git.AddPatterns("", []byte("a/**/b\n**/logs\ncache/**\nx...\np/*/q\n"))
+
+	paths := []struct {
+		path  string
+		isDir bool
+	}{
+		{"a/b", false}, {"a/x/b", false}, {"a/x/y/b", false}, {"a/x/b/c", false},
+		{"logs", true}, {"src/logs", true}, {"cache", true}, {"cache/f", false},
+		{"x...", false}, {"xyz", false}, {"p/q", false}, {"p/m/q", false}, {"p/m/n/q", false},
+	}
+	for _, p := range paths {
+		got, want := dots.MatchWithReason(p.path, p.isDir), git.MatchWithReason(p.path, p.isDir)
+		if got.Ignored != want.Ignored || got.Line != want.Line {
+			t.Errorf("MatchWithReason(%q, %v) = %+v, want the git equivalent %+v", p.path, p.isDir, got, want)
+		}
+	}
+
+	infos := dots.MatchingRules("a/x/b", false)
+	if len(infos) != 1 || infos[0].Pattern != "a/.../b" || infos[0].Canonical != "a/**/b" {
+		t.Errorf("MatchingRules(a/x/b) = %+v, want pattern a/.../b with canonical a/**/b", infos)
+	}
+
+	// Under the default token, "..." is an ordinary name.
+	d := New()
+	d.AddPatterns("", []byte("a/.../b\n"))
+	if d.Match("a/x/b", false) || !d.Match("a/.../b", false) {
+		t.Error("default matcher: ... should be literal")
+	}
+}
+
+func TestMatch_AnchoredOnly(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{AnchoredOnly: true, Canonicalize: true})
+	m.AddPatterns("", []byte("README.md\n*.log\nbuild/\n**/gen\n!keep.log\n"))
+	m.AddPatterns("src", []byte("main.o\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"README.md", false, true},
+		{"docs/README.md", false, false},
+		{"debug.log", false, true},
+		{"a/debug.log", false, false},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build/out.js", false, true},
+		{"a/build/out.js", false, false},
+		{"gen", true, true},
+		{"a/b/gen", true, true}, // a leading **/ still floats
+		{"src/main.o", false, true},
+		{"src/lib/main.o", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	infos := m.MatchingRules("debug.log", false)
+	if len(infos) != 1 || !infos[0].Anchored || infos[0].Pattern != "*.log" || infos[0].Canonical != "/*.log" {
+		t.Errorf("MatchingRules(debug.log) = %+v, want anchored *.log with canonical /*.log", infos)
+	}
+}
+
+func TestSub_MatchesParent(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n/build/\n!keep.log\n"))
+	m.AddPatterns("src", []byte("gen/\n/main.o\n"))
+
+	sub := m.Sub("src")
+	paths := []struct {
+		path  string
+		isDir bool
+	}{
+		{"main.go", false},
+		{"main.o", false},
+		{"lib/main.o", false},
+		{"debug.log", false},
+		{"keep.log", false},
+		{"gen", true},
+		{"gen/out.go", false},
+		{"build", true}, // /build/ is anchored at the root, not under src
+		{"a/b/c.log", false},
+	}
+	for _, p := range paths {
+		got := sub.MatchWithReason(p.path, p.isDir)
+		want := m.MatchWithReason("src/"+p.path, p.isDir)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Sub(src).MatchWithReason(%q) = %+v, want %+v", p.path, got, want)
+		}
+	}
+
+	if got, want := sub.MatchComponents([]string{"gen", "x.go"}, false), m.Match("src/gen/x.go", false); got != want {
+		t.Errorf("Sub(src).MatchComponents(gen/x.go) = %v, want %v", got, want)
+	}
+}
+
+func TestSub_Nested(t *testing.T) {
+	m := New()
+	m.AddPatterns("src/lib", []byte("*.tmp\n"))
+
+	sub := m.Sub("src").Sub("lib/")
+	if !sub.Match("a.tmp", false) {
+		t.Error("Sub(src).Sub(lib).Match(a.tmp) = false, want true")
+	}
+	if m.Sub("").Match("a.tmp", false) {
+		t.Error("Sub(\"\").Match(a.tmp) = true, want false")
+	}
+}
+
+func TestSub_Isolation(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+	sub := m.Sub("src")
+
+	// Patterns added to the view are scoped under its basePath.
+	sub.AddPatterns("", []byte("*.tmp\n"))
+	if !sub.Match("a.tmp", false) {
+		t.Error("sub: a.tmp should be ignored by its own pattern")
+	}
+	if m.Match("src/a.tmp", false) {
+		t.Error("parent: patterns added to a view must not leak into the parent")
+	}
+	if got := sub.MatchingRules("a.tmp", false); len(got) != 1 || got[0].BasePath != "src" {
+		t.Errorf("sub: MatchingRules(a.tmp) = %+v, want one rule scoped to src", got)
+	}
+
+	// Patterns added to the parent afterward are not visible to the view.
+	m.AddPatterns("", []byte("*.bak\n"))
+	if sub.Match("a.bak", false) {
+		t.Error("sub: parent patterns added after Sub should not be visible")
+	}
+	if !sub.Match("a.log", false) {
+		t.Error("sub: parent patterns present at Sub time should still apply")
+	}
+}
+
+func TestMatch_URLDecodePaths(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{URLDecodePaths: true})
+	m.AddPatterns("", []byte("/src/main.go\nsecret/\nliteral%2Fname\n"))
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"src/main.go", true},
+		{"src%2Fmain.go", true},
+		{"src%2fmain%2Ego", true},
+		{"src%252Fmain.go", false},     // one decode only: literal "src%2Fmain.go"
+		{"docs%2F..%2Fsecret/x", true}, // ".." resolved after decoding
+		{"literal%2Fname", false},      // patterns are not decoded
+		{"100%", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, false); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// Without the option, encoded paths are literal names.
+	d := New()
+	d.AddPatterns("", []byte("/src/main.go\n"))
+	if d.Match("src%2Fmain.go", false) {
+		t.Error("default matcher: src%2Fmain.go should not be decoded")
+	}
+}
+
+func TestMatch_Splitter(t *testing.T) {
+	dotted := func(p string) []string { return strings.Split(p, ".") }
+	m := NewWithOptions(MatcherOptions{Splitter: dotted})
+	m.AddPatterns("", []byte("com/example/internal/*\n!com/example/internal/Api\n*Test\n"))
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"com.example.internal.Impl", true},
+		{"com.example.internal.Api", false},
+		{"com.example.FooTest", true},
+		{"com.example.Foo", false},
+		{"com..example.internal.Impl", true}, // empty segments are dropped
+		{"com.example.internal\x00.Impl", false},
+		{"com/example/internal/Impl", true}, // "/" still separates
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, false); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if r := m.MatchWithReason("com.example.internal.Impl", false); r.Rule != "com/example/internal/*" || r.PathDepth != 4 {
+		t.Errorf("MatchWithReason = %+v, want rule com/example/internal/* at depth 4", r)
+	}
+	if !m.Sub("com/example").Match("internal.Impl", false) {
+		t.Error("Sub view should prepend its scope to the split path")
+	}
+
+	// The splitter sees the normalized path unless SplitRawPaths is set.
+	var seen string
+	spy := func(p string) []string { seen = p; return strings.Split(p, "/") }
+	m = NewWithOptions(MatcherOptions{Splitter: spy})
+	m.Match("./a//b/", true)
+	if seen != "a/b" {
+		t.Errorf("Splitter saw %q, want the normalized %q", seen, "a/b")
+	}
+	m = NewWithOptions(MatcherOptions{Splitter: spy, SplitRawPaths: true})
+	m.AddPatterns("", []byte("/secret\n"))
+	if m.Match("a/../secret", false) || seen != "a/../secret" {
+		t.Errorf("Splitter saw %q; raw \"..\" should be a literal segment", seen)
+	}
+}
+
+// TestMatch_SettleAgreesWithReason checks that Match's early exit past the
+// last negation never changes the decision MatchWithReason reaches.
+func TestMatch_SettleAgreesWithReason(t *testing.T) {
+	contents := [][]string{
+		{"*.log\nbuild/\n*.tmp\n"},
+		{"!keep.log\n*.log\nlogs/\n*.log\n"},
+		{"*.log\n!keep.log\n*.tmp\nkeep.log\n"},
+		{"*\n", "!*.go\n", "vendor/\n"},
+		{"logs/\n", "!logs/keep/\n", "*.tmp\n"},
+		{"/*\n!/src\n", "*.o\n"},
+	}
+	paths := []struct {
+		path  string
+		isDir bool
+	}{
+		{"debug.log", false}, {"keep.log", false}, {"a/keep.log", false},
+		{"build", true}, {"build/x.go", false}, {"x.tmp", false},
+		{"main.go", false}, {"vendor/x.go", false}, {"logs/keep", true},
+		{"logs/keep/x.log", false}, {"src/a.o", false}, {"src/main.go", false},
+		{"README", false},
+	}
+
+	for i, blobs := range contents {
+		m := New()
+		for _, blob := range blobs {
+			m.AddPatterns("", []byte(blob))
+		}
+		for _, p := range paths {
+			want := m.MatchWithReason(p.path, p.isDir).Ignored
+			if got := m.Match(p.path, p.isDir); got != want {
+				t.Errorf("contents[%d]: Match(%q, %v) = %v, MatchWithReason says %v",
+					i, p.path, p.isDir, got, want)
+			}
+		}
+	}
+}
+
+func TestMatchWithReason_ReportsLastRuleAfterNegations(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("!keep.log\n*.log\ndebug.*\n"))
+
+	// Match may stop at *.log, but MatchWithReason still reports the last
+	// matching rule.
+	result := m.MatchWithReason("debug.log", false)
+	if !result.Ignored || result.Rule != "debug.*" || result.Line != 3 {
+		t.Errorf("MatchWithReason(debug.log) = %+v, want rule debug.* at line 3", result)
+	}
+}
+
+func TestMatchResult_Describe(t *testing.T) {
+	m := New()
+	m.AddPatternsWithSource("", ".gitignore", []byte("*.log\n!keep.log\n"))
+	m.AddPatterns("src", []byte("# generated\ngen/\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  string
+	}{
+		{"debug.log", false, `ignored by rule "*.log" at line 1 of .gitignore`},
+		{"src/gen", true, `ignored by rule "gen/" at line 2`},
+		{"keep.log", false, `re-included by negation "!keep.log" at line 2 of .gitignore`},
+		{"main.go", false, "no rule matched"},
+	}
+	for _, tt := range tests {
+		if got := m.MatchWithReason(tt.path, tt.isDir).Describe(); got != tt.want {
+			t.Errorf("MatchWithReason(%q).Describe() = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	allowList := NewWithOptions(MatcherOptions{DefaultIgnored: true})
+	if got, want := allowList.MatchWithReason("x", false).Describe(), "ignored, but no rule matched"; got != want {
+		t.Errorf("DefaultIgnored Describe() = %s, want %s", got, want)
+	}
+}
+
+func TestMatchResult_String(t *testing.T) {
+	tests := []struct {
+		result MatchResult
+		want   string
+	}{
+		{MatchResult{}, "ignored=false matched=false"},
+		{
+			MatchResult{Ignored: true, Matched: true, Rule: "*.log", Line: 2, BasePath: "src", PathDepth: 2},
+			`ignored=true matched=true rule="*.log" line=2 base="src"`,
+		},
+		{
+			MatchResult{Matched: true, Rule: "!keep.log", Line: 5, Source: ".gitignore"},
+			`ignored=false matched=true rule="!keep.log" line=5 source=".gitignore"`,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.result.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+
+	m := New()
+	m.AddPatterns("src", []byte("# logs\n*.log\n"))
+	want := `ignored=true matched=true rule="*.log" line=2 base="src"`
+	if got := m.MatchWithReason("src/debug.log", false).String(); got != want {
+		t.Errorf("MatchWithReason(src/debug.log).String() = %s, want %s", got, want)
+	}
+}
+
+func TestMatch_AlwaysIgnore(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{AlwaysIgnore: DefaultAlwaysIgnore()})
+	m.AddPatterns("", []byte("*.log\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{".git", true, true},
+		{".git/config", false, true},
+		{".git/objects/ab/cdef", false, true},
+		{"vendor/lib/.git/HEAD", false, true}, // any depth
+		{".hg/store", false, true},
+		{".svn", true, true},
+		{".git", false, false}, // a gitlink file, not a directory
+		{".github/workflows/ci.yml", false, false},
+		{"main.go", false, false},
+		{"debug.log", false, true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+	if r := m.MatchWithReason(".git/config", false); r.Source != "always-ignore" || r.Rule != ".git/" {
+		t.Errorf("MatchWithReason(.git/config) = %+v, want rule .git/ from always-ignore", r)
+	}
+
+	// The rules come first, so a negation can re-include a directory.
+	m.AddPatterns("", []byte("!.svn/\n"))
+	if m.Match(".svn/entries", false) {
+		t.Error("!.svn/ should re-include .svn")
+	}
+
+	// Names are literal, including ones that look like syntax.
+	lit := NewWithOptions(MatcherOptions{AlwaysIgnore: []string{"[cache]", "!keep", "#tmp", "out/gen", ""}})
+	for _, p := range []string{"[cache]/x", "!keep/x", "#tmp/x", "out/gen/x"} {
+		if !lit.Match(p, false) {
+			t.Errorf("Match(%q) = false, want true", p)
+		}
+	}
+	for _, p := range []string{"c/x", "keep/x", "src/out/gen/x"} {
+		if lit.Match(p, false) {
+			t.Errorf("Match(%q) = true, want false", p)
+		}
+	}
+
+	// New and a zero AlwaysIgnore add nothing, as in git.
+	if New().RuleCount() != 0 || NewWithOptions(MatcherOptions{}).Match(".git/config", false) {
+		t.Error("without AlwaysIgnore, .git should not be ignored")
+	}
+}
+
+func TestMatch_AutoIgnoreGitDir(t *testing.T) {
+	// An allow-list that re-includes every directory and negations aimed
+	// at .git itself: none of them may reopen it.
+	content := []byte("*\n!*/\n!*.go\n!.git/\n!.git/config\n")
+
+	off := New()
+	off.AddPatterns("", content)
+	if off.Match(".git/config", false) {
+		t.Error("without AutoIgnoreGitDir, .git/config should follow the patterns")
+	}
+
+	m := NewWithOptions(MatcherOptions{AutoIgnoreGitDir: true})
+	m.AddPatterns("", content)
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{".git", true, true},
+		{".git/config", false, true},
+		{".git/hooks/pre-commit.go", false, true},
+		{"vendor/lib/.git/HEAD", false, true}, // any depth
+		{"vendor/lib/.git", false, true},      // a gitfile, decided by "*"
+		{"main.go", false, false},
+		{"src/", true, false},
+		{".github/ci.go", false, false},
+		{".gitignore", false, true}, // decided by "*"
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	r := m.MatchWithReason(".git/config", false)
+	want := MatchResult{Rule: ".git/", Source: "auto-ignore-git-dir", Ignored: true, Matched: true, PathDepth: 2}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("MatchWithReason(.git/config) = %+v, want %+v", r, want)
+	}
+	if m.RuleCount() != 5 {
+		t.Errorf("RuleCount() = %d, want 5: no rule is added", m.RuleCount())
+	}
+	empty := NewWithOptions(MatcherOptions{AutoIgnoreGitDir: true})
+	if !empty.MatchPrefix([]string{".git"}, true) || !empty.MatchPrefix([]string{"lib", ".git", "config"}, false) {
+		t.Error("MatchPrefix should report .git as ignored")
+	}
+	if empty.MatchPrefix([]string{".git"}, false) || empty.MatchPrefix([]string{"src"}, true) {
+		t.Error("MatchPrefix should only report .git directories")
+	}
+
+	// A file named .git is left to the patterns.
+	kept := NewWithOptions(MatcherOptions{AutoIgnoreGitDir: true})
+	if kept.Match("sub/.git", false) || !kept.Match("sub/.git", true) {
+		t.Error("only a .git directory should be ignored without patterns")
+	}
+
+	// Views, case folding, force-tracked directories and namespaces honour it.
+	if !m.Sub("vendor").Match(".git/HEAD", false) || !m.Snapshot().Match(".git/HEAD", false) {
+		t.Error("Sub and Snapshot should ignore .git")
+	}
+	ci := NewWithOptions(MatcherOptions{AutoIgnoreGitDir: true, CaseInsensitive: true})
+	if !ci.Match(".GIT/config", false) {
+		t.Error("CaseInsensitive should ignore .GIT")
+	}
+	m.AddForceTrackedDir("vendor")
+	if !m.Match("vendor/.git/HEAD", false) {
+		t.Error("a force-tracked directory should not reopen .git")
+	}
+	m.AddPatternsNS("x", "", []byte("!**\n"))
+	if !m.MatchNS("x", ".git/config", false) {
+		t.Error("MatchNS should ignore .git")
+	}
+	tree := m.Classify(FileTree{IsDir: true, Children: []FileTree{
+		{Name: ".git", IsDir: true, Children: []FileTree{{Name: "config"}}},
+	}})
+	if c := tree.Children[0].Children[0]; !c.Result.Ignored || c.Result.Source != "auto-ignore-git-dir" {
+		t.Errorf("Classify(.git/config) = %+v, want ignored by auto-ignore-git-dir", c.Result)
+	}
+}
+
+func TestMatch_DefaultIgnored(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{DefaultIgnored: true})
+	m.AddPatterns("", []byte("!*.go\n!docs/\n*_test.go\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"README.md", false, true},      // unmatched: ignored by default
+		{"main.go", false, false},       // allowed by !*.go
+		{"src/main.go", false, false},   // unmatched parent does not block
+		{"main_test.go", false, true},   // later rule re-ignores
+		{"docs", true, false},           // allowed directory
+		{"docs/guide.md", false, true},  // ...but not its unmatched contents
+		{"src", true, true},             // unmatched directory
+		{"../outside.go", false, false}, // invalid paths never match
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	result := m.MatchWithReason("README.md", false)
+	if !result.Ignored || result.Matched || result.Negated() {
+		t.Errorf("MatchWithReason(README.md) = %+v, want Ignored without a match", result)
+	}
+	if got := m.MatchMany([]string{"a.go", "a.txt"}, nil); got[0] || !got[1] {
+		t.Errorf("MatchMany() = %v, want [false true]", got)
+	}
+	if !m.MatchPrefix([]string{"anything"}, true) {
+		t.Error("MatchPrefix() = false, want true: every path may be default-ignored")
+	}
+}
+
+func TestMatch_StopAfterRules(t *testing.T) {
+	content := []byte("*.log\n!keep.log\nbuild/\n")
+	tests := []struct {
+		limit     int
+		keepLog   bool // keep.log ignored
+		buildFile bool // build/out.js ignored
+	}{
+		{0, false, true},
+		{1, true, false},
+		{2, false, false},
+		{3, false, true},
+		{10, false, true},
+	}
+	for _, tt := range tests {
+		m := NewWithOptions(MatcherOptions{StopAfterRules: tt.limit})
+		m.AddPatterns("", content)
+
+		if got := m.Match("keep.log", false); got != tt.keepLog {
+			t.Errorf("limit %d: Match(keep.log) = %v, want %v", tt.limit, got, tt.keepLog)
+		}
+		if got := m.Match("build/out.js", false); got != tt.buildFile {
+			t.Errorf("limit %d: Match(build/out.js) = %v, want %v", tt.limit, got, tt.buildFile)
+		}
+		if got := m.Snapshot().Match("build/out.js", false); got != tt.buildFile {
+			t.Errorf("limit %d: Snapshot().Match(build/out.js) = %v, want %v", tt.limit, got, tt.buildFile)
+		}
+		if got := m.MatchPrefix([]string{"build"}, true); got != tt.buildFile {
+			t.Errorf("limit %d: MatchPrefix(build) = %v, want %v", tt.limit, got, tt.buildFile)
+		}
+	}
+
+	m := NewWithOptions(MatcherOptions{StopAfterRules: 1})
+	m.AddPatterns("", content)
+	if r := m.MatchWithReason("keep.log", false); r.Line != 1 {
+		t.Errorf("MatchWithReason(keep.log).Line = %d, want 1 (only the first rule consulted)", r.Line)
+	}
+	if got := len(m.MatchingRules("keep.log", false)); got != 2 {
+		t.Errorf("MatchingRules(keep.log) returned %d rules, want 2: listings are not limited", got)
+	}
+	if got := m.RuleCount(); got != 3 {
+		t.Errorf("RuleCount() = %d, want 3", got)
+	}
+}
+
+func TestMatchEither(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n*.log\n!logs/\n*.d\nx.d/\n"))
+
+	tests := []struct {
+		path     string
+		want     bool
+		wantRule string
+	}{
+		{"build", true, "build/"},        // only as a directory
+		{"build/out.js", true, "build/"}, // inside it either way
+		{"debug.log", true, "*.log"},     // as a file
+		{"x.d", true, "*.d"},             // file decision reported when both ignore
+		{"src", false, ""},
+		{"logs", false, ""}, // "!logs/" re-includes only the directory
+	}
+	for _, tt := range tests {
+		r := m.MatchEither(tt.path)
+		if r.Ignored != tt.want || r.Rule != tt.wantRule {
+			t.Errorf("MatchEither(%q) = %+v, want ignored=%v rule=%q", tt.path, r, tt.want, tt.wantRule)
+		}
+		if either := m.Match(tt.path, false) || m.Match(tt.path, true); r.Ignored != either {
+			t.Errorf("MatchEither(%q).Ignored = %v, want Match as file OR as directory = %v", tt.path, r.Ignored, either)
+		}
+	}
+
+	var calls int
+	hooked := NewWithOptions(MatcherOptions{OnMatch: func(MatchResult) { calls++ }})
+	hooked.AddPatterns("", []byte("build/\n"))
+	hooked.MatchEither("build")
+	hooked.MatchEither("")
+	if calls != 2 {
+		t.Errorf("OnMatch called %d times, want once per MatchEither", calls)
+	}
+}
+
+func TestMatchWithoutRule(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n!keep.log\nbuild/\n"))
+
+	if m.Match("keep.log", false) {
+		t.Fatal("keep.log should be re-included by the negation")
+	}
+	r := m.MatchWithoutRule("keep.log", false, 1)
+	if !r.Ignored || r.Rule != "*.log" || r.Line != 1 {
+		t.Errorf("without the negation, keep.log = %+v, want ignored by *.log", r)
+	}
+	if r := m.MatchWithoutRule("keep.log", false, 0); r.Ignored || r.Rule != "!keep.log" {
+		t.Errorf("without *.log, keep.log = %+v, want kept by the negation alone", r)
+	}
+	if r := m.MatchWithoutRule("build/out.js", false, 2); r.Ignored {
+		t.Errorf("without build/, build/out.js = %+v, want kept", r)
+	}
+
+	// Out-of-range indexes remove nothing, and the matcher is unchanged.
+	for _, i := range []int{-1, 3} {
+		if got, want := m.MatchWithoutRule("keep.log", false, i), m.MatchWithReason("keep.log", false); !reflect.DeepEqual(got, want) {
+			t.Errorf("MatchWithoutRule(keep.log, %d) = %+v, want %+v", i, got, want)
+		}
+	}
+	if m.Match("keep.log", false) || m.RuleCount() != 3 {
+		t.Error("MatchWithoutRule must not modify the matcher")
+	}
+}
+
+func TestMatch_ReturnFirstNegationWins(t *testing.T) {
+	rules := []byte("*.log\n!keep.log\n*.log\nlogs/\n!logs/a.log\nvendor/\n!vendor/\nvendor/\n")
+	git := New()
+	git.AddPatterns("", rules)
+	first := NewWithOptions(MatcherOptions{ReturnFirstNegationWins: true})
+	first.AddPatterns("", rules)
+
+	tests := []struct {
+		path      string
+		isDir     bool
+		wantGit   bool
+		wantFirst bool
+	}{
+		{"keep.log", false, true, false},     // re-ignored by the later *.log only under git
+		{"debug.log", false, true, true},     // no negation involved
+		{"logs/a.log", false, true, true},    // parent directory still excludes
+		{"vendor", true, true, false},        // negated directory stays re-included
+		{"vendor/x.go", false, true, false},  // ...and so does its content
+		{"src/keep.log", false, true, false}, // floating negation
+	}
+	for _, tt := range tests {
+		if got := git.Match(tt.path, tt.isDir); got != tt.wantGit {
+			t.Errorf("git: Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.wantGit)
+		}
+		if got := first.Match(tt.path, tt.isDir); got != tt.wantFirst {
+			t.Errorf("first-negation-wins: Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.wantFirst)
+		}
+		if got := first.MatchWithReason(tt.path, tt.isDir).Ignored; got != tt.wantFirst {
+			t.Errorf("first-negation-wins: MatchWithReason(%q, %v).Ignored = %v, want %v", tt.path, tt.isDir, got, tt.wantFirst)
+		}
+	}
+
+	result := first.MatchWithReason("keep.log", false)
+	if result.Rule != "!keep.log" || result.Line != 2 {
+		t.Errorf("MatchWithReason(keep.log) = %+v, want the locking negation on line 2", result)
+	}
+
+	// A negation only locks once it has matched; earlier ignores are still
+	// overridden by it and ignores after a non-matching negation apply.
+	if !first.Match("other.log", false) {
+		t.Error("other.log should be ignored: the negation does not match it")
+	}
+}
+
+func TestMatchIgnoringNegations(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n!important.log\nbuild/\n!build/\n!*.md\n"))
 
 	tests := []struct {
-		name  string
-		path  string
-		isDir bool
-		want  bool
+		path        string
+		isDir       bool
+		wantRaw     bool
+		wantRawRule string
+		wantNormal  bool
 	}{
-		{"test.log file", "test.log", false, true},
-		{"debug.log file", "debug.log", false, true},
-		{"important.log negated", "important.log", false, false}, // negated
-		{"main.go no match", "main.go", false, false},
-		{"build dir", "build", true, true},
-		{"build not a dir", "build", false, false}, // not a dir
-		{"src/test.log nested", "src/test.log", false, true},
-		{"src/build nested dir", "src/build", true, true},
+		{"important.log", false, true, "*.log", false},
+		{"debug.log", false, true, "*.log", true},
+		{"build/out.js", false, true, "build/", false},
+		{"README.md", false, false, "", false},
+		{"main.go", false, false, "", false},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := m.Match(tt.path, tt.isDir)
-			if got != tt.want {
-				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
-			}
-		})
+		raw := m.MatchIgnoringNegations(tt.path, tt.isDir)
+		if raw.Ignored != tt.wantRaw || raw.Rule != tt.wantRawRule {
+			t.Errorf("MatchIgnoringNegations(%q) = %+v, want Ignored=%v Rule=%q", tt.path, raw, tt.wantRaw, tt.wantRawRule)
+		}
+		if raw.Negated() {
+			t.Errorf("MatchIgnoringNegations(%q) reported a negation: %+v", tt.path, raw)
+		}
+		if got := m.Match(tt.path, tt.isDir); got != tt.wantNormal {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.wantNormal)
+		}
 	}
-}
 
-func TestMatch_WindowsPaths(t *testing.T) {
-	if runtime.GOOS != "windows" {
-		t.Skip("backslash-to-slash conversion only applies on Windows")
+	calls := 0
+	hooked := NewWithOptions(MatcherOptions{OnMatch: func(MatchResult) { calls++ }})
+	hooked.AddPatterns("", []byte("*.log\n!important.log\n"))
+	hooked.MatchIgnoringNegations("important.log", false)
+	if calls != 0 {
+		t.Errorf("OnMatch called %d times, want 0", calls)
 	}
+}
 
+func TestMatchSafe(t *testing.T) {
 	m := New()
-	m.AddPatterns("", []byte("*.log\nsrc/build/\n"))
+	m.AddPatterns("", []byte("b\n*.log\n"))
+	m.AddPatterns("a", []byte("/b\n!x\n"))
 
 	tests := []struct {
-		name  string
-		path  string
-		isDir bool
-		want  bool
+		path     string
+		wantOK   bool
+		wantRule string
 	}{
-		{"src\\test.log file", "src\\test.log", false, true},
-		{"src\\build dir", "src\\build", true, true},
-		{"src\\lib\\debug.log nested", "src\\lib\\debug.log", false, true},
+		{"../outside/x", false, ""},
+		{"a/../../x", false, ""},
+		{"/../x", false, ""},
+		{"./..", false, ""},
+		{"", false, ""},
+		{"a/..", false, ""},
+		{"a/../b", true, "b"},
+		{"a/b", true, "/b"},
+		{"a/./c/../x.log", true, "*.log"},
+		{"x..log", true, "*.log"},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := m.Match(tt.path, tt.isDir)
-			if got != tt.want {
-				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
-			}
-		})
+		result, ok := m.MatchSafe(tt.path, false)
+		if ok != tt.wantOK || result.Rule != tt.wantRule {
+			t.Errorf("MatchSafe(%q) = %+v, %v; want Rule=%q, %v", tt.path, result, ok, tt.wantRule, tt.wantOK)
+		}
+		if ok && !reflect.DeepEqual(result, m.MatchWithReason(tt.path, false)) {
+			t.Errorf("MatchSafe(%q) disagrees with MatchWithReason", tt.path)
+		}
+	}
+
+	// The root of a Sub view is its basePath.
+	if _, ok := m.Sub("a").MatchSafe("../b", false); ok {
+		t.Error("Sub view: \"../b\" escapes the view and should not be ok")
+	}
+
+	// Escapes are checked after percent-decoding.
+	decoding := NewWithOptions(MatcherOptions{URLDecodePaths: true})
+	if _, ok := decoding.MatchSafe("%2e%2e/x", false); ok {
+		t.Error("URLDecodePaths: \"%2e%2e/x\" should not be ok")
+	}
+	if _, ok := decoding.MatchSafe("%252e%252e/x", false); !ok {
+		t.Error("URLDecodePaths: decoding once leaves \"%2e%2e/x\", which is a plain name")
 	}
 }
 
-func TestMatch_EmptyPath(t *testing.T) {
+func TestMatchNative(t *testing.T) {
 	m := New()
-	m.AddPatterns("", []byte("*.log\n"))
+	m.AddPatterns("", []byte("*.log\nbuild/\n"))
+	root := "/home/u/proj"
 
-	if m.Match("", false) {
-		t.Error("Match('') should return false")
+	tests := []struct {
+		path    string
+		isDir   bool
+		wantOK  bool
+		ignored bool
+	}{
+		{"/home/u/proj/src/x.log", false, true, true},
+		{"/home/u/proj/src/main.go", false, true, false},
+		{"/home/u/proj/build/", false, true, true}, // trailing separator: a directory
+		{"src/x.log", false, true, true},           // already relative
+		{"/home/u/other/x.log", false, false, false},
+		{"/home/u/proj", true, false, false}, // the root itself
+		{"../x.log", false, false, false},
+	}
+	for _, tt := range tests {
+		result, ok := m.MatchNative(root, tt.path, tt.isDir)
+		if ok != tt.wantOK || result.Ignored != tt.ignored {
+			t.Errorf("MatchNative(%q, %q) = %+v, %v; want Ignored=%v, %v", root, tt.path, result, ok, tt.ignored, tt.wantOK)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		result, ok := m.MatchNative(`C:\proj`, `\\?\C:\proj\src\x.log`, false)
+		if !ok || !result.Ignored {
+			t.Errorf("MatchNative(extended-length path) = %+v, %v; want ignored", result, ok)
+		}
 	}
 }
 
-func TestMatch_CaseInsensitive(t *testing.T) {
-	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
-	m.AddPatterns("", []byte("BUILD/\n*.LOG\n"))
+func TestIgnoreDepth(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\nnode_modules/\nbuild/\n!keep.log\n"))
 
 	tests := []struct {
-		name  string
 		path  string
 		isDir bool
-		want  bool
+		want  int
 	}{
-		{"BUILD uppercase dir", "BUILD", true, true},
-		{"build lowercase dir", "build", true, true},
-		{"Build mixed dir", "Build", true, true},
-		{"test.LOG uppercase ext", "test.LOG", false, true},
-		{"test.log lowercase ext", "test.log", false, true},
-		{"test.Log mixed ext", "test.Log", false, true},
+		{"a/b/c/d.log", false, 3},       // extension rule: the leaf itself
+		{"x/node_modules/y", false, 1},  // directory rule: the directory
+		{"x/node_modules", true, 1},     // the directory itself
+		{"build/out/app.log", false, 0}, // outermost ignored ancestor wins
+		{"a/keep.log", false, -1},       // re-included
+		{"build/keep.log", false, 0},    // ...but not inside an ignored directory
+		{"src/main.go", false, -1},
+		{"", false, -1},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := m.Match(tt.path, tt.isDir)
-			if got != tt.want {
-				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
-			}
-		})
+		if got := m.IgnoreDepth(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("IgnoreDepth(%q, %v) = %d, want %d", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	// Indexes are into the path as given to a Sub view.
+	sub := m.Sub("x")
+	if got := sub.IgnoreDepth("node_modules/y", false); got != 0 {
+		t.Errorf("Sub(x).IgnoreDepth(node_modules/y) = %d, want 0", got)
+	}
+	if got := m.Sub("build/out").IgnoreDepth("a/b.txt", false); got != 0 {
+		t.Errorf("Sub(build/out).IgnoreDepth(a/b.txt) = %d, want 0 for an ignored base", got)
+	}
+
+	// Force-tracking stops ancestors above the tracked directory counting.
+	m.AddPatterns("build/keepme", []byte("*.tmp\n"))
+	m.AddForceTrackedDir("build/keepme")
+	if got := m.IgnoreDepth("build/keepme/sub/x.tmp", false); got != 3 {
+		t.Errorf("IgnoreDepth under tracked dir = %d, want 3", got)
+	}
+
+	// Under DefaultIgnored an unmatched ancestor does not count.
+	d := NewWithOptions(MatcherOptions{DefaultIgnored: true})
+	d.AddPatterns("", []byte("!*.go\n"))
+	if got := d.IgnoreDepth("src/readme.md", false); got != 1 {
+		t.Errorf("DefaultIgnored: IgnoreDepth(src/readme.md) = %d, want 1", got)
 	}
 }
 
@@ -523,6 +2119,35 @@ func TestMatchWithReason_LastMatchWins(t *testing.T) {
 	}
 }
 
+func TestMatchWithReason_TieBreakAcrossBasePaths(t *testing.T) {
+	// Parent before child, as git reads them: the nested rule wins, which
+	// agrees with git's deeper-file-wins precedence.
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+	m.AddPatterns("src", []byte("!debug.log\n"))
+	r := m.MatchWithReason("src/debug.log", false)
+	if r.Ignored || r.BasePath != "src" {
+		t.Errorf("parent first: got %+v, want the src negation to win", r)
+	}
+
+	// Child before parent: load order still decides, so the root rule wins.
+	m = New()
+	m.AddPatterns("src", []byte("!debug.log\n"))
+	m.AddPatterns("", []byte("*.log\n"))
+	r = m.MatchWithReason("src/debug.log", false)
+	if !r.Ignored || r.BasePath != "" || r.Rule != "*.log" {
+		t.Errorf("child first: got %+v, want the root *.log to win", r)
+	}
+
+	// The same holds for two rules of equal specificity.
+	m = New()
+	m.AddPatterns("src", []byte("debug.log\n"))
+	m.AddPatterns("", []byte("!debug.log\n"))
+	if m.Match("src/debug.log", false) {
+		t.Error("the later root negation should win over the earlier nested rule")
+	}
+}
+
 func TestMatcher_Concurrent(t *testing.T) {
 	m := New()
 	m.AddPatterns("", []byte("*.log\n*.tmp\nbuild/\n**/cache/\n"))
@@ -791,6 +2416,87 @@ foo/**
 	}
 }
 
+// TestMatcher_DoubleStarWithWildcards checks ** followed by wildcard
+// segments across several directory levels, first with the hard-cap budget
+// and then with the default, to make sure budget accounting never cuts a
+// legitimate deep match short.
+func TestMatcher_DoubleStarWithWildcards(t *testing.T) {
+	deep := strings.Repeat("d/", 40)
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b.go", true},
+		{"src/**/*.go", "src/a/b/c.go", true},
+		{"src/**/*.go", "src/a/b/c/d.go", true},
+		{"src/**/*.go", "src/" + deep + "x.go", true},
+		{"src/**/*.go", "src/a/b/c.txt", false},
+		{"src/**/*.go", "lib/src/a.go", false},
+		{"**/*_test.go", "x_test.go", true},
+		{"**/*_test.go", "a/b/c/x_test.go", true},
+		{"**/*_test.go", deep + "x_test.go", true},
+		{"**/*_test.go", "a/b/c/x.go", false},
+		{"a/**/b*/**/c", "a/b/c", true},
+		{"a/**/b*/**/c", "a/x/b1/y/c", true},
+		{"a/**/b*/**/c", "a/x/y/b2/z/w/c", true},
+		{"a/**/b*/**/c", "a/" + deep + "b/" + deep + "c", true},
+		{"a/**/b*/**/c", "a/x/c", false},
+		{"a/**/b*/**/c", "z/a/b/c", false},
+	}
+
+	for _, budget := range []int{-1, 0} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("budget=%d/%s/%s", budget, tt.pattern, tt.path), func(t *testing.T) {
+				m := NewWithOptions(MatcherOptions{MaxBacktrackIterations: budget})
+				m.AddPatterns("", []byte(tt.pattern+"\n"))
+				if got := m.Match(tt.path, false); got != tt.want {
+					t.Errorf("Match(%q) with %q = %v, want %v", tt.path, tt.pattern, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestMatch_NegatedAnchored covers "!/pattern" negations: the re-include
+// must stay anchored to the .gitignore's directory (root or basePath).
+func TestMatch_NegatedAnchored(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		content string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"!/build re-includes root dir", "", "build/\n!/build\n", "build", true, false},
+		{"!/build/ re-includes root contents", "", "build/\n!/build/\n", "build/out.js", false, false},
+		{"!/build leaves nested dir", "", "build/\n!/build\n", "sub/build", true, true},
+		{"!/build leaves nested contents", "", "build/\n!/build\n", "sub/build/out.js", false, true},
+		{"!/build/ re-includes root dir", "", "build/\n!/build/\n", "build", true, false},
+		{"!/build/ leaves nested dir", "", "build/\n!/build/\n", "a/b/build", true, true},
+		{"!/build/ skips root file", "", "build\n!/build/\n", "build", false, true},
+		{"!/src/keep.log root", "", "*.log\n!/src/keep.log\n", "src/keep.log", false, false},
+		{"!/src/keep.log nested", "", "*.log\n!/src/keep.log\n", "lib/src/keep.log", false, true},
+		{"!/src/keep.log sibling", "", "*.log\n!/src/keep.log\n", "src/other.log", false, true},
+		{"!/build in basePath", "app", "build\n!/build\n", "app/build", true, false},
+		{"!/build in basePath nested", "app", "build\n!/build\n", "app/sub/build", true, true},
+		{"!/build outside basePath", "app", "build\n!/build\n", "build", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.AddPatterns(tt.base, []byte(tt.content))
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
 // Examples from spec Section 4
 func TestMatchWithReason_SpecExamples(t *testing.T) {
 	m := New()
@@ -948,3 +2654,273 @@ func BenchmarkMatch_Simple(b *testing.B) {
 		m.Match("src/main.go", false)
 	}
 }
+
+func TestMatchWithReason_AncestorRule(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n*.js\n!keep.js\n"))
+
+	// git reports the rule that ignored the outermost ignored ancestor.
+	tests := []struct {
+		path string
+		rule string
+		line int
+	}{
+		{"build/a.js", "build/", 1},
+		{"build/keep.js", "build/", 1},
+		{"src/build/x/y.txt", "build/", 1},
+		{"src/a.js", "*.js", 2},
+	}
+	for _, tt := range tests {
+		got := m.MatchWithReason(tt.path, false)
+		if !got.Ignored || got.Rule != tt.rule || got.Line != tt.line {
+			t.Errorf("MatchWithReason(%q) = %v, want ignored by %q (line %d)", tt.path, got, tt.rule, tt.line)
+		}
+		if !m.Match(tt.path, false) {
+			t.Errorf("Match(%q) = false, want true", tt.path)
+		}
+	}
+
+	if got := m.MatchWithReason("keep.js", false); got.Ignored || got.Rule != "!keep.js" {
+		t.Errorf("MatchWithReason(keep.js) = %v, want re-included by !keep.js", got)
+	}
+}
+
+func TestAddPatternsRebased_FromRoot(t *testing.T) {
+	m := New()
+	m.AddPatternsRebased("", "vendor/lib", []byte("/build\n*.log\ndocs/tmp/\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"vendor/lib/build", true, true},
+		{"vendor/lib/src/build", true, false}, // anchored at the new base
+		{"build", true, false},                // not at the old root
+		{"vendor/lib/a/debug.log", false, true},
+		{"debug.log", false, false}, // floating, but only under the new base
+		{"vendor/lib/docs/tmp/x", false, true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	want := New()
+	want.AddPatterns("vendor/lib", []byte("/build\n*.log\ndocs/tmp/\n"))
+	paths := []string{"vendor/lib/build", "vendor/lib/docs/tmp/x", "vendor/lib/x.log", "build"}
+	if !Equivalent(m, want, paths, []bool{true}) {
+		t.Errorf("rebasing from root should equal AddPatterns at the new base: %v", Diff(m, want, paths, []bool{true}))
+	}
+}
+
+func TestAddPatternsRebased_StripsComponents(t *testing.T) {
+	m := New()
+	m.AddPatternsRebased("proj", "vendor/proj", []byte("proj/build/\n/*/gen\nproj/**/cache\n*.tmp\nother/x\n/proj\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"vendor/proj/build/out.js", false, true},
+		{"vendor/proj/proj/build/out.js", false, false},
+		{"vendor/proj/gen", true, true},
+		{"vendor/proj/cache", true, true},
+		{"vendor/proj/a/b/cache", true, true},
+		{"vendor/proj/x.tmp", false, true},
+		{"vendor/proj/other/x", false, false},
+		{"other/x", false, false},
+		{"vendor/proj", true, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	// Rules report their text as written.
+	if got := m.MatchWithReason("vendor/proj/build", true); got.Rule != "proj/build/" || got.BasePath != "vendor/proj" {
+		t.Errorf("MatchWithReason(vendor/proj/build) = %v, want rule %q base %q", got, "proj/build/", "vendor/proj")
+	}
+
+	var skipped []string
+	for _, w := range m.Warnings() {
+		skipped = append(skipped, w.Pattern)
+	}
+	if !equalStrings(skipped, []string{"other/x", "/proj"}) {
+		t.Errorf("Warnings() patterns = %q, want %q", skipped, []string{"other/x", "/proj"})
+	}
+	if m.RuleCount() != 4 {
+		t.Errorf("RuleCount() = %d, want 4", m.RuleCount())
+	}
+}
+
+func TestAddPatternsRebased_DoubleStarAndCase(t *testing.T) {
+	m := New()
+	m.AddPatternsRebased("a/b", "x", []byte("a/**/y\n"))
+	if m.RuleCount() != 0 || len(m.Warnings()) != 1 {
+		t.Errorf("** in stripped components: RuleCount() = %d, Warnings() = %+v; want 0 rules, 1 warning", m.RuleCount(), m.Warnings())
+	}
+
+	// The stripped component no longer counts toward specificity.
+	rebased := New()
+	rebased.AddPatternsRebased("proj", "x", []byte("proj/build/\n"))
+	plain := New()
+	plain.AddPatterns("x", []byte("/build/\n"))
+	if got, want := RuleSpecificity(rebased.rules[0].info(0)), RuleSpecificity(plain.rules[0].info(0)); got != want {
+		t.Errorf("RuleSpecificity(rebased proj/build/) = %d, want %d as for x:/build/", got, want)
+	}
+
+	ci := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	ci.AddPatternsRebased("Proj", "vendor", []byte("PROJ/Build/\n"))
+	if !ci.Match("vendor/build", true) {
+		t.Error("case-insensitive rebase should strip PROJ for fromBase Proj")
+	}
+}
+
+// cancelAfterCheck is a context that passes MatchContext's up-front Err
+// check but reports cancellation from then on, so the cancellation is
+// observed mid-match.
+type cancelAfterCheck struct {
+	context.Context
+	checked bool
+	done    chan struct{}
+}
+
+func (c *cancelAfterCheck) Done() <-chan struct{} { return c.done }
+
+func (c *cancelAfterCheck) Err() error {
+	if !c.checked {
+		c.checked = true
+		return nil
+	}
+	return context.Canceled
+}
+
+func TestMatchContext(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n*.log\n!keep.log\n"))
+
+	got, err := m.MatchContext(context.Background(), "build/out.js", false)
+	if err != nil || !reflect.DeepEqual(got, m.MatchWithReason("build/out.js", false)) {
+		t.Errorf("MatchContext(background) = %v, %v; want MatchWithReason result", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err := m.MatchContext(ctx, "debug.log", false); !errors.Is(err, context.Canceled) || got.Matched {
+		t.Errorf("MatchContext(canceled) = %v, %v; want zero result, context.Canceled", got, err)
+	}
+}
+
+func TestMatchContext_CanceledMidMatch(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&sb, "*.ext%d\n", i)
+	}
+	m := New()
+	m.AddPatterns("", []byte(sb.String()))
+
+	ctx := &cancelAfterCheck{Context: context.Background(), done: make(chan struct{})}
+	close(ctx.done)
+
+	start := time.Now()
+	got, err := m.MatchContext(ctx, "src/main.go", false)
+	if !errors.Is(err, context.Canceled) || got.Matched {
+		t.Fatalf("MatchContext = %v, %v; want zero result, context.Canceled", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("MatchContext took %v after cancellation, want prompt return", elapsed)
+	}
+}
+
+func TestMatchContext_DeadlineDuringBacktracking(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{MaxBacktrackIterations: -1})
+	m.AddPatterns("", []byte(strings.Repeat("*a", 30)+"b\n"))
+	name := strings.Repeat("a", 200)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := m.MatchContext(ctx, name, false)
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Skipf("match finished in %v before the deadline", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("MatchContext error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("MatchContext took %v, want it bounded by the 10ms deadline", elapsed)
+	}
+}
+
+func TestAddPatterns_RejectLegacyLineEndings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []ParseWarning
+	}{
+		{"CRLF", "*.log\r\nbuild/\r\n", []ParseWarning{{Line: 1, Message: "CRLF line ending, expected LF", BasePath: "src"}}},
+		{"CR only", "*.log\rbuild/\r", []ParseWarning{{Line: 1, Message: "CR-only line ending, expected LF", BasePath: "src"}}},
+		{"LF", "*.log\nbuild/\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewWithOptions(MatcherOptions{RejectLegacyLineEndings: true})
+			m.AddPatterns("src", []byte(tt.content))
+
+			got := m.Warnings()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Warnings() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Warnings()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+			// Content is still parsed.
+			if m.RuleCount() != 2 || !m.Match("src/a.log", false) {
+				t.Errorf("RuleCount() = %d, want 2 rules still parsed", m.RuleCount())
+			}
+		})
+	}
+
+	d := New()
+	d.AddPatterns("", []byte("*.log\r\n"))
+	if w := d.Warnings(); len(w) != 0 {
+		t.Errorf("without the option Warnings() = %+v, want none", w)
+	}
+}
+
+func TestMatchWithReason_ContributingBasePaths(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.tmp\n"))
+	m.AddPatternsWithSource("src", "src/.gitignore", []byte("!keep.tmp\n*.bak\ngen/\n"))
+	m.AddPatternsWithSource("src/lib", "src/lib/.gitignore", []byte("keep.*\n"))
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"src/keep.tmp", []string{"", "src"}},
+		{"src/lib/keep.tmp", []string{"", "src", "src/lib"}},
+		{"src/lib/x.bak", []string{"src"}},
+		{"src/gen/keep.tmp", []string{"src"}}, // the scopes that ignored src/gen
+		{"keep.tmp", []string{""}},
+		{"main.go", nil},
+	}
+	for _, tt := range tests {
+		if got := m.MatchWithReason(tt.path, false).ContributingBasePaths; !equalStrings(got, tt.want) {
+			t.Errorf("MatchWithReason(%q).ContributingBasePaths = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// The decisive rule's basePath is the last contributor here.
+	if r := m.MatchWithReason("src/keep.tmp", false); r.Ignored || r.BasePath != "src" {
+		t.Errorf("MatchWithReason(src/keep.tmp) = %+v, want re-included by src", r)
+	}
+}
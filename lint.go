@@ -0,0 +1,244 @@
+package ignore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintKind identifies the category of a LintIssue.
+type LintKind string
+
+const (
+	// LintShadowed marks an ignore rule that can never change a match
+	// outcome because an earlier, broader ignore rule already matches
+	// every path it could, with no negation in between.
+	LintShadowed LintKind = "shadowed"
+
+	// LintLikelyDirectory marks a single-segment pattern without a trailing
+	// slash whose name is conventionally a build-output directory ("build",
+	// "dist"). It also matches files of that name, which is rarely intended.
+	// Advisory only.
+	LintLikelyDirectory LintKind = "likely-directory"
+
+	// LintScopeConfusion marks an anchored negation in a nested scope whose
+	// pattern repeats an anchored ignore rule from an enclosing scope, such
+	// as "!/dist" in app/.gitignore next to "/dist" in the root. Anchored
+	// patterns are relative to their own scope, so the negation re-includes
+	// app/dist and never touches the dist the outer rule ignores. Advisory
+	// only.
+	LintScopeConfusion LintKind = "scope-confusion"
+)
+
+// artifactDirs are the names LintLikelyDirectory treats as build-output
+// directories.
+var artifactDirs = map[string]bool{
+	"bin":    true,
+	"build":  true,
+	"dist":   true,
+	"out":    true,
+	"target": true,
+}
+
+// LintIssue is one diagnostic reported by Lint.
+type LintIssue struct {
+	// Kind is the category of the issue.
+	Kind LintKind
+
+	// Rule is the rule the issue is about.
+	Rule RuleInfo
+
+	// Related lists other rules involved in the issue. For LintShadowed it
+	// holds the earlier rule doing the shadowing; for LintScopeConfusion,
+	// the enclosing-scope rule the negation appears to target.
+	Related []RuleInfo
+
+	// Message is a human-readable description, naming the lines involved.
+	Message string
+}
+
+// Lint inspects the loaded rules for likely mistakes and returns one
+// LintIssue per finding, in rule order. It never changes the Matcher.
+//
+// Currently reported:
+//
+//   - LintShadowed: a later ignore rule whose matched set is provably a
+//     subset of an earlier ignore rule in the same or an enclosing scope,
+//     with no negation in between (for example "debug.log" after "*.log").
+//     The subset test is deliberately conservative — it only recognizes a
+//     single-segment floating earlier rule that is a literal name or a "*"
+//     followed by a literal suffix — so a rule that is not reported may
+//     still be dead.
+//   - LintLikelyDirectory: a build-output name such as "dist" without a
+//     trailing slash.
+//   - LintScopeConfusion: an anchored negation in a nested scope that
+//     repeats an anchored ignore rule of an enclosing scope, which it
+//     cannot affect.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) Lint() []LintIssue {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var issues []LintIssue
+	var broad []int // indexes of earlier rules that may shadow later ones
+	for i := range m.rules {
+		r := &m.rules[i]
+		if r.negate {
+			if j, ok := m.scopeTarget(r); ok {
+				e := &m.rules[j]
+				ri, ei := r.info(i), e.info(j)
+				issues = append(issues, LintIssue{
+					Kind:    LintScopeConfusion,
+					Rule:    ri,
+					Related: []RuleInfo{ei},
+					Message: fmt.Sprintf("%q (line %d) in %s only re-includes %s, not %s ignored by %q (line %d) in %s",
+						ri.Pattern, ri.Line, scopeName(r.basePath), anchoredTarget(r), anchoredTarget(e), ei.Pattern, ei.Line, scopeName(e.basePath)),
+				})
+			}
+
+			// A negation may re-include part of an overlapping broad rule's
+			// set; a later rule could then re-ignore it, so it is live.
+			kept := broad[:0]
+			for _, j := range broad {
+				base := m.rules[j].basePath
+				if !scopeCovers(base, r.basePath) && !scopeCovers(r.basePath, base) {
+					kept = append(kept, j)
+				}
+			}
+			broad = kept
+			continue
+		}
+		for _, j := range broad {
+			if e := &m.rules[j]; shadows(e, r) {
+				ei, ri := e.info(j), r.info(i)
+				issues = append(issues, LintIssue{
+					Kind:    LintShadowed,
+					Rule:    ri,
+					Related: []RuleInfo{ei},
+					Message: fmt.Sprintf("%q (line %d) is shadowed by %q (line %d)", ri.Pattern, ri.Line, ei.Pattern, ei.Line),
+				})
+				break
+			}
+		}
+		if likelyDirectory(r) {
+			ri := r.info(i)
+			issues = append(issues, LintIssue{
+				Kind:    LintLikelyDirectory,
+				Rule:    ri,
+				Message: fmt.Sprintf("%q (line %d) also matches files named %s; use %q to match only the directory", ri.Pattern, ri.Line, r.segments[0].value, ri.Pattern+"/"),
+			})
+		}
+		if _, ok := floatingSuffix(r); ok {
+			broad = append(broad, i)
+		}
+	}
+	return issues
+}
+
+// shadows reports whether every path matched by the later rule r is also
+// matched by the earlier ignore rule e. False means "not provable", not
+// "not shadowed".
+func shadows(e, r *rule) bool {
+	if e.dirOnly != r.dirOnly || len(r.segments) == 0 || !scopeCovers(e.basePath, r.basePath) {
+		return false
+	}
+	suffix, ok := floatingSuffix(e)
+	if !ok {
+		return false
+	}
+	last := r.segments[len(r.segments)-1]
+	if last.doubleStar {
+		return false
+	}
+	es := e.segments[0]
+	if !es.wildcard {
+		// Literal name: r's final segment must be the same literal.
+		return !last.wildcard && last.value == es.value
+	}
+	// "*" + suffix: r's final segment must end with the same literal bytes.
+	tail := len(last.value) - len(suffix)
+	return tail >= 0 && last.value[tail:] == suffix
+}
+
+// floatingSuffix reports whether r is a single-segment, floating, non-negated
+// rule of the shapes the shadowing test understands: a plain literal name
+// (suffix is the name) or "*" followed by a literal suffix such as "*.log".
+func floatingSuffix(r *rule) (string, bool) {
+	if r.negate || r.anchored || len(r.segments) != 1 {
+		return "", false
+	}
+	seg := r.segments[0]
+	if seg.doubleStar {
+		return "", false
+	}
+	if !seg.wildcard {
+		return seg.value, true
+	}
+	if seg.starCount != 1 || seg.value[0] != '*' {
+		return "", false
+	}
+	suffix := seg.value[1:]
+	if strings.ContainsAny(suffix, "*?[]\\") {
+		return "", false
+	}
+	return suffix, true
+}
+
+// likelyDirectory reports whether r is a non-negated, non-directory-only
+// single-segment rule naming one of the artifactDirs.
+func likelyDirectory(r *rule) bool {
+	return !r.negate && !r.dirOnly && len(r.segments) == 1 && artifactDirs[r.segments[0].value]
+}
+
+// scopeTarget returns the index of an anchored ignore rule in a scope
+// strictly enclosing that of the anchored negation r whose segments are the
+// same as r's — the rule r was most likely written to re-include. Callers
+// must hold mu.
+func (m *Matcher) scopeTarget(r *rule) (int, bool) {
+	if !r.anchored || r.basePath == "" {
+		return 0, false
+	}
+	for j := range m.rules {
+		e := &m.rules[j]
+		if e.negate || !e.anchored || e.dirOnly != r.dirOnly || e.basePath == r.basePath ||
+			!scopeCovers(e.basePath, r.basePath) || len(e.segments) != len(r.segments) {
+			continue
+		}
+		same := true
+		for k := range e.segments {
+			if e.segments[k] != r.segments[k] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// anchoredTarget renders the path an anchored rule matches, relative to the
+// root: its basePath followed by its segments.
+func anchoredTarget(r *rule) string {
+	parts := make([]string, 0, len(r.segments)+1)
+	if r.basePath != "" {
+		parts = append(parts, r.basePath)
+	}
+	for _, seg := range r.segments {
+		if seg.doubleStar {
+			parts = append(parts, "**")
+		} else {
+			parts = append(parts, seg.value)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// scopeName describes a basePath for messages.
+func scopeName(basePath string) string {
+	if basePath == "" {
+		return "the root"
+	}
+	return basePath
+}
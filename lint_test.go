@@ -0,0 +1,196 @@
+package ignore
+
+import (
+	"testing"
+)
+
+func TestLint_Shadowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // shadowed patterns, in order
+	}{
+		{"extension then literal", "*.log\ndebug.log\n", []string{"debug.log"}},
+		{"intervening negation", "*.log\n!x.log\nx.log\n", nil},
+		{"anchored literal", "*.log\nsrc/foo.log\n", []string{"src/foo.log"}},
+		{"wildcard subset", "*.log\ndebug-*.log\n**/*.log\n", []string{"debug-*.log", "**/*.log"}},
+		{"literal duplicate", "build\nbuild\n", []string{"build"}},
+		{"literal nested", "node_modules/\npkg/node_modules/\n", []string{"pkg/node_modules/"}},
+		{"later is broader", "debug.log\n*.log\n", nil},
+		{"different suffix", "*.log\ndebug.txt\nfoo.logs\n", nil},
+		{"dirOnly mismatch", "build/\nbuild\n", nil},
+		{"anchored earlier rule", "/*.log\nsrc/debug.log\n", nil},
+		{"trailing double star", "*.log\nlogs/**\n", nil},
+		{"escaped suffix not understood", "*\\*\nfoo\\*\n", nil},
+		{"ignore after negation reopened", "*.log\n!keep.log\n*.log\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.AddPatterns("", []byte(tt.content))
+			var got []string
+			for _, issue := range m.Lint() {
+				if issue.Kind == LintShadowed {
+					got = append(got, issue.Rule.Pattern)
+				}
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("Lint() shadowed = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLint_NoSegments(t *testing.T) {
+	// The parser now rejects "///", but a rule without segments, which
+	// UnmarshalBinary still accepts, must not be indexed into.
+	m := New()
+	m.AddPatterns("", []byte("*\nbuild\n"))
+	m.rules = append(m.rules, rule{pattern: "///", anchored: true})
+	for _, issue := range m.Lint() {
+		if issue.Rule.Pattern == "///" {
+			t.Errorf("Lint() reported %+v for a rule without segments", issue)
+		}
+	}
+	if shadows(&m.rules[0], &m.rules[2]) {
+		t.Error("shadows() = true for a rule without segments")
+	}
+}
+
+func TestLint_ShadowedDetails(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("# logs\n*.log\n\ndebug.log\n"))
+
+	issues := m.Lint()
+	if len(issues) != 1 {
+		t.Fatalf("Lint() = %+v, want 1 issue", issues)
+	}
+	issue := issues[0]
+	if issue.Rule.Line != 4 || len(issue.Related) != 1 || issue.Related[0].Line != 2 {
+		t.Errorf("Lint() lines: rule %d, related %+v; want rule 4 shadowed by line 2", issue.Rule.Line, issue.Related)
+	}
+	want := `"debug.log" (line 4) is shadowed by "*.log" (line 2)`
+	if issue.Message != want {
+		t.Errorf("Message = %q, want %q", issue.Message, want)
+	}
+}
+
+func TestLint_ShadowedScopes(t *testing.T) {
+	m := New()
+	m.AddPatterns("src", []byte("*.log\n"))
+	m.AddPatterns("", []byte("debug.log\n"))    // wider scope: not shadowed
+	m.AddPatterns("src/lib", []byte("a.log\n")) // nested scope: shadowed
+	m.AddPatterns("srcx", []byte("b.log\n"))    // sibling scope: not shadowed
+
+	issues := m.Lint()
+	if len(issues) != 1 || issues[0].Rule.BasePath != "src/lib" {
+		t.Errorf("Lint() = %+v, want only src/lib's a.log", issues)
+	}
+}
+
+func TestLint_LikelyDirectory(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"bare build", "build\n", []string{"build"}},
+		{"anchored dist", "/dist\n", []string{"/dist"}},
+		{"all artifact names", "bin\nout\ntarget\n", []string{"bin", "out", "target"}},
+		{"wildcard", "*.log\n", nil},
+		{"already dir-only", "build/\n", nil},
+		{"negation", "*\n!build\n", nil},
+		{"nested path", "src/build\n", nil},
+		{"other name", "vendor\nbuilds\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.AddPatterns("", []byte(tt.content))
+			var got []string
+			for _, issue := range m.Lint() {
+				if issue.Kind == LintLikelyDirectory {
+					got = append(got, issue.Rule.Pattern)
+				}
+			}
+			if !equalStrings(got, tt.want) {
+				t.Errorf("Lint() likely-directory = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLint_LikelyDirectoryMessage(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n/build\n"))
+
+	issues := m.Lint()
+	if len(issues) != 1 || issues[0].Kind != LintLikelyDirectory {
+		t.Fatalf("Lint() = %+v, want one likely-directory issue", issues)
+	}
+	want := `"/build" (line 2) also matches files named build; use "/build/" to match only the directory`
+	if issues[0].Message != want {
+		t.Errorf("Message = %q, want %q", issues[0].Message, want)
+	}
+}
+
+func TestLint_ScopeConfusion(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("/dist/\n"))
+	m.AddPatternsWithSource("app", "app/.gitignore", []byte("*.tmp\n!/dist/\n"))
+
+	var issues []LintIssue
+	for _, issue := range m.Lint() {
+		if issue.Kind == LintScopeConfusion {
+			issues = append(issues, issue)
+		}
+	}
+	if len(issues) != 1 {
+		t.Fatalf("Lint() scope-confusion = %+v, want one issue", issues)
+	}
+	issue := issues[0]
+	if issue.Rule.Pattern != "!/dist/" || issue.Rule.BasePath != "app" || issue.Rule.Line != 2 {
+		t.Errorf("Rule = %+v, want the nested negation", issue.Rule)
+	}
+	if len(issue.Related) != 1 || issue.Related[0].Pattern != "/dist/" || issue.Related[0].BasePath != "" {
+		t.Errorf("Related = %+v, want the root rule", issue.Related)
+	}
+	want := `"!/dist/" (line 2) in app only re-includes app/dist, not dist ignored by "/dist/" (line 1) in the root`
+	if issue.Message != want {
+		t.Errorf("Message = %q, want %q", issue.Message, want)
+	}
+}
+
+func TestLint_ScopeConfusionCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		root   string
+		nested string
+		want   bool
+	}{
+		{"anchored pair", "/dist\n", "!/dist\n", true},
+		{"multi-segment", "out/gen\n", "!out/gen\n", true},
+		{"nested negation floats", "/dist\n", "!dist\n", false},
+		{"root rule floats", "dist\n", "!/dist\n", false},
+		{"different name", "/dist\n", "!/build\n", false},
+		{"dir-only differs", "/dist/\n", "!/dist\n", false},
+		{"root negation", "/dist\n!/dist\n", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.AddPatterns("", []byte(tt.root))
+			m.AddPatterns("app", []byte(tt.nested))
+			got := false
+			for _, issue := range m.Lint() {
+				got = got || issue.Kind == LintScopeConfusion
+			}
+			if got != tt.want {
+				t.Errorf("scope-confusion reported = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -42,11 +42,34 @@ const maxRecursionDepth = 200
 // MatcherOptions field.
 const MaxPathDepth = 4096
 
+// pollInterval is how many rules or backtrack ticks pass between checks of
+// a MatchContext context, keeping the per-step cost to a counter increment.
+const pollInterval = 64
+
 // matchContext tracks state during matching to prevent runaway backtracking.
 type matchContext struct {
 	iterations int
 	maxIter    int
 	depth      int
+
+	// done is the Done channel of a MatchContext context (nil otherwise);
+	// stopped records that it was closed mid-match.
+	done    <-chan struct{}
+	polls   int
+	stopped bool
+
+	// skipNegations leaves negation rules out of evaluation
+	// (MatchIgnoringNegations).
+	skipNegations bool
+
+	// maxStarts caps the start positions matchFloating tries (0 =
+	// unlimited; see MatcherOptions.MaxFloatStarts).
+	maxStarts int
+
+	// With trackLimit set, limitRule records the rule whose evaluation
+	// used up the budget (ProbeLimits).
+	trackLimit bool
+	limitRule  *rule
 }
 
 // newMatchContext creates a new match context with the specified limit.
@@ -63,12 +86,46 @@ func newMatchContext(maxIter int) matchContext {
 	}
 }
 
+// newContext returns a matchContext carrying m's limits.
+func (m *Matcher) newContext() matchContext {
+	ctx := newMatchContext(m.opts.MaxBacktrackIterations)
+	ctx.maxStarts = m.opts.MaxFloatStarts
+	return ctx
+}
+
 // tick increments the iteration counter and returns false if limit exceeded.
 func (ctx *matchContext) tick() bool {
 	ctx.iterations++
+	if ctx.done != nil && ctx.interrupted() {
+		return false
+	}
 	return ctx.iterations <= ctx.maxIter
 }
 
+// interrupted reports whether the caller's context has been canceled,
+// looking at the done channel only every pollInterval calls. Once it has,
+// the budget is marked exhausted so every later check short-circuits.
+func (ctx *matchContext) interrupted() bool {
+	if ctx.done == nil {
+		return false
+	}
+	if ctx.stopped {
+		return true
+	}
+	ctx.polls++
+	if ctx.polls%pollInterval != 0 {
+		return false
+	}
+	select {
+	case <-ctx.done:
+		ctx.stopped = true
+		ctx.iterations = ctx.maxIter
+		return true
+	default:
+		return false
+	}
+}
+
 // exhausted reports whether the iteration budget is already used up,
 // without consuming a unit. Used to short-circuit later rules after
 // earlier backtracking has used the budget.
@@ -81,6 +138,10 @@ func (ctx *matchContext) exhausted() bool {
 // pathSegments is the path split by "/".
 // isDir indicates whether the path is a directory.
 // ctx is the shared backtrack budget for the entire Match call.
+//
+// Only the path itself is matched, as git matches it: "build/" matches the
+// directory build but not build/out.js. Files inside a matched directory are
+// ignored through their ancestor instead (see matchRuleAncestor and decide).
 func matchRule(r *rule, path string, pathSegments []string, isDir bool, ctx *matchContext) bool {
 	// Short-circuit if earlier backtracking exhausted the budget.
 	// Read-only — rule enumeration must not itself consume budget,
@@ -89,6 +150,11 @@ func matchRule(r *rule, path string, pathSegments []string, isDir bool, ctx *mat
 		return false
 	}
 
+	// Directory-only patterns never match files.
+	if r.dirOnly && !isDir {
+		return false
+	}
+
 	matchSegments := resolveMatchSegments(r, path, pathSegments)
 	if matchSegments == nil {
 		return false // path not under basePath
@@ -100,21 +166,32 @@ func matchRule(r *rule, path string, pathSegments []string, isDir bool, ctx *mat
 		return len(r.segments) == 0
 	}
 
-	// Directory-only patterns:
-	// - Match directories directly (isDir == true)
-	// - Match files INSIDE matching directories (isDir == false, path is inside dir)
-	// For the "inside dir" case, we use prefix matching
-	prefixMatch := r.dirOnly && !isDir
-
 	// Handle anchored vs floating patterns
 	if r.anchored {
-		if prefixMatch {
-			return matchSegmentsPrefix(r.segments, matchSegments, ctx)
-		}
 		return matchSegmentsExact(r.segments, matchSegments, ctx)
 	}
 
-	return matchFloating(r, matchSegments, prefixMatch, ctx)
+	return matchFloating(r, matchSegments, false, ctx)
+}
+
+// matchRuleAncestor reports whether r matches one of the directories that
+// contain path, within r's scope. The path itself and the basePath directory
+// are not candidates. Ancestors are always directories, so directory-only
+// rules qualify.
+func matchRuleAncestor(r *rule, path string, pathSegments []string, ctx *matchContext) bool {
+	if ctx.exhausted() || len(r.segments) == 0 {
+		return false
+	}
+
+	matchSegments := resolveMatchSegments(r, path, pathSegments)
+	if len(matchSegments) < 2 {
+		return false // not under basePath, or no ancestor inside it
+	}
+
+	if r.anchored {
+		return matchSegmentsPrefix(r.segments, matchSegments, ctx)
+	}
+	return matchFloating(r, matchSegments, true, ctx)
 }
 
 // resolveMatchSegments applies basePath scoping and returns the segments to match against.
@@ -136,11 +213,31 @@ func resolveMatchSegments(r *rule, path string, pathSegments []string) []string
 
 // matchFloating tries to match a floating (unanchored) pattern at any position in the path.
 func matchFloating(r *rule, matchSegments []string, prefixMatch bool, ctx *matchContext) bool {
+	// A leading ** already tries every start position; looping over starts
+	// as well would be quadratic in the path depth.
+	if len(r.segments) > 0 && r.segments[0].doubleStar {
+		if prefixMatch {
+			return matchSegmentsPrefix(r.segments, matchSegments, ctx)
+		}
+		return matchSegmentsExact(r.segments, matchSegments, ctx)
+	}
+
 	maxStart := len(matchSegments) - len(r.segments)
-	if prefixMatch {
+	minStart := 0
+	switch {
+	case prefixMatch && r.fixedLen:
+		// Leave at least one segment inside the matched ancestor.
+		maxStart--
+	case prefixMatch:
 		maxStart = len(matchSegments) - 1
+	case r.fixedLen:
+		// Without ** only the alignment ending at the last segment fits.
+		minStart = maxStart
+	}
+	if ctx.maxStarts > 0 && maxStart-minStart >= ctx.maxStarts {
+		maxStart = minStart + ctx.maxStarts - 1
 	}
-	for i := 0; i <= maxStart; i++ {
+	for i := minStart; i <= maxStart; i++ {
 		if ctx.exhausted() {
 			return false
 		}
@@ -154,15 +251,6 @@ func matchFloating(r *rule, matchSegments []string, prefixMatch bool, ctx *match
 			}
 		}
 	}
-
-	// Special case: pattern with ** can match even if more segments than path
-	if len(r.segments) > 0 && r.segments[0].doubleStar {
-		if prefixMatch {
-			return matchSegmentsPrefix(r.segments, matchSegments, ctx)
-		}
-		return matchSegmentsExact(r.segments, matchSegments, ctx)
-	}
-
 	return false
 }
 
@@ -186,8 +274,9 @@ func matchSegmentsExact(pattern []segment, path []string, ctx *matchContext) boo
 		// ** can match zero or more path segments.
 		// Trailing ** (last segment) must consume at least one segment:
 		// abc/** should match abc/file but not abc itself (matches git behavior).
+		// A middle ** under DoubleStarMinOne likewise needs at least one.
 		minI := 0
-		if len(pattern) == 1 {
+		if len(pattern) == 1 || seg.minOne {
 			minI = 1
 		}
 		ctx.depth++
@@ -224,8 +313,9 @@ func matchSegmentsExact(pattern []segment, path []string, ctx *matchContext) boo
 
 // matchSegmentsPrefix matches pattern as a PREFIX of path.
 // Unlike matchSegmentsExact, this allows the path to have additional segments
-// after the pattern is fully matched. Used for directory patterns matching
-// files inside the directory.
+// after the pattern is fully matched: it reports whether pattern matches some
+// proper ancestor directory of path. Used to find directories whose contents
+// a rule ignores.
 func matchSegmentsPrefix(pattern []segment, path []string, ctx *matchContext) bool {
 	// Bound recursion depth; budget is only consumed inside backtrack loops.
 	if ctx.exhausted() || ctx.depth >= maxRecursionDepth {
@@ -243,10 +333,16 @@ func matchSegmentsPrefix(pattern []segment, path []string, ctx *matchContext) bo
 
 	// Handle ** (double-star)
 	if seg.doubleStar {
-		// ** can match zero or more path segments
-		// Try matching remaining pattern against path starting at each position
+		// ** can match zero or more path segments (one or more if minOne)
+		// Try matching remaining pattern against path starting at each position.
+		// As in matchSegmentsExact, a trailing ** must consume at least one
+		// segment: abc/** matches directories inside abc, not abc itself.
+		minI := 0
+		if len(pattern) == 1 || seg.minOne {
+			minI = 1
+		}
 		ctx.depth++
-		for i := 0; i <= len(path); i++ {
+		for i := minI; i <= len(path); i++ {
 			if matchSegmentsPrefix(pattern[1:], path[i:], ctx) {
 				ctx.depth--
 				return true
@@ -844,9 +844,10 @@ func TestMatchRule_Basic(t *testing.T) {
 		{"dir pattern on file", "build/", "build", false, false},
 		{"dir pattern nested", "build/", "src/build", true, true},
 		// Files INSIDE directories should also be ignored
-		{"dir pattern file inside", "build/", "build/output.js", false, true},
-		{"dir pattern file deep inside", "build/", "build/a/b/c.js", false, true},
-		{"dir pattern nested file inside", "build/", "src/build/output.js", false, true},
+		// Contents match through their ancestor (matchRuleAncestor), not directly
+		{"dir pattern file inside", "build/", "build/output.js", false, false},
+		{"dir pattern file deep inside", "build/", "build/a/b/c.js", false, false},
+		{"dir pattern nested file inside", "build/", "src/build/output.js", false, false},
 
 		// Anchored patterns (contain /)
 		{"anchored match", "src/temp", "src/temp", false, true},
@@ -1089,7 +1090,8 @@ func TestSplitPath(t *testing.T) {
 	}
 }
 
-// TestMatchRule_DirectoryContents tests that files inside ignored directories are also ignored
+// TestMatchRule_DirectoryContents tests that files inside ignored directories
+// are also ignored, either directly or through a matching ancestor.
 func TestMatchRule_DirectoryContents(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1138,15 +1140,54 @@ func TestMatchRule_DirectoryContents(t *testing.T) {
 			}
 			path := normalizePath(tt.path)
 			pathSegs := splitPath(path)
-			got := matchRule(r, path, pathSegs, tt.isDir, testCtx(0))
+			got := matchRule(r, path, pathSegs, tt.isDir, testCtx(0)) ||
+				matchRuleAncestor(r, path, pathSegs, testCtx(0))
 			if got != tt.want {
-				t.Errorf("matchRule(%q, %q, isDir=%v) = %v, want %v",
+				t.Errorf("matchRule||matchRuleAncestor(%q, %q, isDir=%v) = %v, want %v",
 					tt.pattern, tt.path, tt.isDir, got, tt.want)
 			}
 		})
 	}
 }
 
+func TestMatchRuleAncestor(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		basePath string
+		path     string
+		want     bool
+	}{
+		{"dir-only parent", "build/", "", "build/out.js", true},
+		{"dir-only grandparent", "build/", "", "src/build/a/b.js", true},
+		{"plain name parent", "build", "", "build/out.js", true},
+		{"path itself is not an ancestor", "build", "", "build", false},
+		{"anchored parent", "/build", "", "build/out.js", true},
+		{"anchored nested not match", "/build", "", "src/build/out.js", false},
+		{"trailing doublestar needs a directory", "logs/**", "", "logs/x", false},
+		{"trailing doublestar below", "logs/**", "", "logs/a/x", true},
+		{"middle doublestar", "a/**/b", "", "a/x/b/c", true},
+		{"basePath itself excluded", "*", "src", "src/x", false},
+		{"under basePath", "*", "src", "src/a/x", true},
+		{"outside basePath", "*", "src", "lib/a/x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := parseLine(tt.pattern, 1, tt.basePath, "")
+			if r == nil {
+				t.Fatalf("parseLine(%q) returned nil", tt.pattern)
+			}
+			path := normalizePath(tt.path)
+			got := matchRuleAncestor(r, path, splitPath(path), testCtx(0))
+			if got != tt.want {
+				t.Errorf("matchRuleAncestor(%q, base=%q, %q) = %v, want %v",
+					tt.pattern, tt.basePath, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestMatchRule_EscapedWildcards tests that escaped wildcards match literally
 func TestMatchRule_EscapedWildcards(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,77 @@
+package ignore
+
+import (
+	"strings"
+)
+
+// Merge returns a new Matcher holding the rules of matchers concatenated in
+// argument order, so that later matchers take precedence under
+// last-match-wins just as if their patterns had been added to one Matcher
+// in sequence: Merge(global, repo, cli) lets a negation from cli re-include
+// a path ignored by global.
+//
+// The result uses the first matcher's options and, if it is a Sub view, its
+// scope. Rules are shared rather than recompiled, so options that act at
+// parse time (CommentChar, DoubleStarMinOne, and the like) keep whatever
+// effect they had in each source. Rules from a case-sensitive source are
+// case-folded when the first matcher is CaseInsensitive. Collected
+// warnings, preserved raw content, force-tracked directories, and
+// predicates are carried over; fallbacks (see SetFallback) are not. If the
+// combined rules exceed the first matcher's MaxPatterns, the excess is
+// dropped with a warning.
+//
+// The sources are not modified and later changes to them do not affect the
+// result. Nil matchers are skipped; with none, Merge returns New().
+//
+// Thread-safe: each source is read under its own lock.
+func Merge(matchers ...*Matcher) *Matcher {
+	var merged *Matcher
+	for _, src := range matchers {
+		if src == nil {
+			continue
+		}
+		src.mu.RLock()
+		if merged == nil {
+			merged = &Matcher{opts: src.opts, prefix: src.prefix, trace: newMatchTrace(src.opts.TraceHistory)}
+		}
+		rules := append([]rule(nil), src.rules...)
+		if merged.opts.CaseInsensitive && !src.opts.CaseInsensitive {
+			for i := range rules {
+				rules[i].segments = append([]segment(nil), rules[i].segments...)
+			}
+			foldRules(rules)
+		}
+		merged.rules = append(merged.rules, rules...)
+		merged.warnings = append(merged.warnings, src.warnings...)
+		if merged.opts.PreserveRawContent {
+			merged.raw = append(merged.raw, src.raw...)
+		}
+		for _, dir := range src.forceTracked {
+			if merged.opts.CaseInsensitive && !src.opts.CaseInsensitive {
+				dir = strings.ToLower(dir)
+			}
+			merged.forceTracked = appendUnique(merged.forceTracked, dir)
+		}
+		merged.predicates = append(merged.predicates, src.predicates...)
+		src.mu.RUnlock()
+	}
+	if merged == nil {
+		return New()
+	}
+
+	if limit := merged.opts.MaxPatterns; limit >= 0 && len(merged.rules) > limit {
+		merged.rules = merged.rules[:limit]
+		w := ParseWarning{Message: "maximum pattern count reached, excess patterns truncated"}
+		if handler := merged.opts.WarningHandler; handler != nil {
+			handler(w)
+		} else {
+			merged.warnings = append(merged.warnings, w)
+		}
+	}
+	for i := range merged.rules {
+		if merged.rules[i].negate {
+			merged.negateEnd = i + 1
+		}
+	}
+	return merged
+}
@@ -0,0 +1,93 @@
+package ignore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge_NegationOverrides(t *testing.T) {
+	global := New()
+	global.AddPatterns("", []byte("*.log\nbuild/\n"))
+	repo := New()
+	repo.AddPatterns("", []byte("!keep.log\n"))
+
+	merged := Merge(global, repo)
+
+	// Reference: the same patterns added to one matcher in sequence.
+	want := New()
+	want.AddPatterns("", []byte("*.log\nbuild/\n"))
+	want.AddPatterns("", []byte("!keep.log\n"))
+
+	paths := []string{"debug.log", "keep.log", "src/keep.log", "build/out.js", "main.go"}
+	for _, p := range paths {
+		if got, w := merged.MatchWithReason(p, false), want.MatchWithReason(p, false); !reflect.DeepEqual(got, w) {
+			t.Errorf("MatchWithReason(%q) = %+v, want %+v", p, got, w)
+		}
+	}
+	if merged.Match("keep.log", false) {
+		t.Error("negation from the second matcher should override the first's ignore")
+	}
+
+	// Argument order is precedence order.
+	if !Merge(repo, global).Match("keep.log", false) {
+		t.Error("with the negation first, the later *.log should win")
+	}
+}
+
+func TestMerge_Isolation(t *testing.T) {
+	a := New()
+	a.AddPatterns("", []byte("*.tmp\n"))
+	b := New()
+	b.AddPatterns("", []byte("*.bak\n"))
+	merged := Merge(a, b)
+
+	a.AddPatterns("", []byte("!x.tmp\n"))
+	merged.AddPatterns("", []byte("*.out\n"))
+	if !merged.Match("x.tmp", false) {
+		t.Error("merged matcher should not see rules added to a source afterwards")
+	}
+	if a.Match("y.out", false) || b.Match("y.out", false) {
+		t.Error("sources should not see rules added to the merged matcher")
+	}
+	if got := merged.RuleCount(); got != 3 {
+		t.Errorf("RuleCount() = %d, want 3", got)
+	}
+}
+
+func TestMerge_Options(t *testing.T) {
+	insensitive := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	insensitive.AddPatterns("", []byte("*.LOG\n"))
+	sensitive := New()
+	sensitive.AddPatterns("docs", []byte("Draft.md\n"))
+	sensitive.AddPatterns("", []byte("bad pattern\\\n"))
+
+	merged := Merge(nil, insensitive, sensitive)
+	if !merged.Match("DEBUG.log", false) {
+		t.Error("first matcher's CaseInsensitive option should apply")
+	}
+	if !merged.Match("docs/DRAFT.MD", false) {
+		t.Error("rules from a case-sensitive source should be case-folded")
+	}
+	if sensitive.Match("docs/DRAFT.MD", false) {
+		t.Error("folding must not modify the source matcher's rules")
+	}
+	if len(merged.Warnings()) != len(sensitive.Warnings()) || len(merged.Warnings()) == 0 {
+		t.Errorf("Warnings() = %v, want the sources' warnings", merged.Warnings())
+	}
+
+	limited := NewWithOptions(MatcherOptions{MaxPatterns: 2})
+	limited.AddPatterns("", []byte("a\n"))
+	other := New()
+	other.AddPatterns("", []byte("b\nc\n"))
+	capped := Merge(limited, other)
+	if got := capped.RuleCount(); got != 2 {
+		t.Errorf("RuleCount() = %d, want MaxPatterns of 2", got)
+	}
+	if len(capped.Warnings()) != 1 {
+		t.Errorf("Warnings() = %v, want a truncation warning", capped.Warnings())
+	}
+
+	if m := Merge(); m == nil || m.RuleCount() != 0 {
+		t.Error("Merge() should return an empty matcher")
+	}
+}
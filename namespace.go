@@ -0,0 +1,84 @@
+package ignore
+
+// AddPatternsNS adds gitignore content under basePath to the namespace ns,
+// a rule set of its own inside m, for servers that hold the rules of many
+// repositories without a Matcher per repository. Namespaced rules are
+// consulted only by MatchNS with the same ns: they never affect Match or
+// another namespace, and m's own rules never affect them.
+//
+// Parsing follows m's options and works as AddPatterns does, including the
+// warnings, which are delivered or collected as usual with their BasePath.
+// MaxPatterns applies to each namespace separately. The empty namespace is
+// m's own rule set, so AddPatternsNS("", basePath, content) is
+// AddPatterns(basePath, content).
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) AddPatternsNS(ns, basePath string, content []byte) {
+	if ns == "" {
+		m.AddPatterns(basePath, content)
+		return
+	}
+	if content == nil {
+		return
+	}
+	normalizedBase := normalizePath(basePath)
+	if m.prefix != "" {
+		normalizedBase = m.scope(basePath)
+	}
+	newRules, parseWarnings := parseLines(normalizedBase, content, "", m.opts.parseOptions())
+	if m.opts.CaseInsensitive {
+		foldRules(newRules)
+	}
+
+	m.mu.Lock()
+	rules := m.namespaces[ns]
+	newRules, parseWarnings = m.limitRules(len(rules), newRules, parseWarnings, normalizedBase, "")
+	if m.namespaces == nil {
+		m.namespaces = make(map[string][]rule)
+	}
+	m.namespaces[ns] = append(rules, newRules...)
+	handler := m.opts.WarningHandler
+	if handler == nil {
+		m.warnings = append(m.warnings, parseWarnings...)
+	}
+	m.mu.Unlock()
+
+	if handler != nil {
+		for _, w := range parseWarnings {
+			handler(w)
+		}
+	}
+}
+
+// MatchNS is Match against the rules added to namespace ns with
+// AddPatternsNS. Only those rules decide the path, under m's options, so
+// DefaultIgnored applies to paths they leave unmatched, but force-tracked
+// directories, predicates and the fallback, which belong to m's own rule
+// set, are not consulted. A namespace nothing was added to matches
+// nothing. MatchNS("", path, isDir) is Match(path, isDir). OnMatch, if
+// configured, is called once with the result.
+//
+// Namespaces are not carried over by Sub, Snapshot, Merge, Compact or
+// MarshalBinary.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchNS(ns, path string, isDir bool) bool {
+	if ns == "" {
+		return m.Match(path, isDir)
+	}
+	var result MatchResult
+	var segBuf [32]string
+	if p, pathSegments, dir, ok := m.preparePath(path, isDir, segBuf[:0]); ok {
+		if r, git := m.gitDirResult(pathSegments, dir); git {
+			result = r
+		} else {
+			ctx := m.newContext()
+			m.mu.RLock()
+			rules := m.namespaces[ns]
+			result = m.applyDefault(decide(rules, len(rules), p, pathSegments, dir, &ctx))
+			m.mu.RUnlock()
+		}
+	}
+	m.report(path, isDir, result)
+	return result.Ignored
+}
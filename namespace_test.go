@@ -0,0 +1,81 @@
+package ignore
+
+import (
+	"testing"
+)
+
+func TestMatchNS_Isolation(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.tmp\n"))
+	m.AddPatternsNS("repoA", "", []byte("*.log\nbuild/\n!keep.log\n"))
+	m.AddPatternsNS("repoA", "src", []byte("gen/\n"))
+	m.AddPatternsNS("repoB", "", []byte("*.o\n!important.log\n"))
+
+	tests := []struct {
+		ns    string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"repoA", "debug.log", false, true},
+		{"repoA", "keep.log", false, false},
+		{"repoA", "build/out.js", false, true},
+		{"repoA", "src/gen/a.go", false, true},
+		{"repoA", "main.o", false, false},      // repoB's rule
+		{"repoA", "scratch.tmp", false, false}, // m's own rule
+		{"repoB", "main.o", false, true},
+		{"repoB", "debug.log", false, false},
+		{"repoB", "build/out.js", false, false},
+		{"repoB", "src/gen/a.go", false, false},
+		{"repoC", "debug.log", false, false},
+		{"", "scratch.tmp", false, true},
+		{"", "debug.log", false, false},
+		{"", "main.o", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.MatchNS(tt.ns, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("MatchNS(%q, %q, %v) = %v, want %v", tt.ns, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	// Namespaced rules never reach m's own rule set.
+	if m.RuleCount() != 1 || m.Match("debug.log", false) || m.Match("main.o", false) {
+		t.Errorf("namespaced rules leaked into Match (RuleCount() = %d)", m.RuleCount())
+	}
+}
+
+func TestMatchNS_Options(t *testing.T) {
+	var warnings []ParseWarning
+	m := NewWithOptions(MatcherOptions{
+		CaseInsensitive: true,
+		MaxPatterns:     2,
+		WarningHandler:  func(w ParseWarning) { warnings = append(warnings, w) },
+	})
+	m.AddPatterns("", []byte("a\nb\n"))
+	m.AddPatternsNS("x", "Src", []byte("*.LOG\n!\nKeep.log\nextra\n"))
+
+	if !m.MatchNS("x", "SRC/Debug.log", false) || !m.MatchNS("x", "src/keep.log", false) {
+		t.Error("namespaced rules should follow CaseInsensitive")
+	}
+	if m.MatchNS("x", "src/extra", false) {
+		t.Error("MaxPatterns should truncate the namespace at 2 rules")
+	}
+	// The "!" line and the truncation, both for the namespace alone.
+	if len(warnings) != 2 || warnings[0].Line != 2 || warnings[0].BasePath != "Src" {
+		t.Errorf("warnings = %+v, want the empty negation and a truncation warning", warnings)
+	}
+
+	def := NewWithOptions(MatcherOptions{DefaultIgnored: true})
+	def.AddPatternsNS("x", "", []byte("!*.go\n"))
+	if !def.MatchNS("x", "README", false) || def.MatchNS("x", "main.go", false) {
+		t.Error("DefaultIgnored should apply to namespace decisions")
+	}
+
+	var calls int
+	hooked := NewWithOptions(MatcherOptions{OnMatch: func(MatchResult) { calls++ }})
+	hooked.AddPatternsNS("x", "", []byte("*.log\n"))
+	hooked.MatchNS("x", "a.log", false)
+	if calls != 1 {
+		t.Errorf("OnMatch called %d times, want 1", calls)
+	}
+}
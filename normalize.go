@@ -3,6 +3,7 @@ package ignore
 
 import (
 	"bytes"
+	"net/url"
 	"path"
 	"runtime"
 	"strings"
@@ -80,6 +81,146 @@ func normalizePath(p string) string {
 	return p
 }
 
+// joinSegments joins the segments returned by MatcherOptions.Splitter with
+// "/", dropping empty ones, into the form the matcher works on. It returns
+// "" if a segment contains a NUL byte, as normalizePath does.
+func joinSegments(segs []string) string {
+	var b strings.Builder
+	for _, seg := range segs {
+		if seg == "" {
+			continue
+		}
+		if strings.IndexByte(seg, 0) >= 0 {
+			return ""
+		}
+		if b.Len() > 0 {
+			b.WriteByte('/')
+		}
+		b.WriteString(seg)
+	}
+	return b.String()
+}
+
+// hasTrailingSeparator reports whether p ends in a path separator, which
+// marks it as a directory ("build/"). Backslash counts only on Windows, as in
+// normalizePath.
+func hasTrailingSeparator(p string) bool {
+	if p == "" {
+		return false
+	}
+	last := p[len(p)-1]
+	return last == '/' || (last == '\\' && runtime.GOOS == "windows")
+}
+
+// escapesRoot reports whether p resolves above the root once "." and ".."
+// are resolved. Unlike normalizePath, a leading "/" does not pin "/.." to
+// the root: p is always taken as relative, so "/../x" escapes.
+func escapesRoot(p string) bool {
+	if runtime.GOOS == "windows" {
+		p = strings.ReplaceAll(p, "\\", "/")
+	}
+	if !strings.Contains(p, "..") {
+		return false
+	}
+	p = path.Clean(strings.TrimLeft(p, "/"))
+	return p == ".." || strings.HasPrefix(p, "../")
+}
+
+// nativeRelPath converts the native path p into a slash-separated path
+// relative to root, for MatchNative. A relative p is taken to be relative
+// to root already and is returned as given, so it may still climb out with
+// ".."; callers check that with escapesRoot. An absolute p must lie under
+// root; ok is false when it does not, or when root is empty.
+//
+// With windows set, both are read as Windows paths: backslashes separate,
+// the drive, extended-length and UNC forms listed on MatchNative are
+// recognized, and volumes and the root compare case-insensitively. It is a
+// parameter rather than a runtime.GOOS check so the Windows forms can be
+// tested on every OS.
+func nativeRelPath(root, p string, windows bool) (rel string, ok bool) {
+	pVol, pRest := splitVolume(p, windows)
+	if pVol == "" && !strings.HasPrefix(pRest, "/") {
+		return pRest, true
+	}
+	rVol, rRest := splitVolume(root, windows)
+	if root == "" || (rVol == "" && !strings.HasPrefix(rRest, "/")) {
+		return "", false // an absolute path needs an absolute root
+	}
+	equal, hasPrefix := func(a, b string) bool { return a == b }, strings.HasPrefix
+	if windows {
+		equal = strings.EqualFold
+		hasPrefix = func(s, prefix string) bool {
+			return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+		}
+	}
+	if !equal(pVol, rVol) {
+		return "", false
+	}
+
+	pRest = path.Clean("/" + pRest)
+	rRest = path.Clean("/" + rRest)
+	switch {
+	case rRest == "/":
+		return pRest[1:], true
+	case equal(pRest, rRest):
+		return "", true
+	case hasPrefix(pRest, rRest+"/"):
+		return pRest[len(rRest)+1:], true
+	}
+	return "", false
+}
+
+// splitVolume splits a native path into its volume and the slash-separated
+// remainder (see nativeRelPath). Drive letters and UNC volumes are
+// recognized only with windows set; volume is "" otherwise.
+func splitVolume(p string, windows bool) (volume, rest string) {
+	if !windows {
+		return "", p
+	}
+	p = strings.ReplaceAll(p, "\\", "/")
+	if strings.HasPrefix(p, "//?/") || strings.HasPrefix(p, "//./") {
+		p = p[len("//?/"):]
+		if len(p) >= 4 && strings.EqualFold(p[:4], "UNC/") {
+			p = "//" + p[4:]
+		}
+	}
+	switch {
+	case len(p) >= 2 && p[1] == ':' && ('a' <= p[0]|0x20 && p[0]|0x20 <= 'z'):
+		return p[:2], p[2:]
+	case strings.HasPrefix(p, "//"):
+		// "//server/share/rest": the volume ends after the share name.
+		end := 2
+		for n := 0; n < 2 && end < len(p); n++ {
+			if i := strings.IndexByte(p[end:], '/'); i >= 0 {
+				end += i + 1
+			} else {
+				end = len(p) + 1
+			}
+		}
+		if end > len(p) {
+			return p, ""
+		}
+		return p[:end-1], p[end-1:]
+	}
+	return "", p
+}
+
+// decodePath percent-decodes p exactly once (MatcherOptions.URLDecodePaths),
+// so "src%2Fmain.go" becomes "src/main.go" while "src%252Fmain.go" becomes
+// the literal name "src%2Fmain.go" rather than being decoded twice. A path
+// with a malformed escape (e.g., "100%") is returned unchanged, since it was
+// evidently not encoded. "+" is left alone: it is not a space in paths.
+func decodePath(p string) string {
+	if strings.IndexByte(p, '%') < 0 {
+		return p
+	}
+	decoded, err := url.PathUnescape(p)
+	if err != nil {
+		return p
+	}
+	return decoded
+}
+
 // normalizeContent normalizes .gitignore file content for parsing.
 // It handles platform-specific encoding variations.
 //
@@ -117,6 +258,24 @@ func normalizeContent(content []byte) []byte {
 	return buf
 }
 
+// stripInlineComment cuts line at the first unescaped space or tab that is
+// followed by commentChar (MatcherOptions.AllowInlineComments):
+// "*.log # debug logs" → "*.log". A commentChar with no whitespace before it
+// ("foo#bar") or escaped by a backslash ("foo \#bar") is kept.
+func stripInlineComment(line string, commentChar byte) string {
+	for i := 0; i+1 < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case ' ', '\t':
+			if line[i+1] == commentChar {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
 // trimTrailingWhitespace removes trailing spaces and tabs from a line,
 // respecting backslash-escaped spaces per the gitignore spec.
 //
@@ -153,3 +312,30 @@ func trimTrailingWhitespace(line string) string {
 
 	return line[:end]
 }
+
+// legacyLineEndings returns the 1-indexed numbers of the first line ending
+// in CRLF and the first ending in a lone CR, counting lines as
+// normalizeContent splits them. Zero means no such line.
+func legacyLineEndings(content []byte) (crlf, cr int) {
+	if bytes.IndexByte(content, '\r') < 0 {
+		return 0, 0
+	}
+	line := 1
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\n':
+			line++
+		case '\r':
+			if i+1 < len(content) && content[i+1] == '\n' {
+				if crlf == 0 {
+					crlf = line
+				}
+				i++
+			} else if cr == 0 {
+				cr = line
+			}
+			line++
+		}
+	}
+	return crlf, cr
+}
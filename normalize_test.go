@@ -217,6 +217,30 @@ func TestNormalizePathIdempotent(t *testing.T) {
 	}
 }
 
+func TestDecodePath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"src/main.go", "src/main.go"},
+		{"src%2Fmain.go", "src/main.go"},
+		{"src%2fmain.go", "src/main.go"},
+		{"my%20file.txt", "my file.txt"},
+		{"src%252Fmain.go", "src%2Fmain.go"}, // decoded once, not twice
+		{"100%", "100%"},                     // malformed: left as-is
+		{"a%zzb", "a%zzb"},                   // malformed: left as-is
+		{"a+b", "a+b"},                       // + is not a space in paths
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := decodePath(tt.input); got != tt.want {
+				t.Errorf("decodePath(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 // TestNormalizeContentIdempotent verifies that normalizing twice produces same result
 func TestNormalizeContentIdempotent(t *testing.T) {
 	contents := [][]byte{
@@ -238,3 +262,122 @@ func TestNormalizeContentIdempotent(t *testing.T) {
 		}
 	}
 }
+
+func TestLegacyLineEndings(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		crlf, cr int
+	}{
+		{"LF only", "a\nb\n", 0, 0},
+		{"CRLF", "a\r\nb\r\n", 1, 0},
+		{"CR only", "a\rb\r", 0, 1},
+		{"mixed", "a\nb\r\nc\rd\r\n", 2, 3},
+		{"trailing CR", "a\nb\r", 0, 2},
+		{"empty", "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crlf, cr := legacyLineEndings([]byte(tt.content))
+			if crlf != tt.crlf || cr != tt.cr {
+				t.Errorf("legacyLineEndings(%q) = (%d, %d), want (%d, %d)", tt.content, crlf, cr, tt.crlf, tt.cr)
+			}
+		})
+	}
+}
+
+func TestEscapesRoot(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"..", true},
+		{"../x", true},
+		{"a/../..", true},
+		{"/../x", true},
+		{"//..//x", true},
+		{"a/..", false},
+		{"a/../b", false},
+		{"x..y", false},
+		{"..x", false},
+		{"a/b", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := escapesRoot(tt.path); got != tt.want {
+			t.Errorf("escapesRoot(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNativeRelPath(t *testing.T) {
+	tests := []struct {
+		root, path string
+		windows    bool
+		want       string
+		ok         bool
+	}{
+		// Windows drive, extended-length and UNC forms.
+		{`C:\proj`, `C:\proj\src\x.log`, true, "src/x.log", true},
+		{`C:\proj`, `c:\PROJ\src\x.log`, true, "src/x.log", true},
+		{`C:\proj`, `\\?\C:\proj\src\x.log`, true, "src/x.log", true},
+		{`\\?\C:\proj\`, `C:/proj/src/x.log`, true, "src/x.log", true},
+		{`C:\proj`, `\\.\C:\proj\x`, true, "x", true},
+		{`\\server\share\proj`, `\\server\share\proj\a\b.txt`, true, "a/b.txt", true},
+		{`\\server\share\proj`, `\\?\UNC\SERVER\Share\proj\a\b.txt`, true, "a/b.txt", true},
+		{`\\server\share`, `\\server\share\a`, true, "a", true},
+		{`C:\`, `C:\a\b`, true, "a/b", true},
+		{`C:\proj`, `C:\proj`, true, "", true},
+		{`C:\proj`, `C:\proj\a\..\..\other\x`, true, "", false},
+		{`C:\proj`, `C:\project\x`, true, "", false},
+		{`C:\proj`, `D:\proj\x`, true, "", false},
+		{`\\server\share\proj`, `\\server\other\proj\x`, true, "", false},
+		{`C:\proj`, `\\server\share\proj\x`, true, "", false},
+		{`C:\proj`, `src\x.log`, true, "src/x.log", true}, // already relative
+		{"", `C:\proj\x`, true, "", false},
+
+		// Elsewhere only "/" separates and names compare exactly.
+		{"/home/u/proj", "/home/u/proj/src/x.log", false, "src/x.log", true},
+		{"/home/u/proj/", "/home/u/proj//src/./x.log", false, "src/x.log", true},
+		{"/home/u/proj", "/home/u/PROJ/x", false, "", false},
+		{"/home/u/proj", "/home/u/project/x", false, "", false},
+		{"/", "/etc/x", false, "etc/x", true},
+		{"/home/u/proj", `C:\proj\x`, false, `C:\proj\x`, true}, // not a volume here
+		{"proj", "/home/u/proj/x", false, "", false},
+		{"/home/u/proj", "../x", false, "../x", true}, // left to escapesRoot
+	}
+
+	for _, tt := range tests {
+		got, ok := nativeRelPath(tt.root, tt.path, tt.windows)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("nativeRelPath(%q, %q, %v) = %q, %v, want %q, %v",
+				tt.root, tt.path, tt.windows, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestStripInlineComment(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"*.log # debug logs", "*.log"},
+		{"*.log\t#tab", "*.log"},
+		{"*.log  #", "*.log "},
+		{"foo#bar", "foo#bar"},
+		{"foo \\#bar", "foo \\#bar"},
+		{"foo\\ #bar", "foo\\ #bar"},
+		{"foo\\\\ #bar", "foo\\\\"},
+		{" #x", ""},
+		{"#comment", "#comment"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := stripInlineComment(tt.line, '#'); got != tt.want {
+			t.Errorf("stripInlineComment(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -11,21 +11,27 @@ type ParseWarning struct {
 	Message  string // Human-readable warning message
 	Line     int    // Line number (1-indexed)
 	BasePath string // Directory containing the .gitignore (empty for root)
+	Source   string // File the pattern came from, as in MatchResult.Source (may be empty)
 }
 
 // rule represents a single parsed gitignore pattern.
 // Rules are evaluated in order; later rules can override earlier ones.
 type rule struct {
 	pattern       string    // original pattern (for debugging/reporting)
+	raw           string    // source line before any trimming (MatchResult.RawLine)
+	canonical     string    // canonical form of pattern (empty unless canonicalizing)
 	basePath      string    // directory scope (empty = root)
 	basePathSlash string    // basePath + "/" (pre-computed, empty if basePath is empty)
 	source        string    // path/label of the source file that supplied this rule (may be empty)
 	baseSegCount  int       // number of segments in basePath (pre-computed)
 	segments      []segment // parsed pattern segments for matching
+	stripped      int       // leading components of pattern not in segments (redundant "**", AddPatternsRebased)
 	line          int       // line number in source file (1-indexed)
 	negate        bool      // true if pattern started with !
 	dirOnly       bool      // true if pattern ended with /
 	anchored      bool      // true if pattern should match from basePath only
+	fixedLen      bool      // no ** segment: matches exactly len(segments) path segments
+	final         bool      // negation whose match later rules cannot undo (ReturnFirstNegationWins)
 }
 
 // segment represents one part of a pattern split by "/".
@@ -34,43 +40,73 @@ type segment struct {
 	value        string // literal or pattern text (empty for **)
 	wildcard     bool   // contains * (but not **) - requires glob matching
 	doubleStar   bool   // is ** - matches zero or more directories
+	minOne       bool   // middle ** that must match at least one directory (DoubleStarMinOne)
 	hasQuestion  bool   // contains ?
 	hasEscape    bool   // contains backslash
 	hasCharClass bool   // contains [ (character class or literal bracket)
 	starCount    int    // number of * characters
 }
 
+// parseOptions carries the MatcherOptions settings that influence how
+// individual lines are parsed. The zero value is not valid; use
+// defaultParseOptions or MatcherOptions.parseOptions.
+type parseOptions struct {
+	maxPatternLength int    // -1 for unlimited
+	commentChar      byte   // byte that starts a comment line (git: '#')
+	trimLeadingSpace bool   // strip leading spaces/tabs (git: false)
+	canonicalize     bool   // record rule.canonical for each rule
+	doubleStarMinOne bool   // middle ** requires at least one directory (git: false)
+	doubleStarToken  string // segment spelling any depth instead of ** (git: "")
+	anchoredOnly     bool   // treat every pattern as anchored (git: false)
+	rejectLegacyEOL  bool   // warn about CRLF and CR-only line endings
+	inlineComments   bool   // strip " #..." trailing comments (git: false)
+	finalNegation    bool   // a matching negation cannot be overridden (git: false)
+}
+
+// defaultParseOptions is git's dialect with no line-length limit.
+var defaultParseOptions = parseOptions{
+	maxPatternLength: -1,
+	commentChar:      '#',
+}
+
 // parseLines parses gitignore content into rules.
 // It normalizes content (BOM, line endings) and processes each line.
-// maxPatternLength limits individual line length (-1 for unlimited).
 // source is an optional informational label (e.g., the path to the
 // originating .gitignore file) carried on each parsed rule and surfaced via
 // MatchResult.Source. Pass "" if no source label is available.
+// opts.maxPatternLength limits individual line length (-1 for unlimited).
 // Returns parsed rules and any warnings for malformed patterns.
-func parseLines(basePath string, content []byte, maxPatternLength int, source string) ([]rule, []ParseWarning) {
+func parseLines(basePath string, content []byte, source string, opts parseOptions) ([]rule, []ParseWarning) {
+	var warnings []ParseWarning
+	if opts.rejectLegacyEOL {
+		crlf, cr := legacyLineEndings(content)
+		if crlf > 0 {
+			warnings = append(warnings, ParseWarning{
+				Line:     crlf,
+				Message:  "CRLF line ending, expected LF",
+				BasePath: basePath,
+				Source:   source,
+			})
+		}
+		if cr > 0 {
+			warnings = append(warnings, ParseWarning{
+				Line:     cr,
+				Message:  "CR-only line ending, expected LF",
+				BasePath: basePath,
+				Source:   source,
+			})
+		}
+	}
+
 	// Normalize content (BOM, CRLF)
 	content = normalizeContent(content)
 
 	lines := strings.Split(string(content), "\n")
 	rules := make([]rule, 0, len(lines))
-	var warnings []ParseWarning
 
 	for i, line := range lines {
-		lineNum := i + 1 // 1-indexed
-
-		if maxPatternLength >= 0 && len(line) > maxPatternLength {
-			warnings = append(warnings, ParseWarning{
-				Line:     lineNum,
-				Pattern:  line,
-				Message:  "pattern exceeds maximum length, skipped",
-				BasePath: basePath,
-			})
-			continue
-		}
-
-		r, warning := parseLine(line, lineNum, basePath, source)
+		r, warning := parseContentLine(line, i+1, basePath, source, opts)
 		if warning != nil {
-			warning.BasePath = basePath
 			warnings = append(warnings, *warning)
 		}
 		if r != nil {
@@ -81,13 +117,51 @@ func parseLines(basePath string, content []byte, maxPatternLength int, source st
 	return rules, warnings
 }
 
-// parseLine parses a single line from a .gitignore file.
+// parseContentLine parses line lineNum (1-indexed) of normalized content,
+// skipping lines longer than opts.maxPatternLength. Unlike parseLineWith,
+// the warning it returns carries basePath and source.
+func parseContentLine(line string, lineNum int, basePath, source string, opts parseOptions) (*rule, *ParseWarning) {
+	if opts.maxPatternLength >= 0 && len(line) > opts.maxPatternLength {
+		return nil, &ParseWarning{
+			Line:     lineNum,
+			Pattern:  line,
+			Message:  "pattern exceeds maximum length, skipped",
+			BasePath: basePath,
+			Source:   source,
+		}
+	}
+
+	r, warning := parseLineWith(line, lineNum, basePath, source, opts)
+	if warning != nil {
+		warning.BasePath = basePath
+		warning.Source = source
+	}
+	return r, warning
+}
+
+// parseLine parses a single line from a .gitignore file using git's dialect.
 // Returns nil rule for empty lines, comments, and malformed patterns.
 // Returns a warning for patterns that become empty after processing.
 // source is propagated onto the returned rule for provenance reporting.
 func parseLine(line string, lineNum int, basePath, source string) (*rule, *ParseWarning) {
-	// Step 1: Trim trailing whitespace (Git behavior)
+	return parseLineWith(line, lineNum, basePath, source, defaultParseOptions)
+}
+
+// parseLineWith is parseLine with an explicit dialect (comment character
+// and friends) taken from opts.
+func parseLineWith(line string, lineNum int, basePath, source string, opts parseOptions) (*rule, *ParseWarning) {
+	raw := line
+
+	// Step 1: Trim trailing whitespace (Git behavior). Leading whitespace is
+	// part of the pattern in git; only non-git dialects strip it, or cut a
+	// trailing comment first.
+	if opts.inlineComments {
+		line = stripInlineComment(line, opts.commentChar)
+	}
 	line = trimTrailingWhitespace(line)
+	if opts.trimLeadingSpace {
+		line = strings.TrimLeft(line, " \t")
+	}
 
 	// Step 2: Skip empty lines (no warning)
 	if line == "" {
@@ -95,7 +169,7 @@ func parseLine(line string, lineNum int, basePath, source string) (*rule, *Parse
 	}
 
 	// Step 3: Skip comments
-	if strings.HasPrefix(line, "#") {
+	if line[0] == opts.commentChar {
 		return nil, nil
 	}
 
@@ -113,9 +187,10 @@ func parseLine(line string, lineNum int, basePath, source string) (*rule, *Parse
 		line = line[1:]
 	}
 
-	// Step 5: Handle \# escape (after negation to support !\#foo)
-	if strings.HasPrefix(line, "\\#") {
-		line = line[1:] // Remove backslash, keep literal #
+	// Step 5: Handle \# escape (after negation to support !\#foo).
+	// The escaped character follows the configured comment character.
+	if len(line) >= 2 && line[0] == '\\' && line[1] == opts.commentChar {
+		line = line[1:] // Remove backslash, keep literal comment char
 	}
 
 	// Step 6: Check for directory-only (trailing /)
@@ -154,6 +229,23 @@ func parseLine(line string, lineNum int, basePath, source string) (*rule, *Parse
 		}
 	}
 
+	// Step 8c: An empty path component ("a//b", "//a", or "a//" once the
+	// directory marker is gone) never matches in git, which compares the
+	// pattern against paths that have no empty components.
+	if strings.Contains(line, "//") || strings.HasSuffix(line, "/") {
+		return nil, &ParseWarning{
+			Line:    lineNum,
+			Pattern: original,
+			Message: "consecutive slashes are invalid (pattern never matches)",
+		}
+	}
+
+	// Step 8d: In a dialect with its own any-depth token, spell it "**" so
+	// the steps below see git syntax.
+	if opts.doubleStarToken != "" {
+		line = translateDoubleStar(line, opts.doubleStarToken)
+	}
+
 	// Step 9: Determine anchoring
 	anchored, line, emptyAfterSlash := determineAnchoring(line)
 	if emptyAfterSlash {
@@ -163,27 +255,158 @@ func parseLine(line string, lineNum int, basePath, source string) (*rule, *Parse
 			Message: "pattern is empty after removing leading slash",
 		}
 	}
+	if opts.anchoredOnly {
+		anchored = true
+	}
 
-	// Step 10: Parse into segments
+	// Step 10: Parse into segments. Leading "**" segments before a single
+	// name are redundant, since a one-segment pattern already floats:
+	// "**/*.log" is "*.log". Dropping them spares the matcher the
+	// double-star walk.
 	segments := parseSegments(line)
+	stripped := 0
+	if last := len(segments) - 1; !anchored && last > 0 && !segments[last].doubleStar {
+		lead := 0
+		for segments[lead].doubleStar {
+			lead++
+		}
+		if lead == last {
+			segments, stripped = segments[last:], last
+		}
+	}
+	fixedLen := true
+	for i := range segments {
+		if segments[i].doubleStar {
+			fixedLen = false
+			if opts.doubleStarMinOne && i > 0 && i < len(segments)-1 {
+				segments[i].minOne = true
+			}
+		}
+	}
 
 	r := &rule{
 		pattern:  original,
+		raw:      raw,
 		basePath: basePath,
 		source:   source,
 		line:     lineNum,
 		negate:   negate,
 		dirOnly:  dirOnly,
 		anchored: anchored,
+		fixedLen: fixedLen,
+		final:    negate && opts.finalNegation,
 		segments: segments,
+		stripped: stripped,
 	}
 	if basePath != "" {
 		r.basePathSlash = basePath + "/"
 		r.baseSegCount = len(splitPath(basePath))
 	}
+	if opts.canonicalize {
+		r.canonical = canonicalPattern(r, opts.commentChar)
+	}
 	return r, nil
 }
 
+// canonicalPattern rebuilds r's pattern text in a normal form, so that
+// spellings which parse to the same rule compare equal: "a/**/**/b" and
+// "a/**/b", "/a/b" and "a/b", "**/foo" and "foo". The result
+// parses back to an equivalent rule.
+//
+// Segments are never rewritten: git matches "." literally, so "./foo" stays
+// "./foo" (and still matches nothing).
+func canonicalPattern(r *rule, commentChar byte) string {
+	if len(r.segments) == 0 {
+		return r.pattern // degenerate; nothing to normalize
+	}
+	segs := make([]segment, 0, len(r.segments))
+	for i, seg := range r.segments {
+		// Consecutive ** segments match exactly what a single one does
+		// (unless each must consume a directory under DoubleStarMinOne).
+		if seg.doubleStar && !seg.minOne && i > 0 && r.segments[i-1].doubleStar {
+			continue
+		}
+		segs = append(segs, seg)
+	}
+	// A leading ** on an otherwise single-segment pattern is the same as
+	// the bare floating segment, whether or not it was written "/**/foo".
+	anchored := r.anchored
+	if len(segs) == 2 && segs[0].doubleStar && !segs[1].doubleStar {
+		segs = segs[1:]
+		anchored = false
+	}
+
+	var b strings.Builder
+	b.Grow(len(r.pattern))
+	if r.negate {
+		b.WriteByte('!')
+	}
+	// A leading slash is only needed where nothing else anchors the pattern.
+	if anchored && (len(segs) == 1 || segs[0].doubleStar) {
+		b.WriteByte('/')
+	}
+	for i, seg := range segs {
+		if i > 0 {
+			b.WriteByte('/')
+		}
+		if seg.doubleStar {
+			b.WriteString("**")
+			continue
+		}
+		// Re-escape a first character the parser would otherwise consume.
+		if i == 0 && b.Len() == 0 && (seg.value[0] == '!' || seg.value[0] == commentChar) {
+			b.WriteByte('\\')
+		}
+		b.WriteString(seg.value)
+	}
+	if r.dirOnly {
+		b.WriteByte('/')
+	}
+	return b.String()
+}
+
+// PatternsEqual reports whether the .gitignore lines a and b parse to the
+// same rule under git's default dialect, by comparing their canonical forms
+// (see MatcherOptions.Canonicalize). It sees through spellings that differ
+// only in redundant syntax — "a/**/**/b" and "a/**/b", "**/foo" and "foo",
+// "/a/b" and "a/b", trailing whitespace — while anchoring, negation, and a
+// trailing "/" still distinguish patterns: "/foo" and "foo" are not equal.
+//
+// "./foo" is not equal to "foo": git matches "." literally, so "./foo"
+// matches nothing. The comparison is conservative; patterns that differ only
+// in unneeded escapes ("\a" and "a") are reported as different. Lines that
+// produce no rule (blank, comment, or malformed, such as "a//b") are never
+// equal to anything.
+func PatternsEqual(a, b string) bool {
+	opts := defaultParseOptions
+	opts.canonicalize = true
+	ra, _ := parseLineWith(a, 1, "", "", opts)
+	rb, _ := parseLineWith(b, 1, "", "", opts)
+	return ra != nil && rb != nil && ra.canonical == rb.canonical
+}
+
+// translateDoubleStar rewrites the segments of pattern equal to token as
+// "**", and existing "**" segments as the equivalent single-segment "*".
+func translateDoubleStar(pattern, token string) string {
+	parts := strings.Split(pattern, "/")
+	changed := false
+	for i, part := range parts {
+		switch part {
+		case token:
+			parts[i] = "**"
+		case "**":
+			parts[i] = "*"
+		default:
+			continue
+		}
+		changed = true
+	}
+	if !changed {
+		return pattern
+	}
+	return strings.Join(parts, "/")
+}
+
 // determineAnchoring resolves the anchoring state of a pattern line.
 // A pattern is anchored if it starts with / or contains / (except **/ prefix).
 // Returns the anchored flag, the trimmed line, and whether the line became empty
@@ -202,43 +425,43 @@ func determineAnchoring(line string) (anchored bool, trimmed string, emptyAfterS
 	return false, line, false
 }
 
-// parseSegments splits a pattern by "/" and classifies each segment.
+// parseSegments splits a pattern by "/" and classifies each segment in a
+// single scan, without building an intermediate slice of parts. Segment
+// values are substrings of pattern.
 func parseSegments(pattern string) []segment {
-	parts := strings.Split(pattern, "/")
-	segments := make([]segment, 0, len(parts))
-
-	for _, part := range parts {
-		// Skip empty parts (from leading/trailing/double slashes)
-		if part == "" {
+	segments := make([]segment, 0, strings.Count(pattern, "/")+1)
+
+	start := 0
+	seg := segment{}
+	for i := 0; i <= len(pattern); i++ {
+		if i < len(pattern) && pattern[i] != '/' {
+			// Detect wildcard characters and compute flags as we go.
+			switch pattern[i] {
+			case '*':
+				seg.wildcard = true
+				seg.starCount++
+			case '?':
+				seg.wildcard = true
+				seg.hasQuestion = true
+			case '\\':
+				seg.wildcard = true
+				seg.hasEscape = true
+			case '[':
+				seg.wildcard = true
+				seg.hasCharClass = true
+			}
 			continue
 		}
 
-		seg := segment{value: part}
-
-		if part == "**" {
-			seg.doubleStar = true
-			seg.value = ""
-		} else {
-			// Single pass to detect wildcard characters and compute flags.
-			for i := 0; i < len(part); i++ {
-				switch part[i] {
-				case '*':
-					seg.wildcard = true
-					seg.starCount++
-				case '?':
-					seg.wildcard = true
-					seg.hasQuestion = true
-				case '\\':
-					seg.wildcard = true
-					seg.hasEscape = true
-				case '[':
-					seg.wildcard = true
-					seg.hasCharClass = true
-				}
-			}
+		// Skip empty parts (from leading/trailing/double slashes)
+		if part := pattern[start:i]; part == "**" {
+			segments = append(segments, segment{doubleStar: true})
+		} else if part != "" {
+			seg.value = part
+			segments = append(segments, seg)
 		}
-
-		segments = append(segments, seg)
+		start = i + 1
+		seg = segment{}
 	}
 
 	return segments
@@ -192,6 +192,48 @@ func TestParseLine_Anchoring(t *testing.T) {
 	}
 }
 
+func TestParseLine_RedundantDoubleStar(t *testing.T) {
+	// A leading **/ before a single name is dropped, since the name floats
+	// anyway; the rule still reports the pattern as written.
+	tests := []struct {
+		line         string
+		wantSegments string
+		wantFixedLen bool
+	}{
+		{"**/*.log", "*.log(wild)", true},
+		{"**/foo", "foo", true},
+		{"**/**/foo", "foo", true},
+		{"**/build/", "build", true},
+		{"!**/keep.log", "keep.log", true},
+
+		// Kept: the rest has a slash, or the pattern is all **.
+		{"**/foo/bar", "**/foo/bar", false},
+		{"**/foo/**", "**/foo/**", false},
+		{"**", "**", false},
+		{"**/**", "**/**", false},
+		{"/**/foo", "**/foo", false},
+		{"a/**/foo", "a/**/foo", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			r, w := parseLine(tt.line, 1, "", "")
+			if r == nil {
+				t.Fatalf("parseLine(%q) returned nil, warning %v", tt.line, w)
+			}
+			if got := segmentsString(r.segments); got != tt.wantSegments {
+				t.Errorf("parseLine(%q) segments = %q, want %q", tt.line, got, tt.wantSegments)
+			}
+			if r.fixedLen != tt.wantFixedLen {
+				t.Errorf("parseLine(%q).fixedLen = %v, want %v", tt.line, r.fixedLen, tt.wantFixedLen)
+			}
+			if r.pattern != tt.line {
+				t.Errorf("parseLine(%q).pattern = %q, want the pattern as written", tt.line, r.pattern)
+			}
+		})
+	}
+}
+
 func TestParseLine_EscapedHash(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -224,6 +266,143 @@ func TestParseLine_EscapedHash(t *testing.T) {
 	}
 }
 
+func TestParseLine_CommentChar(t *testing.T) {
+	opts := defaultParseOptions
+	opts.commentChar = ';'
+
+	tests := []struct {
+		name        string
+		line        string
+		wantNil     bool
+		wantSegment string
+	}{
+		{"semicolon comment", "; this is a comment", true, ""},
+		{"hash is literal", "#notes", false, "#notes"},
+		{"escaped semicolon", "\\;literal", false, ";literal"},
+		{"negated escaped semicolon", "!\\;literal", false, ";literal"},
+		{"plain pattern", "*.log", false, "*.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := parseLineWith(tt.line, 1, "", "", opts)
+			if w != nil {
+				t.Fatalf("parseLineWith(%q) returned warning: %v", tt.line, w)
+			}
+			if tt.wantNil {
+				if r != nil {
+					t.Errorf("parseLineWith(%q) returned rule, want nil", tt.line)
+				}
+				return
+			}
+			if r == nil {
+				t.Fatalf("parseLineWith(%q) returned nil", tt.line)
+			}
+			if len(r.segments) != 1 || r.segments[0].value != tt.wantSegment {
+				t.Errorf("parseLineWith(%q) segments = %s, want %q",
+					tt.line, segmentsString(r.segments), tt.wantSegment)
+			}
+		})
+	}
+}
+
+func TestCanonicalPattern(t *testing.T) {
+	opts := defaultParseOptions
+	opts.canonicalize = true
+
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"redundant leading slash", []string{"/a/b", "a/b"}, "a/b"},
+		{"single segment keeps anchor", []string{"/build"}, "/build"},
+		{"consecutive double stars", []string{"a/**/**/b", "a/**/b"}, "a/**/b"},
+		{"leading double star", []string{"**/foo", "foo", "/**/foo", "**/**/foo"}, "foo"},
+		{"trailing double star", []string{"logs/**", "logs/**/**"}, "logs/**"},
+		{"dir only", []string{"build/", "**/build/"}, "build/"},
+		{"negation", []string{"!/a/b/", "!a/b/"}, "!a/b/"},
+		{"escaped bang", []string{"\\!keep"}, "\\!keep"},
+		{"escaped hash", []string{"\\#notes"}, "\\#notes"},
+		{"dot segment kept", []string{"./foo"}, "./foo"},
+		{"wildcards kept", []string{"*.log", "**/*.log"}, "*.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, line := range tt.lines {
+				r, _ := parseLineWith(line, 1, "", "", opts)
+				if r == nil {
+					t.Fatalf("parseLineWith(%q) returned nil", line)
+				}
+				if r.canonical != tt.want {
+					t.Errorf("canonical(%q) = %q, want %q", line, r.canonical, tt.want)
+				}
+				if r.pattern != line {
+					t.Errorf("pattern(%q) = %q, want original preserved", line, r.pattern)
+				}
+
+				// The canonical form must itself be canonical.
+				again, _ := parseLineWith(r.canonical, 1, "", "", opts)
+				if again == nil || again.canonical != r.canonical {
+					t.Errorf("canonical(%q) = %q is not stable", line, r.canonical)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalPattern_Disabled(t *testing.T) {
+	r, _ := parseLine("a/**/**/b", 1, "", "")
+	if r.canonical != "" {
+		t.Errorf("canonical = %q without canonicalize, want empty", r.canonical)
+	}
+}
+
+func TestCanonicalPattern_NoSegments(t *testing.T) {
+	// The parser now rejects "///", but a rule without segments must
+	// still be returned as written rather than indexed into.
+	if r, w := parseLineWith("///", 1, "", "", parseOptions{maxPatternLength: -1, commentChar: '#', canonicalize: true}); r != nil || w == nil {
+		t.Errorf("parseLineWith(///) = %+v, %+v; want a warning and no rule", r, w)
+	}
+	if got := canonicalPattern(&rule{pattern: "///", anchored: true}, '#'); got != "///" {
+		t.Errorf("canonicalPattern(no segments) = %q, want %q", got, "///")
+	}
+}
+
+func TestPatternsEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"foo", "foo", true},
+		{"foo//bar", "foo/bar", false}, // "foo//bar" never matches, as in git
+		{"**/foo", "foo", true},
+		{"/a/b", "a/b", true},
+		{"a/**/**/b", "a/**/b", true},
+		{"*.log  ", "*.log", true},
+		{"!foo/", "!foo/", true},
+
+		{"/foo", "foo", false},    // anchored vs floating
+		{"./foo", "foo", false},   // git matches "." literally: ./foo matches nothing
+		{"foo/", "foo", false},    // directory-only
+		{"!foo", "foo", false},    // negation
+		{"\\a", "a", false},       // conservative: unneeded escape
+		{"foo\\", "foo\\", false}, // malformed on both sides
+		{"#foo", "#foo", false},   // comment
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := PatternsEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("PatternsEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := PatternsEqual(tt.b, tt.a); got != tt.want {
+			t.Errorf("PatternsEqual(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
 func TestParseLine_EscapedBang(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -389,6 +568,15 @@ func TestParseLine_Warnings(t *testing.T) {
 		{"escaped backslash", "foo\\\\", false},
 		// Triple backslash: \\\\ is \\+\, trailing lone \ is invalid
 		{"triple backslash", "foo\\\\\\", true},
+		// The trailing / is removed first, exposing the lone backslash
+		{"trailing backslash before slash", "foo\\/", true},
+		{"negated trailing backslash", "!foo\\", true},
+		// An empty path component never matches (git parity)
+		{"double slash", "a//b", true},
+		{"leading double slash", "//a", true},
+		{"trailing double slash", "a//", true},
+		{"double slash after double star", "**//b", true},
+		{"single trailing slash", "a/", false},
 	}
 
 	for _, tt := range tests {
@@ -566,7 +754,7 @@ src/temp
 **/cache
 `)
 
-	rules, warnings := parseLines("", content, -1, "")
+	rules, warnings := parseLines("", content, "", defaultParseOptions)
 
 	if len(warnings) != 0 {
 		t.Errorf("parseLines returned %d warnings, want 0", len(warnings))
@@ -619,7 +807,7 @@ func TestParseLines_WithWarnings(t *testing.T) {
 valid.txt
 `)
 
-	rules, warnings := parseLines("", content, -1, "")
+	rules, warnings := parseLines("", content, "", defaultParseOptions)
 
 	// Should have 2 warnings (! and / become empty)
 	if len(warnings) != 2 {
@@ -639,7 +827,7 @@ func TestParseLines_CRLF(t *testing.T) {
 	// Windows line endings
 	content := []byte("*.log\r\nbuild/\r\n!important.log\r\n")
 
-	rules, warnings := parseLines("", content, -1, "")
+	rules, warnings := parseLines("", content, "", defaultParseOptions)
 
 	if len(warnings) != 0 {
 		t.Errorf("parseLines returned warnings: %v", warnings)
@@ -649,11 +837,41 @@ func TestParseLines_CRLF(t *testing.T) {
 	}
 }
 
+func TestParseLines_FinalLineEnding(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no newline", "*.log\nbuild/"},
+		{"LF", "*.log\nbuild/\n"},
+		{"CR", "*.log\rbuild/\r"},
+		{"CRLF", "*.log\r\nbuild/\r\n"},
+		{"mixed, CR last", "*.log\r\nbuild/\r"},
+		{"BOM, no newline", "\xEF\xBB\xBF*.log\nbuild/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, warnings := parseLines("", []byte(tt.content), "", defaultParseOptions)
+			if len(warnings) != 0 {
+				t.Errorf("parseLines returned warnings: %v", warnings)
+			}
+			if len(rules) != 2 {
+				t.Fatalf("parseLines returned %d rules, want 2", len(rules))
+			}
+			if last := rules[1]; last.pattern != "build/" || last.line != 2 || !last.dirOnly {
+				t.Errorf("last rule = %q (line %d, dirOnly %v), want %q on line 2",
+					last.pattern, last.line, last.dirOnly, "build/")
+			}
+		})
+	}
+}
+
 func TestParseLines_BOM(t *testing.T) {
 	// UTF-8 BOM
 	content := append([]byte{0xEF, 0xBB, 0xBF}, []byte("*.log\nbuild/\n")...)
 
-	rules, warnings := parseLines("", content, -1, "")
+	rules, warnings := parseLines("", content, "", defaultParseOptions)
 
 	if len(warnings) != 0 {
 		t.Errorf("parseLines returned warnings: %v", warnings)
@@ -670,7 +888,7 @@ func TestParseLines_BOM(t *testing.T) {
 func TestParseLines_WithBasePath(t *testing.T) {
 	content := []byte("*.log\ntemp/\n")
 
-	rules, _ := parseLines("src/lib", content, -1, "")
+	rules, _ := parseLines("src/lib", content, "", defaultParseOptions)
 
 	for _, r := range rules {
 		if r.basePath != "src/lib" {
@@ -0,0 +1,59 @@
+package ignore
+
+import (
+	"io/fs"
+)
+
+// AddPredicate registers fn as an additional ignore source for MatchInfo,
+// for metadata rules no pattern can express, such as "ignore files over
+// 100 MB". A predicate that returns true acts like an ignore rule at the
+// lowest priority: it decides only paths that no rule matches, so a
+// matching negation overrides it.
+//
+// fn receives the path and FileInfo passed to MatchInfo. Predicates run in
+// the order they were added, stopping at the first that returns true; they
+// are called without the matcher's lock held and may be called
+// concurrently. A nil fn is ignored. Predicates added to a Sub view stay in
+// that view.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) AddPredicate(fn func(path string, info fs.FileInfo) bool) {
+	if fn == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.predicates = append(m.predicates, fn)
+}
+
+// MatchInfo is MatchWithReason for a path whose metadata is at hand: isDir
+// is taken from info and, when no rule matches the path, the predicates
+// added with AddPredicate are consulted. A path a predicate ignores is
+// reported with Ignored true and Matched false, the way DefaultIgnored
+// reports unmatched paths. Predicates are not consulted for paths that
+// normalize to empty or exceed MaxPathDepth.
+//
+// A nil info makes MatchInfo the same as MatchWithReason(path, false).
+// Pass Lstat info to match a symlink as git does, as a non-directory.
+// OnMatch, if configured, is called once with the final result.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchInfo(path string, info fs.FileInfo) MatchResult {
+	if info == nil {
+		return m.MatchWithReason(path, false)
+	}
+	result := m.matchWithReason(path, info.IsDir())
+	if !result.Matched && !result.Ignored && result.PathDepth > 0 {
+		m.mu.RLock()
+		predicates := m.predicates
+		m.mu.RUnlock()
+		for _, fn := range predicates {
+			if fn(path, info) {
+				result.Ignored = true
+				break
+			}
+		}
+	}
+	m.report(path, info.IsDir(), result)
+	return result
+}
@@ -0,0 +1,82 @@
+package ignore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchInfo_SizePredicate(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"debug.log":  "x",
+		"small.txt":  "x",
+		"big.bin":    string(make([]byte, 200)),
+		"keep.bin":   string(make([]byte, 200)),
+		"huge.log":   string(make([]byte, 200)),
+		"build/a.go": "x",
+	})
+	stat := func(rel string) fs.FileInfo {
+		t.Helper()
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+
+	m := New()
+	m.AddPatterns("", []byte("*.log\n!keep.bin\nbuild/\n"))
+	m.AddPredicate(func(path string, info fs.FileInfo) bool {
+		return !info.IsDir() && info.Size() > 100
+	})
+
+	tests := []struct {
+		path        string
+		wantIgnored bool
+		wantMatched bool
+	}{
+		{"debug.log", true, true},   // pattern
+		{"huge.log", true, true},    // pattern and predicate: the rule is reported
+		{"big.bin", true, false},    // predicate only
+		{"keep.bin", false, true},   // negation overrides the predicate
+		{"small.txt", false, false}, // neither
+		{"build", true, true},       // isDir comes from info
+	}
+	for _, tt := range tests {
+		r := m.MatchInfo(tt.path, stat(tt.path))
+		if r.Ignored != tt.wantIgnored || r.Matched != tt.wantMatched {
+			t.Errorf("MatchInfo(%q) = %+v, want ignored=%v matched=%v", tt.path, r, tt.wantIgnored, tt.wantMatched)
+		}
+	}
+
+	if m.Match("big.bin", false) {
+		t.Error("Match should not consult predicates")
+	}
+	if r := m.MatchInfo("big.bin", nil); r.Ignored {
+		t.Error("MatchInfo with nil info should not consult predicates")
+	}
+}
+
+func TestAddPredicate_SubAndMerge(t *testing.T) {
+	info, err := os.Stat(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := func(string, fs.FileInfo) bool { return true }
+
+	m := New()
+	m.AddPredicate(nil)
+	sub := m.Sub("vendor")
+	sub.AddPredicate(all)
+	if !sub.MatchInfo("lib", info).Ignored {
+		t.Error("predicate added to a Sub view should apply to it")
+	}
+	if m.MatchInfo("vendor/lib", info).Ignored {
+		t.Error("predicate added to a Sub view should stay in the view")
+	}
+	if !Merge(m, sub).MatchInfo("lib", info).Ignored {
+		t.Error("Merge should carry predicates over")
+	}
+}
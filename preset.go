@@ -0,0 +1,53 @@
+package ignore
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// presetFS holds the built-in pattern sets loaded by AddPreset, one
+// presets/<name>.gitignore file per preset. They are small, curated subsets
+// of GitHub's github/gitignore templates (CC0-1.0).
+//
+//go:embed presets/*.gitignore
+var presetFS embed.FS
+
+// AddPreset adds a built-in set of patterns for a common ecosystem, scoped to
+// the root. Available presets:
+//
+//   - "go":      compiled binaries, test binaries, coverage output, go.work
+//   - "node":    node_modules/, npm/yarn/pnpm logs, coverage, build output
+//   - "python":  __pycache__/, *.py[cod], packaging output, virtualenvs
+//   - "macos":   .DS_Store, resource forks, volume metadata
+//   - "windows": Thumbs.db, desktop.ini, $RECYCLE.BIN/, installer files
+//
+// Presets are deliberately small: they cover the files almost every project
+// in the ecosystem wants ignored, not every tool's output. Rules report the
+// source "preset:<name>" in MatchResult.Source. Presets may be combined, and
+// like any AddPatterns call, rules added later take precedence.
+//
+// Returns an error naming the available presets if name is unknown.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) AddPreset(name string) error {
+	content, err := presetFS.ReadFile("presets/" + name + ".gitignore")
+	if err != nil || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	m.addPatternsFromSource("", content, "preset:"+name)
+	return nil
+}
+
+// PresetNames returns the names accepted by AddPreset, sorted.
+func PresetNames() []string {
+	entries, _ := fs.ReadDir(presetFS, "presets")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".gitignore"))
+	}
+	sort.Strings(names)
+	return names
+}
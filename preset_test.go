@@ -0,0 +1,71 @@
+package ignore
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddPreset(t *testing.T) {
+	tests := []struct {
+		preset  string
+		ignored string
+		isDir   bool
+		kept    string
+	}{
+		{"go", "cmd/app.exe", false, "main.go"},
+		{"node", "node_modules", true, "package.json"},
+		{"python", "pkg/__pycache__", true, "pkg/main.py"},
+		{"macos", "docs/.DS_Store", false, "docs/README.md"},
+		{"windows", "photos/Thumbs.db", false, "photos/a.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			m := New()
+			if err := m.AddPreset(tt.preset); err != nil {
+				t.Fatalf("AddPreset(%q) error = %v", tt.preset, err)
+			}
+			if m.RuleCount() == 0 {
+				t.Fatalf("AddPreset(%q) added no rules", tt.preset)
+			}
+			if w := m.Warnings(); len(w) != 0 {
+				t.Errorf("AddPreset(%q) warnings = %+v", tt.preset, w)
+			}
+
+			result := m.MatchWithReason(tt.ignored, tt.isDir)
+			if !result.Ignored {
+				t.Errorf("%s preset: %q not ignored", tt.preset, tt.ignored)
+			}
+			if want := "preset:" + tt.preset; result.Source != want {
+				t.Errorf("%s preset: Source = %q, want %q", tt.preset, result.Source, want)
+			}
+			if m.Match(tt.kept, false) {
+				t.Errorf("%s preset: %q ignored, want kept", tt.preset, tt.kept)
+			}
+		})
+	}
+}
+
+func TestAddPreset_Unknown(t *testing.T) {
+	m := New()
+	for _, name := range []string{"rust", "", "../go", "presets/go"} {
+		err := m.AddPreset(name)
+		if err == nil {
+			t.Errorf("AddPreset(%q) error = nil, want error", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), "go, macos, node, python, windows") {
+			t.Errorf("AddPreset(%q) error = %q, want list of presets", name, err)
+		}
+	}
+	if m.RuleCount() != 0 {
+		t.Errorf("RuleCount() = %d after failed AddPreset, want 0", m.RuleCount())
+	}
+}
+
+func TestPresetNames(t *testing.T) {
+	want := []string{"go", "macos", "node", "python", "windows"}
+	if got := PresetNames(); !equalStrings(got, want) {
+		t.Errorf("PresetNames() = %q, want %q", got, want)
+	}
+}
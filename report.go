@@ -0,0 +1,116 @@
+package ignore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadReport describes the outcome of loading a single ignore file, for
+// editors that render diagnostics inline. See AddPatternsFileReport.
+type LoadReport struct {
+	// Source is the path the patterns were read from.
+	Source string
+
+	// Rules is the number of rules added to the matcher.
+	Rules int
+
+	// Empty reports that the file exists but holds nothing but whitespace,
+	// for tools that warn about an accidentally emptied ignore file. A file
+	// of comments also adds no rules but is not Empty. A missing file is
+	// an error rather than an Empty report.
+	Empty bool
+
+	// Warnings holds the parse warnings for this load only, in the order
+	// they were reported. Warnings that concern the file as a whole (such
+	// as the pattern count limit being reached) have Line 0.
+	Warnings []ParseWarning
+}
+
+// ByLine groups the warnings by line number. Warnings not tied to a line
+// are under key 0. Returns nil if there are no warnings.
+func (r LoadReport) ByLine() map[int][]ParseWarning {
+	if len(r.Warnings) == 0 {
+		return nil
+	}
+	lines := make(map[int][]ParseWarning)
+	for _, w := range r.Warnings {
+		lines[w.Line] = append(lines[w.Line], w)
+	}
+	return lines
+}
+
+// Line returns the warnings reported for line n (1-indexed), in order, or
+// nil if there are none.
+func (r LoadReport) Line(n int) []ParseWarning {
+	var out []ParseWarning
+	for _, w := range r.Warnings {
+		if w.Line == n {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// AddPatternsFileReport is AddPatternsFromFile that also returns a
+// LoadReport for the file: how many rules it added, whether it was empty,
+// and the parse warnings it produced, with line-indexed access. The warnings are still delivered
+// through the WarningHandler or collected for Warnings() as usual; the report
+// is an additional, per-file view of them.
+//
+// If path does not exist or cannot be read, the error is returned wrapped
+// and the report is empty.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) AddPatternsFileReport(basePath, path string) (LoadReport, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return LoadReport{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	n, warnings := m.loadFile(basePath, content, path)
+	empty := len(bytes.TrimSpace(content)) == 0
+	return LoadReport{Source: path, Rules: n, Empty: empty, Warnings: warnings}, nil
+}
+
+// MarshalJSON encodes w in the shape editor and CI problem matchers expect:
+//
+//	{"file":".gitignore","line":3,"column":1,"pattern":"!","message":"..."}
+//
+// file is w.Source, empty when the patterns did not come from a file.
+// Warnings concern a whole line, so column is 1, or 0 along with line for
+// warnings not tied to a line (such as the pattern count limit).
+func (w ParseWarning) MarshalJSON() ([]byte, error) {
+	column := 0
+	if w.Line > 0 {
+		column = 1
+	}
+	return json.Marshal(struct {
+		File    string `json:"file"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		Pattern string `json:"pattern"`
+		Message string `json:"message"`
+	}{w.Source, w.Line, column, w.Pattern, w.Message})
+}
+
+// WarningsJSON returns the collected warnings (see Warnings) as JSON lines:
+// one object per warning in the format of ParseWarning.MarshalJSON, each
+// followed by a newline, ready to feed a CI annotation step. Returns nil if
+// there are no warnings.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) WarningsJSON() ([]byte, error) {
+	warnings := m.Warnings()
+	if len(warnings) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, w := range warnings {
+		if err := enc.Encode(w); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,166 @@
+package ignore
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddPatternsFileReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	content := "*.log\nfoo\\\n# comment\n!\nbuild/\n/\r\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	m := NewWithOptions(MatcherOptions{RejectLegacyLineEndings: true})
+	report, err := m.AddPatternsFileReport("", path)
+	if err != nil {
+		t.Fatalf("AddPatternsFileReport: %v", err)
+	}
+	if report.Source != path || report.Rules != 2 {
+		t.Errorf("report = {Source %q, Rules %d}, want {%q, 2}", report.Source, report.Rules, path)
+	}
+
+	// Line 2: trailing backslash; line 4: empty negation; line 6: empty
+	// after removing the slash, plus the CRLF ending.
+	byLine := report.ByLine()
+	want := map[int]int{2: 1, 4: 1, 6: 2}
+	if len(byLine) != len(want) {
+		t.Fatalf("ByLine() = %+v, want lines %v", byLine, want)
+	}
+	for line, n := range want {
+		if got := report.Line(line); len(got) != n {
+			t.Errorf("Line(%d) = %+v, want %d warnings", line, got, n)
+		}
+		if got := byLine[line]; len(got) != n {
+			t.Errorf("ByLine()[%d] = %+v, want %d warnings", line, got, n)
+		}
+	}
+	if got := report.Line(1); got != nil {
+		t.Errorf("Line(1) = %+v, want nil", got)
+	}
+
+	// The warnings are still delivered through the usual channel.
+	if got := m.Warnings(); len(got) != len(report.Warnings) {
+		t.Errorf("Warnings() has %d entries, report has %d", len(got), len(report.Warnings))
+	}
+}
+
+func TestAddPatternsFileReport_NoWarnings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	if err := os.WriteFile(path, []byte("*.log\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	report, err := New().AddPatternsFileReport("", path)
+	if err != nil {
+		t.Fatalf("AddPatternsFileReport: %v", err)
+	}
+	if report.Rules != 1 || report.Warnings != nil || report.ByLine() != nil {
+		t.Errorf("report = %+v, want 1 rule and no warnings", report)
+	}
+}
+
+func TestAddPatternsFileReport_Missing(t *testing.T) {
+	report, err := New().AddPatternsFileReport("", filepath.Join(t.TempDir(), "missing"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want wrapped os.ErrNotExist", err)
+	}
+	if report.Source != "" || report.Rules != 0 || report.Empty {
+		t.Errorf("report = %+v, want empty", report)
+	}
+}
+
+func TestAddPatternsFileReport_Empty(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		empty   bool
+	}{
+		{"zero bytes", "", true},
+		{"blank lines", "\n  \r\n\t\n", true},
+		{"comments only", "# nothing yet\n", false},
+		{"patterns", "*.log\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_"))
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			report, err := New().AddPatternsFileReport("", path)
+			if err != nil {
+				t.Fatalf("AddPatternsFileReport: %v", err)
+			}
+			if report.Empty != tt.empty || report.Source != path {
+				t.Errorf("report = %+v, want Empty %v for an existing file", report, tt.empty)
+			}
+		})
+	}
+}
+
+func TestParseWarning_MarshalJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	if err := os.WriteFile(path, []byte("*.log\n!\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	m := New()
+	if err := m.AddPatternsFromFile("", path); err != nil {
+		t.Fatalf("AddPatternsFromFile: %v", err)
+	}
+
+	warnings := m.Warnings()
+	if len(warnings) != 1 || warnings[0].Source != path {
+		t.Fatalf("Warnings() = %+v, want one warning from %s", warnings, path)
+	}
+	got, err := json.Marshal(warnings[0])
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want, _ := json.Marshal(path)
+	wantJSON := `{"file":` + string(want) + `,"line":2,"column":1,"pattern":"!","message":"pattern is empty after processing"}`
+	if string(got) != wantJSON {
+		t.Errorf("MarshalJSON() = %s\nwant %s", got, wantJSON)
+	}
+
+	// Warnings not tied to a line have no column either.
+	got, _ = json.Marshal(ParseWarning{Message: "limit"})
+	if string(got) != `{"file":"","line":0,"column":0,"pattern":"","message":"limit"}` {
+		t.Errorf("MarshalJSON() = %s", got)
+	}
+}
+
+func TestWarningsJSON(t *testing.T) {
+	m := New()
+	if out, err := m.WarningsJSON(); out != nil || err != nil {
+		t.Errorf("WarningsJSON() = %q, %v; want nil for no warnings", out, err)
+	}
+
+	m.AddPatterns("", []byte("!\nfoo\\\n"))
+	out, err := m.WarningsJSON()
+	if err != nil {
+		t.Fatalf("WarningsJSON: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("WarningsJSON() = %q, want 2 lines", out)
+	}
+	for i, line := range lines {
+		var w struct {
+			File    string `json:"file"`
+			Line    int    `json:"line"`
+			Pattern string `json:"pattern"`
+		}
+		if err := json.Unmarshal([]byte(line), &w); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if w.File != "" || w.Line != i+1 {
+			t.Errorf("line %d = %+v, want no file and line %d", i+1, w, i+1)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package ignore
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RuleInfo describes a single compiled rule for introspection and tooling.
+// It is a read-only copy; changing it has no effect on the Matcher.
+type RuleInfo struct {
+	// Pattern is the pattern text as written (after trailing-whitespace
+	// trimming), including any leading "!" — the same string reported by
+	// MatchResult.Rule.
+	Pattern string
+
+	// Canonical is the pattern in canonical form, so that equivalent
+	// spellings compare equal. Empty unless the Matcher was created with
+	// MatcherOptions.Canonicalize.
+	Canonical string
+
+	// Source identifies the file or stream that supplied the rule (see
+	// MatchResult.Source). Empty for rules added via AddPatterns.
+	Source string
+
+	// BasePath is the directory scope of the rule. Empty means root.
+	BasePath string
+
+	// Line is the 1-indexed line number in the source.
+	Line int
+
+	// Index is the rule's position in evaluation order (0-based). Later
+	// indexes take precedence under last-match-wins.
+	Index int
+
+	// Negate reports whether the pattern started with "!".
+	Negate bool
+
+	// DirOnly reports whether the pattern ended with "/".
+	DirOnly bool
+
+	// Anchored reports whether the pattern only matches relative to BasePath
+	// (leading "/" or an interior "/").
+	Anchored bool
+
+	// Stripped is the number of leading path components of Pattern that
+	// are not part of the compiled rule: the components AddPatternsRebased
+	// removed, and a redundant leading "**" ("**/*.log" compiles to
+	// "*.log"). The rule matches as if Pattern began after them.
+	Stripped int
+}
+
+// info returns the exported description of r at evaluation position index.
+func (r *rule) info(index int) RuleInfo {
+	return RuleInfo{
+		Pattern:   r.pattern,
+		Canonical: r.canonical,
+		Source:    r.source,
+		BasePath:  r.basePath,
+		Line:      r.line,
+		Index:     index,
+		Negate:    r.negate,
+		DirOnly:   r.dirOnly,
+		Anchored:  r.anchored,
+		Stripped:  r.stripped,
+	}
+}
+
+// MatchingRules returns every rule whose pattern matches path, in evaluation
+// order, regardless of whether it ignores or re-includes it. Unlike
+// MatchWithReason it does not stop at the final decision, so an editor can
+// highlight every line that is relevant to a path.
+//
+// Only rules matching the path itself are reported; rules that match an
+// ancestor directory (and would block a re-include via the parent-excluded
+// rule) are not. The backtrack budget is shared across all rules, as in
+// MatchWithReason. Returns nil if no rule matches.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) MatchingRules(path string, isDir bool) []RuleInfo {
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return nil
+	}
+	ctx := m.newContext()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []RuleInfo
+	for i := range m.rules {
+		r := &m.rules[i]
+		if matchRule(r, path, pathSegments, isDir, &ctx) {
+			result = append(result, r.info(i))
+		}
+	}
+	return result
+}
+
+// UnreachableRules returns the ignore rules that can never change a match
+// outcome because an earlier catch-all rule in the same or an enclosing
+// scope already ignores every path they could match. A catch-all is a
+// non-negated "*" or "**" pattern (any anchoring for "**"; floating only for
+// "*", since "/*" matches top-level entries alone).
+//
+// The analysis is conservative. A negation rule between the catch-all and a
+// later rule, in an overlapping scope, makes that later rule reachable again
+// (it may re-ignore something the negation re-included). Negation rules are
+// never reported: they are exactly the rules that can still change outcomes
+// after a catch-all.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) UnreachableRules() []RuleInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []RuleInfo
+	var catchAlls []string // basePaths of catch-alls still in force
+	for i := range m.rules {
+		r := &m.rules[i]
+		if r.negate {
+			// The negation reopens every catch-all whose scope overlaps it.
+			kept := catchAlls[:0]
+			for _, base := range catchAlls {
+				if !scopeCovers(base, r.basePath) && !scopeCovers(r.basePath, base) {
+					kept = append(kept, base)
+				}
+			}
+			catchAlls = kept
+			continue
+		}
+		for _, base := range catchAlls {
+			if scopeCovers(base, r.basePath) {
+				result = append(result, r.info(i))
+				break
+			}
+		}
+		if r.isCatchAll() {
+			catchAlls = append(catchAlls, r.basePath)
+		}
+	}
+	return result
+}
+
+// CaseRedundantRules returns rules that differ from an earlier rule only in
+// letter case ("*.LOG" after "*.log") and are therefore redundant under the
+// matcher's case mode. With CaseInsensitive unset such rules are distinct,
+// so the result is always nil.
+//
+// A later rule is only reported if no rule of the opposite polarity in an
+// overlapping scope sits between the two: in "*.LOG", "!keep.log", "*.log"
+// the last line re-ignores what the negation re-included, so it is needed.
+// Exact textual duplicates are not reported.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) CaseRedundantRules() []RuleInfo {
+	if !m.opts.CaseInsensitive {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Rules are bucketed by their identity under sameRule. For each, the
+	// latest index and pattern text are kept, plus the latest index whose
+	// text differs from that one, so a case variant is found in constant
+	// time. lastAt[p] and lastUnder[p] hold, per basePath, the latest rule
+	// of polarity p (1 for negations) at that scope and at or under it.
+	type variants struct {
+		last, other int
+		pattern     string
+	}
+	seen := make(map[string]*variants)
+	var lastAt, lastUnder [2]map[string]int
+	for p := range lastAt {
+		lastAt[p] = make(map[string]int)
+		lastUnder[p] = make(map[string]int)
+	}
+
+	var result []RuleInfo
+	for i := range m.rules {
+		r := &m.rules[i]
+		p := 0
+		if r.negate {
+			p = 1
+		}
+
+		// The latest rule of the opposite polarity whose scope overlaps
+		// r's: at r's scope or an enclosing one, or anywhere under r's.
+		barrier := -1
+		if j, ok := lastUnder[1-p][r.basePath]; ok {
+			barrier = j
+		}
+		forEachScope(r.basePath, func(scope string) {
+			if j, ok := lastAt[1-p][scope]; ok && j > barrier {
+				barrier = j
+			}
+			lastUnder[p][scope] = i
+		})
+		lastAt[p][r.basePath] = i
+
+		key := ruleKey(r)
+		v := seen[key]
+		if v == nil {
+			seen[key] = &variants{last: i, other: -1, pattern: r.pattern}
+			continue
+		}
+		variant := v.last
+		if v.pattern == r.pattern {
+			variant = v.other
+		}
+		if variant > barrier {
+			result = append(result, r.info(i))
+		}
+		if v.pattern != r.pattern {
+			v.other, v.pattern = v.last, r.pattern
+		}
+		v.last = i
+	}
+	return result
+}
+
+// ruleKey encodes what sameRule compares, so rules sameRule reports equal
+// share a key.
+func ruleKey(r *rule) string {
+	var b strings.Builder
+	writeField := func(s string) {
+		b.WriteString(strconv.Itoa(len(s)))
+		b.WriteByte(':')
+		b.WriteString(s)
+	}
+	writeField(r.basePath)
+	b.WriteByte(binaryFlags(r.negate, r.dirOnly, r.anchored))
+	for _, seg := range r.segments {
+		if seg.doubleStar {
+			b.WriteByte('*')
+			continue
+		}
+		writeField(seg.value)
+	}
+	return b.String()
+}
+
+// forEachScope calls fn with basePath and each enclosing scope, ending
+// with the root "".
+func forEachScope(basePath string, fn func(scope string)) {
+	for {
+		fn(basePath)
+		if basePath == "" {
+			return
+		}
+		k := strings.LastIndexByte(basePath, '/')
+		if k < 0 {
+			k = 0
+		}
+		basePath = basePath[:k]
+	}
+}
+
+// RulesContaining returns the rules, in evaluation order and across all
+// basePaths, with a literal segment equal to token: "build/", "/out/build"
+// and "build/**/*.o" all contain "build", while "build*" and "*build" do
+// not. Escapes are resolved first, so "\#tmp" contains "#tmp". The comparison
+// follows the matcher's case mode. Only pattern segments are searched, not
+// BasePath. Returns nil if no rule contains token.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) RulesContaining(token string) []RuleInfo {
+	if token == "" {
+		return nil
+	}
+	if m.opts.CaseInsensitive {
+		token = strings.ToLower(token)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []RuleInfo
+	for i := range m.rules {
+		for _, seg := range m.rules[i].segments {
+			if seg.literal() == token {
+				result = append(result, m.rules[i].info(i))
+				break
+			}
+		}
+	}
+	return result
+}
+
+// DoubleStarRules returns the rules, in evaluation order and across all
+// basePaths, with a "**" segment, for auditing slow matching: "**" is what
+// drives backtracking cost (see BatchStats and ProbeLimits). A redundant
+// leading "**/", as in "**/*.log", is dropped at parse time, so such rules
+// are not returned. "**.log" is an ordinary wildcard and is not returned
+// either. Returns nil if no rule has a "**" segment.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) DoubleStarRules() []RuleInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []RuleInfo
+	for i := range m.rules {
+		for _, seg := range m.rules[i].segments {
+			if seg.doubleStar {
+				result = append(result, m.rules[i].info(i))
+				break
+			}
+		}
+	}
+	return result
+}
+
+// literal returns the text a segment without wildcards matches, with
+// escapes resolved, or "" if the segment has wildcards or is "**".
+func (seg segment) literal() string {
+	switch {
+	case seg.doubleStar || seg.starCount > 0 || seg.hasQuestion || seg.hasCharClass:
+		return ""
+	case !seg.hasEscape:
+		return seg.value
+	}
+	var b strings.Builder
+	for i := 0; i < len(seg.value); i++ {
+		if seg.value[i] == '\\' && i+1 < len(seg.value) {
+			i++
+		}
+		b.WriteByte(seg.value[i])
+	}
+	return b.String()
+}
+
+// RuleSpecificity scores how narrowly r selects paths, so tools showing
+// several matching rules can rank them; higher is more specific. It is
+// a heuristic for display only and plays no part in matching, where the
+// last matching rule wins regardless of its score.
+//
+// Each literal path segment, in the pattern or in BasePath, scores 4. A
+// segment with wildcards scores 2, or 3 when it also holds literal text
+// ("*.js"), and "**" scores nothing. Anchored and directory-only rules score
+// 1 more each, and negation does not count. So "/src/build/out.js" (13)
+// ranks above "src/*.js" (8), "build/" (5) and "*.js" (3).
+//
+// The score is computed from r.Pattern, r.BasePath and the flags alone;
+// equivalent spellings such as "**/foo" and "foo" score the same. The
+// r.Stripped components a rebased rule no longer matches do not count.
+func RuleSpecificity(r RuleInfo) int {
+	pattern := r.Pattern
+	if r.Negate {
+		pattern = strings.TrimPrefix(pattern, "!")
+	}
+	if r.DirOnly {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	score := 4 * len(splitPath(r.BasePath))
+	segments := parseSegments(pattern)
+	if r.Stripped <= len(segments) {
+		segments = segments[r.Stripped:]
+	}
+	for _, seg := range segments {
+		switch {
+		case seg.doubleStar:
+		case seg.starCount == 0 && !seg.hasQuestion && !seg.hasCharClass:
+			score += 4 // literal, possibly with escapes
+		case strings.Trim(seg.value, "*?") != "":
+			score += 3
+		default:
+			score += 2
+		}
+	}
+	if r.Anchored {
+		score++
+	}
+	if r.DirOnly {
+		score++
+	}
+	return score
+}
+
+// sameRule reports whether a and b are the same compiled rule: same scope,
+// flags, and segment values. In case-insensitive mode segment values are
+// stored lowercased, so rules differing only by case compare equal.
+func sameRule(a, b *rule) bool {
+	if a.basePath != b.basePath || a.negate != b.negate || a.dirOnly != b.dirOnly ||
+		a.anchored != b.anchored || len(a.segments) != len(b.segments) {
+		return false
+	}
+	for k := range a.segments {
+		if a.segments[k].doubleStar != b.segments[k].doubleStar || a.segments[k].value != b.segments[k].value {
+			return false
+		}
+	}
+	return true
+}
+
+// isCatchAll reports whether r ignores every path in its scope.
+func (r *rule) isCatchAll() bool {
+	if r.negate || r.dirOnly || len(r.segments) != 1 {
+		return false
+	}
+	seg := r.segments[0]
+	return seg.doubleStar || (seg.value == "*" && !r.anchored)
+}
+
+// scopeCovers reports whether rules scoped to outer can see paths under the
+// inner basePath (outer is inner or one of its ancestors).
+func scopeCovers(outer, inner string) bool {
+	return outer == "" || inner == outer || strings.HasPrefix(inner, outer+"/")
+}
@@ -0,0 +1,327 @@
+package ignore
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func rulePatterns(infos []RuleInfo) []string {
+	if infos == nil {
+		return nil
+	}
+	out := make([]string, len(infos))
+	for i, ri := range infos {
+		out[i] = ri.Pattern
+	}
+	return out
+}
+
+func TestMatchingRules_Overlapping(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\nlogs/\n!important.log\nimportant.*\n"))
+	m.AddPatternsWithSource("src", "src/.gitignore", []byte("*.log\n"))
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  []string
+	}{
+		{"important.log", false, []string{"*.log", "!important.log", "important.*"}},
+		{"src/important.log", false, []string{"*.log", "!important.log", "important.*", "*.log"}},
+		{"debug.log", false, []string{"*.log"}},
+		{"logs", true, []string{"logs/"}},
+		{"logs", false, nil},
+		{"main.go", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := rulePatterns(m.MatchingRules(tt.path, tt.isDir))
+			if !equalStrings(got, tt.want) {
+				t.Errorf("MatchingRules(%q, %v) = %q, want %q", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchingRules_Metadata(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("# header\n/build/\n"))
+	m.AddPatternsWithSource("src", "src/.gitignore", []byte("!keep.txt\n"))
+
+	got := m.MatchingRules("build", true)
+	want := RuleInfo{Pattern: "/build/", Line: 2, Index: 0, DirOnly: true, Anchored: true}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("MatchingRules(build) = %+v, want [%+v]", got, want)
+	}
+
+	got = m.MatchingRules("src/keep.txt", false)
+	want = RuleInfo{Pattern: "!keep.txt", Source: "src/.gitignore", BasePath: "src", Line: 1, Index: 1, Negate: true}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("MatchingRules(src/keep.txt) = %+v, want [%+v]", got, want)
+	}
+}
+
+func TestUnreachableRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"leading star", "*\n*.log\nbuild/\n", []string{"*.log", "build/"}},
+		{"double star", "**\nfoo\n", []string{"foo"}},
+		{"negation reopens", "*\n!*.go\nvendor/*.go\n", nil},
+		{"rules before catch-all", "*.log\n*\n", nil},
+		{"anchored star is not catch-all", "/*\nsrc/*.log\n", nil},
+		{"dir-only star is not catch-all", "*/\nfoo\n", nil},
+		{"negations never reported", "*\n!keep\n", nil},
+		{"later ignore after negation", "*\n!keep\n*.tmp\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.AddPatterns("", []byte(tt.content))
+			got := rulePatterns(m.UnreachableRules())
+			if !equalStrings(got, tt.want) {
+				t.Errorf("UnreachableRules() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnreachableRules_Scopes(t *testing.T) {
+	m := New()
+	m.AddPatterns("src", []byte("*\n"))
+	m.AddPatterns("", []byte("*.log\n"))      // root scope: not covered by src's catch-all
+	m.AddPatterns("src/lib", []byte("*.o\n")) // nested under src: unreachable
+	m.AddPatterns("srcx", []byte("*.tmp\n"))  // sibling with shared prefix: reachable
+	m.AddPatterns("lib", []byte("!keep\n"))   // unrelated negation does not reopen src
+	m.AddPatterns("src", []byte("generated\n"))
+
+	got := m.UnreachableRules()
+	want := []RuleInfo{
+		{Pattern: "*.o", BasePath: "src/lib", Line: 1, Index: 2},
+		{Pattern: "generated", BasePath: "src", Line: 1, Index: 5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("UnreachableRules() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("UnreachableRules()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMatchingRules_Canonical(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{Canonicalize: true})
+	m.AddPatterns("", []byte("src/gen/\n/src/gen/\n"))
+
+	got := m.MatchingRules("src/gen", true)
+	if len(got) != 2 {
+		t.Fatalf("MatchingRules(src/gen) = %+v, want 2 rules", got)
+	}
+	if got[0].Pattern == got[1].Pattern {
+		t.Errorf("Pattern should preserve original spelling, got %q twice", got[0].Pattern)
+	}
+	for _, ri := range got {
+		if ri.Canonical != "src/gen/" {
+			t.Errorf("Canonical(%q) = %q, want %q", ri.Pattern, ri.Canonical, "src/gen/")
+		}
+	}
+}
+
+func TestCaseRedundantRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"extension", "*.log\n*.LOG\n", []string{"*.LOG"}},
+		{"dir-only", "Build/\nbuild/\n", []string{"build/"}},
+		{"negations", "*\n!Keep.txt\n!keep.txt\n", []string{"!keep.txt"}},
+		{"exact duplicate not reported", "*.log\n*.log\n", nil},
+		{"different flags", "build\nBUILD/\n/Build\n", nil},
+		{"intervening negation", "*.LOG\n!keep.log\n*.log\n", nil},
+		{"intervening ignore", "!Keep\nkeep\n!keep\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ci := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+			ci.AddPatterns("", []byte(tt.content))
+			if got := rulePatterns(ci.CaseRedundantRules()); !equalStrings(got, tt.want) {
+				t.Errorf("case-insensitive: CaseRedundantRules() = %q, want %q", got, tt.want)
+			}
+
+			cs := New()
+			cs.AddPatterns("", []byte(tt.content))
+			if got := cs.CaseRedundantRules(); got != nil {
+				t.Errorf("case-sensitive: CaseRedundantRules() = %+v, want nil", got)
+			}
+		})
+	}
+}
+
+func TestCaseRedundantRules_Scopes(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	m.AddPatterns("src", []byte("*.log\n"))
+	m.AddPatterns("lib", []byte("!x.log\n")) // unrelated scope: does not intervene
+	m.AddPatterns("src", []byte("*.Log\n"))
+	m.AddPatterns("", []byte("*.LOG\n")) // different scope: not a duplicate
+
+	got := m.CaseRedundantRules()
+	if len(got) != 1 || got[0].Pattern != "*.Log" || got[0].Index != 2 {
+		t.Errorf("CaseRedundantRules() = %+v, want [*.Log at index 2]", got)
+	}
+}
+
+// caseRedundantReference is the direct quadratic definition of
+// CaseRedundantRules: scan back from each rule until a rule of the other
+// polarity in an overlapping scope.
+func caseRedundantReference(rules []rule) []string {
+	var out []string
+	for i := range rules {
+		r := &rules[i]
+		for j := i - 1; j >= 0; j-- {
+			e := &rules[j]
+			if e.negate != r.negate {
+				if scopeCovers(e.basePath, r.basePath) || scopeCovers(r.basePath, e.basePath) {
+					break
+				}
+				continue
+			}
+			if e.pattern != r.pattern && sameRule(e, r) {
+				out = append(out, r.basePath+":"+r.pattern)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func TestCaseRedundantRules_MatchesReference(t *testing.T) {
+	pool := []string{"*.log", "*.LOG", "*.Log", "!keep.log", "!KEEP.log", "build/", "Build/", "BUILD", "/a/**/b", "/A/**/B"}
+	bases := []string{"", "src", "src/gen", "lib"}
+	rng := rand.New(rand.NewSource(1))
+
+	for n := 0; n < 300; n++ {
+		m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+		var desc []string
+		for k := 0; k < 1+rng.Intn(10); k++ {
+			base, pat := bases[rng.Intn(len(bases))], pool[rng.Intn(len(pool))]
+			m.AddPatterns(base, []byte(pat+"\n"))
+			desc = append(desc, base+":"+pat)
+		}
+		var got []string
+		for _, ri := range m.CaseRedundantRules() {
+			got = append(got, ri.BasePath+":"+ri.Pattern)
+		}
+		if want := caseRedundantReference(m.rules); !equalStrings(got, want) {
+			t.Fatalf("rules %q: CaseRedundantRules() = %q, want %q", strings.Join(desc, " "), got, want)
+		}
+	}
+}
+
+func BenchmarkCaseRedundantRules(b *testing.B) {
+	var content strings.Builder
+	for i := 0; i < 20000; i++ {
+		content.WriteString("dir" + strings.Repeat("x", i%7) + "/file" + string(rune('a'+i%26)) + ".LOG\n")
+	}
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	m.AddPatterns("", []byte(content.String()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.CaseRedundantRules()
+	}
+}
+
+func TestRuleSpecificity(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*\n*.js\nbuild/\nsrc/*.js\n/src/build/out.js\n**/out.js\nout.js\n!out.js\nsrc/**/out.js\n"))
+	m.AddPatterns("src", []byte("*.js\n"))
+	score := make(map[string]int)
+	for i := range m.rules {
+		r := m.rules[i].info(i)
+		key := r.Pattern
+		if r.BasePath != "" {
+			key = r.BasePath + ":" + key
+		}
+		score[key] = RuleSpecificity(r)
+	}
+
+	// Each pattern is strictly more specific than the next.
+	order := []string{"/src/build/out.js", "src/**/out.js", "src/*.js", "src:*.js", "build/", "out.js", "*.js", "*"}
+	for i := 1; i < len(order); i++ {
+		if score[order[i-1]] <= score[order[i]] {
+			t.Errorf("RuleSpecificity(%q) = %d, want more than RuleSpecificity(%q) = %d",
+				order[i-1], score[order[i-1]], order[i], score[order[i]])
+		}
+	}
+
+	// Equivalent spellings and negation do not change the score.
+	for _, p := range []string{"**/out.js", "!out.js"} {
+		if score[p] != score["out.js"] {
+			t.Errorf("RuleSpecificity(%q) = %d, want %d as for out.js", p, score[p], score["out.js"])
+		}
+	}
+	if got := score["/src/build/out.js"]; got != 13 {
+		t.Errorf(`RuleSpecificity("/src/build/out.js") = %d, want 13`, got)
+	}
+}
+
+func TestRulesContaining(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n*.log\nbuild*\n/out/build\n!build/keep\n"))
+	m.AddPatterns("src", []byte("*build\nbuild/**/*.o\n\\#build\n"))
+	m.AddPatterns("build", []byte("*.tmp\n"))
+
+	var got []string
+	for _, r := range m.RulesContaining("build") {
+		got = append(got, r.BasePath+":"+r.Pattern)
+	}
+	want := []string{":build/", ":/out/build", ":!build/keep", "src:build/**/*.o"}
+	if !equalStrings(got, want) {
+		t.Errorf("RulesContaining(build) = %q, want %q", got, want)
+	}
+
+	if r := m.RulesContaining("#build"); len(r) != 1 || r[0].Pattern != "\\#build" {
+		t.Errorf("RulesContaining(#build) = %+v, want the escaped rule", r)
+	}
+	if r := m.RulesContaining("BUILD"); r != nil {
+		t.Errorf("RulesContaining(BUILD) = %+v, want nil for a case-sensitive matcher", r)
+	}
+	if r := m.RulesContaining(""); r != nil {
+		t.Errorf("RulesContaining(\"\") = %+v, want nil", r)
+	}
+
+	ci := NewWithOptions(MatcherOptions{CaseInsensitive: true})
+	ci.AddPatterns("", []byte("Build/\n"))
+	if r := ci.RulesContaining("BUILD"); len(r) != 1 {
+		t.Errorf("case-insensitive RulesContaining(BUILD) = %+v, want 1 rule", r)
+	}
+}
+
+func TestDoubleStarRules(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n**/*.tmp\nlogs/**\n**.bak\na/**/b\n!**/keep/**\n/build/\n"))
+	m.AddPatterns("src", []byte("gen/**/*.go\nvendor/\n"))
+
+	var got []string
+	for _, r := range m.DoubleStarRules() {
+		got = append(got, r.BasePath+":"+r.Pattern)
+	}
+	// **/*.tmp loses its redundant **, and **.bak has no ** segment.
+	want := []string{":logs/**", ":a/**/b", ":!**/keep/**", "src:gen/**/*.go"}
+	if !equalStrings(got, want) {
+		t.Errorf("DoubleStarRules() = %q, want %q", got, want)
+	}
+
+	plain := New()
+	plain.AddPatterns("", []byte("*.log\nbuild/\n"))
+	if r := plain.DoubleStarRules(); r != nil {
+		t.Errorf("DoubleStarRules() = %+v, want nil", r)
+	}
+}
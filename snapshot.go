@@ -0,0 +1,72 @@
+package ignore
+
+// Snapshot is an immutable view of a Matcher's rules and options at the time
+// Snapshot was called. Its methods never take the Matcher's lock, so
+// read-heavy services can match from many goroutines without contending on
+// the Matcher's RWMutex.
+// Later AddPatterns calls on the Matcher do not affect an existing Snapshot;
+// take a new one to pick them up.
+//
+// A Snapshot is safe for concurrent use. Results are identical to the
+// Matcher methods of the same name at the moment the snapshot was taken,
+// including for Sub views, and OnMatch is called the same way.
+type Snapshot struct {
+	m *Matcher // private copy whose rules are never appended to
+}
+
+// Snapshot returns an immutable, lock-free view of the current rules and
+// options. Taking a snapshot is O(1): it shares the compiled rules rather
+// than copying them.
+//
+// Thread-safe: can be called concurrently.
+func (m *Matcher) Snapshot() *Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Clip capacity so later appends to m never write into storage the
+	// snapshot can see. The fallback is snapshotted along with m, so it is
+	// frozen too.
+	s := &Snapshot{m: &Matcher{
+		rules:        m.rules[:len(m.rules):len(m.rules)],
+		opts:         m.opts,
+		prefix:       m.prefix,
+		negateEnd:    m.negateEnd,
+		forceTracked: m.forceTracked[:len(m.forceTracked):len(m.forceTracked)],
+		trace:        m.trace,
+	}}
+	if m.fallback != nil {
+		s.m.fallback = m.fallback.Snapshot().m
+	}
+	return s
+}
+
+// Match reports whether path should be ignored, as Matcher.Match does.
+func (s *Snapshot) Match(path string, isDir bool) bool {
+	if s.m.observing() {
+		return s.MatchWithReason(path, isDir).Ignored
+	}
+	var segBuf [32]string
+	path, pathSegments, isDir, ok := s.m.preparePath(path, isDir, segBuf[:0])
+	if !ok {
+		return false
+	}
+	return s.decide(path, pathSegments, isDir, true).Ignored
+}
+
+// MatchWithReason returns detailed information about why a path matches, as
+// Matcher.MatchWithReason does.
+func (s *Snapshot) MatchWithReason(path string, isDir bool) MatchResult {
+	var result MatchResult
+	var segBuf [32]string
+	if p, pathSegments, dir, ok := s.m.preparePath(path, isDir, segBuf[:0]); ok {
+		result = s.decide(p, pathSegments, dir, false)
+	}
+	s.m.report(path, isDir, result)
+	return result
+}
+
+// decide is Matcher.evaluate without the lock: s.m.rules never changes.
+func (s *Snapshot) decide(path string, pathSegments []string, isDir, settle bool) MatchResult {
+	ctx := s.m.newContext()
+	return s.m.resolve(s.m.settleAt(settle), path, pathSegments, isDir, &ctx)
+}
@@ -0,0 +1,96 @@
+package ignore
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot_MatchesMatcher(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n!keep.log\nbuild/\n"))
+	m.AddPatternsWithSource("src", "src/.gitignore", []byte("gen/\n"))
+	s := m.Snapshot()
+
+	paths := []struct {
+		path  string
+		isDir bool
+	}{
+		{"debug.log", false},
+		{"keep.log", false},
+		{"build/out.js", false},
+		{"src/gen", true},
+		{"src/main.go", false},
+		{"", false},
+	}
+	for _, p := range paths {
+		if got, want := s.MatchWithReason(p.path, p.isDir), m.MatchWithReason(p.path, p.isDir); !reflect.DeepEqual(got, want) {
+			t.Errorf("Snapshot.MatchWithReason(%q) = %v, want %v", p.path, got, want)
+		}
+		if got, want := s.Match(p.path, p.isDir), m.Match(p.path, p.isDir); got != want {
+			t.Errorf("Snapshot.Match(%q) = %v, want %v", p.path, got, want)
+		}
+	}
+}
+
+func TestSnapshot_Isolation(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+	s := m.Snapshot()
+
+	m.AddPatterns("", []byte("*.tmp\n!debug.log\n"))
+	if s.Match("x.tmp", false) {
+		t.Error("snapshot should not see rules added after it was taken")
+	}
+	if !s.Match("debug.log", false) {
+		t.Error("snapshot should not see negations added after it was taken")
+	}
+	if !m.Match("x.tmp", false) || m.Match("debug.log", false) {
+		t.Error("matcher should see rules added after the snapshot")
+	}
+}
+
+func TestSnapshot_SubAndOptions(t *testing.T) {
+	var calls int
+	m := NewWithOptions(MatcherOptions{
+		CaseInsensitive: true,
+		OnMatch:         func(MatchResult) { calls++ },
+	})
+	m.AddPatterns("pkg", []byte("*.LOG\n"))
+	s := m.Sub("pkg").Snapshot()
+
+	if !s.Match("Debug.log", false) {
+		t.Error("snapshot of a Sub view should match relative paths case-insensitively")
+	}
+	if r := s.MatchWithReason("main.go", false); r.Matched {
+		t.Errorf("MatchWithReason(main.go) = %v, want no match", r)
+	}
+	if calls != 2 {
+		t.Errorf("OnMatch called %d times, want 2", calls)
+	}
+}
+
+func TestSnapshot_ConcurrentWithAdd(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("*.log\n"))
+	s := m.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if !s.Match("a.log", false) || s.Match("a.tmp", false) {
+					t.Error("snapshot result changed during concurrent AddPatterns")
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			m.AddPatterns("", []byte("*.tmp\n"))
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AddPatternsStream reads gitignore content from r line by line and adds
+// its rules under basePath, reporting each rule and warning through the
+// callbacks as soon as its line is parsed, for progress displays and large
+// files. Callbacks fire in line order; either may be nil.
+//
+// Lines are normalized as AddPatterns normalizes content (BOM, CRLF and CR
+// endings, trailing whitespace), and line numbers and warnings are the
+// same. RuleInfo.Index is the position the rule takes when the stream is
+// added, unless other patterns are added concurrently.
+//
+// The rules are added together once r is exhausted, so Match never sees a
+// partly read stream. MaxPatterns is applied at that point, with the usual
+// warning sent to onWarning. All warnings are also delivered through the
+// WarningHandler or collected for Warnings() as usual.
+//
+// The callbacks return an error so a caller can abort early, on the first
+// warning for instance; plain func(RuleInfo) and func(ParseWarning)
+// callbacks would have no way to stop the read. Return nil to keep going.
+// If a callback returns an error, reading stops and that error is returned;
+// if reading r fails, the error is returned wrapped. Either way no rules
+// are added and no warnings are delivered except through the callbacks.
+// The MaxPatterns warning is the exception: it is sent once the rules are
+// added, so an error onWarning returns for it does not undo them.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) AddPatternsStream(basePath string, r io.Reader, onRule func(RuleInfo) error, onWarning func(ParseWarning) error) error {
+	if r == nil {
+		return nil
+	}
+	normalizedBase := normalizePath(basePath)
+	if m.prefix != "" {
+		normalizedBase = m.scope(basePath)
+	}
+	opts := m.opts.parseOptions()
+	first := m.RuleCount()
+
+	var rules []rule
+	var warnings []ParseWarning
+	var raw []byte
+	warn := func(w ParseWarning) error {
+		warnings = append(warnings, w)
+		if onWarning != nil {
+			return onWarning(w)
+		}
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	lineNum := 0
+	seenCRLF, seenCR := false, false
+	for {
+		chunk, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading patterns: %w", err)
+		}
+		if m.opts.PreserveRawContent {
+			raw = append(raw, chunk...)
+		}
+		if lineNum == 0 {
+			for strings.HasPrefix(chunk, "\xEF\xBB\xBF") { // UTF-8 BOM
+				chunk = chunk[3:]
+			}
+		}
+
+		// A chunk ends at "\n" (or at EOF); a lone "\r" inside it also ends
+		// a line, as in normalizeContent.
+		for line, rest, more := chunk, "", true; more; line = rest {
+			lineNum++
+			var crlf, cr bool
+			if i := strings.IndexAny(line, "\r\n"); i < 0 {
+				more = false
+			} else {
+				crlf = strings.HasPrefix(line[i:], "\r\n")
+				cr = line[i] == '\r' && !crlf
+				end := i + 1
+				if crlf {
+					end++
+				}
+				line, rest = line[:i], line[end:]
+				more = rest != ""
+			}
+			if opts.rejectLegacyEOL && crlf && !seenCRLF {
+				seenCRLF = true
+				if err := warn(ParseWarning{Line: lineNum, Message: "CRLF line ending, expected LF", BasePath: normalizedBase}); err != nil {
+					return err
+				}
+			}
+			if opts.rejectLegacyEOL && cr && !seenCR {
+				seenCR = true
+				if err := warn(ParseWarning{Line: lineNum, Message: "CR-only line ending, expected LF", BasePath: normalizedBase}); err != nil {
+					return err
+				}
+			}
+
+			parsed, w := parseContentLine(line, lineNum, normalizedBase, "", opts)
+			if w != nil {
+				if err := warn(*w); err != nil {
+					return err
+				}
+			}
+			if parsed != nil {
+				rules = append(rules, *parsed)
+				if onRule != nil {
+					if err := onRule(parsed.info(first + len(rules) - 1)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if err != nil {
+			break // io.EOF
+		}
+	}
+
+	_, all := m.commitRules(normalizedBase, "", rules, warnings, raw)
+	if onWarning != nil {
+		for _, w := range all[len(warnings):] {
+			if err := onWarning(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package ignore
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAddPatternsStream_Order(t *testing.T) {
+	content := "\xEF\xBB\xBF*.log\n!\nbuild/\r\nfoo\\\n# comment\r/\n!keep.log\n"
+
+	var events []string
+	m := NewWithOptions(MatcherOptions{RejectLegacyLineEndings: true})
+	err := m.AddPatternsStream("src", strings.NewReader(content),
+		func(r RuleInfo) error {
+			events = append(events, "rule "+r.Pattern)
+			return nil
+		},
+		func(w ParseWarning) error {
+			events = append(events, "warning "+w.Pattern)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("AddPatternsStream: %v", err)
+	}
+
+	// Lines: 1 *.log, 2 !, 3 build/ (CRLF), 4 foo\, 5 # comment (CR), 6 /, 7 !keep.log.
+	want := []string{
+		"rule *.log", "warning !", "warning ", "rule build/",
+		"warning foo\\", "warning ", "warning /", "rule !keep.log",
+	}
+	if !equalStrings(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+
+	// The result is the same as loading the content in one call.
+	whole := NewWithOptions(MatcherOptions{RejectLegacyLineEndings: true})
+	whole.AddPatterns("src", []byte(content))
+	if !reflect.DeepEqual(m.rules, whole.rules) {
+		t.Errorf("stream rules differ from AddPatterns:\ngot  %+v\nwant %+v", m.rules, whole.rules)
+	}
+	if got, want := lineNumbers(m.Warnings()), lineNumbers(whole.Warnings()); !reflect.DeepEqual(got, want) {
+		t.Errorf("warning lines = %v, want %v as from AddPatterns", got, want)
+	}
+	if !m.Match("src/debug.log", false) || m.Match("src/keep.log", false) {
+		t.Error("streamed rules should match like AddPatterns rules")
+	}
+}
+
+// lineNumbers counts warnings by line number.
+func lineNumbers(warnings []ParseWarning) map[int]int {
+	lines := make(map[int]int)
+	for _, w := range warnings {
+		lines[w.Line]++
+	}
+	return lines
+}
+
+func TestAddPatternsStream_Index(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("a\nb\n"))
+
+	var indexes []int
+	err := m.AddPatternsStream("", strings.NewReader("c\nd\n"), func(r RuleInfo) error {
+		indexes = append(indexes, r.Index)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatalf("AddPatternsStream: %v", err)
+	}
+	if !reflect.DeepEqual(indexes, []int{2, 3}) {
+		t.Errorf("indexes = %v, want [2 3]", indexes)
+	}
+	if rules := m.MatchingRules("d", false); len(rules) != 1 || rules[0].Index != 3 {
+		t.Errorf("MatchingRules(d) = %+v, want index 3", rules)
+	}
+}
+
+func TestAddPatternsStream_Abort(t *testing.T) {
+	stop := errors.New("stop")
+	m := New()
+	var seen int
+	err := m.AddPatternsStream("", strings.NewReader("*.log\n!\n*.tmp\n"),
+		func(RuleInfo) error { seen++; return nil },
+		func(ParseWarning) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("err = %v, want the callback's error", err)
+	}
+	if seen != 1 || m.RuleCount() != 0 || m.Warnings() != nil {
+		t.Errorf("after abort: %d rules seen, RuleCount %d, warnings %v; want 1, 0, none", seen, m.RuleCount(), m.Warnings())
+	}
+
+	// A read error also adds nothing.
+	r := iotest.TimeoutReader(strings.NewReader(strings.Repeat("*.log\n", 2000)))
+	if err := m.AddPatternsStream("", r, nil, nil); !errors.Is(err, iotest.ErrTimeout) {
+		t.Errorf("err = %v, want wrapped iotest.ErrTimeout", err)
+	}
+	if m.RuleCount() != 0 {
+		t.Errorf("RuleCount() = %d after a read error, want 0", m.RuleCount())
+	}
+
+	if err := m.AddPatternsStream("", nil, nil, nil); err != nil {
+		t.Errorf("nil reader: err = %v, want nil", err)
+	}
+}
+
+func TestAddPatternsStream_MaxPatterns(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{MaxPatterns: 2})
+	var warnings []ParseWarning
+	err := m.AddPatternsStream("", strings.NewReader("a\nb\nc\n"), nil, func(w ParseWarning) error {
+		warnings = append(warnings, w)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("AddPatternsStream: %v", err)
+	}
+	if m.RuleCount() != 2 || len(warnings) != 1 || warnings[0].Line != 0 {
+		t.Errorf("RuleCount() = %d, warnings %+v; want 2 rules and one limit warning", m.RuleCount(), warnings)
+	}
+}
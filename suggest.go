@@ -0,0 +1,53 @@
+package ignore
+
+import (
+	"strings"
+)
+
+// SuggestPattern returns a gitignore line that ignores exactly path (and,
+// for a directory, its contents) when added to the root .gitignore — the
+// line an editor's "ignore this file" action would append.
+//
+// The path is normalized as Match normalizes it. The result is anchored and
+// in canonical form: a top-level name gets a leading "/" ("/secret.txt"),
+// while a nested path is already anchored by its interior slash
+// ("src/secret.txt"). Directories get a trailing "/". Glob characters, a
+// leading "!" or "#", and trailing spaces are escaped with a backslash so
+// they match literally.
+//
+// SuggestPattern returns "" if path normalizes to empty or lies outside the
+// root, or if its name ends in a tab, which gitignore cannot express.
+func SuggestPattern(path string, isDir bool) string {
+	path = strings.TrimPrefix(normalizePath(path), "/")
+	if path == "" || path == "." || strings.HasSuffix(path, "\t") {
+		return ""
+	}
+
+	var b strings.Builder
+	b.Grow(len(path) + 8)
+	if !strings.Contains(path, "/") {
+		b.WriteByte('/')
+	} else if path[0] == '!' || path[0] == '#' {
+		b.WriteByte('\\')
+	}
+
+	// Trailing spaces survive only if escaped, and only when they end the
+	// line (a directory's trailing "/" protects them).
+	trailing := len(path)
+	if !isDir {
+		trailing = len(strings.TrimRight(path, " "))
+	}
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '\\' || c == '*' || c == '?' || c == '[':
+			b.WriteByte('\\')
+		case c == ' ' && i >= trailing:
+			b.WriteByte('\\')
+		}
+		b.WriteByte(path[i])
+	}
+	if isDir {
+		b.WriteByte('/')
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package ignore
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestSuggestPattern(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  string
+	}{
+		{"top-level file", "secret.txt", false, "/secret.txt"},
+		{"nested file", "src/secret.txt", false, "src/secret.txt"},
+		{"top-level dir", "build", true, "/build/"},
+		{"nested dir", "src/gen", true, "src/gen/"},
+		{"normalized", "./src//gen/", true, "src/gen/"},
+		{"leading slash", "/secret.txt", false, "/secret.txt"},
+		{"glob characters", "a*b?[c].txt", false, "/a\\*b\\?\\[c].txt"},
+		{"leading bang nested", "!x/y", false, "\\!x/y"},
+		{"leading hash nested", "#x/y", false, "\\#x/y"},
+		{"leading bang top-level", "!important", false, "/!important"},
+		{"trailing spaces", "src/name  ", false, "src/name\\ \\ "},
+		{"trailing space dir", "dir ", true, "/dir /"},
+		{"inner space", "my file.txt", false, "/my file.txt"},
+		{"empty", "", false, ""},
+		{"dot", ".", true, ""},
+		{"outside root", "../x", false, ""},
+		{"trailing tab", "x\t", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestPattern(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("SuggestPattern(%q, %v) = %q, want %q", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestPattern_Backslash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("backslash is a path separator on Windows; literal-backslash filenames are not representable")
+	}
+
+	pattern := SuggestPattern("src/a\\b", false)
+	if pattern != "src/a\\\\b" {
+		t.Fatalf("SuggestPattern(src/a\\b) = %q, want %q", pattern, "src/a\\\\b")
+	}
+	m := New()
+	m.AddPatterns("", []byte(pattern+"\n"))
+	if !m.Match("src/a\\b", false) || m.Match("src/ab", false) {
+		t.Errorf("pattern %q should match only the literal-backslash name", pattern)
+	}
+}
+
+func TestSuggestPattern_RoundTrip(t *testing.T) {
+	tests := []struct {
+		path    string
+		isDir   bool
+		sibling string // a path the pattern must not ignore
+	}{
+		{"secret.txt", false, "src/secret.txt"},
+		{"src/secret.txt", false, "lib/src/secret.txt"},
+		{"build", true, "src/build"},
+		{"a*b?[c].txt", false, "axb?c.txt"},
+		{"!x/y", false, "x/y"},
+		{"#x/y", false, "x/y"},
+		{"src/name  ", false, "src/name"},
+		{"**", true, "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			pattern := SuggestPattern(tt.path, tt.isDir)
+			m := New()
+			m.AddPatterns("", []byte(pattern+"\n"))
+			if !m.Match(tt.path, tt.isDir) {
+				t.Errorf("pattern %q does not ignore %q", pattern, tt.path)
+			}
+			if tt.isDir && !m.Match(tt.path+"/inner.txt", false) {
+				t.Errorf("pattern %q does not ignore contents of %q", pattern, tt.path)
+			}
+			if m.Match(tt.sibling, false) {
+				t.Errorf("pattern %q also ignores %q", pattern, tt.sibling)
+			}
+		})
+	}
+}
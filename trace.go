@@ -0,0 +1,96 @@
+package ignore
+
+import "sync"
+
+// TracedMatch is one match decision kept by MatcherOptions.TraceHistory. See
+// RecentMatches.
+type TracedMatch struct {
+	// Path is the path as passed to the matching method. For
+	// MatchComponents it is the components joined with "/", and for
+	// Classify the node's ClassifiedTree.Path.
+	Path string
+
+	// IsDir is the directory flag as passed to the matching method.
+	IsDir bool
+
+	// Result is the full decision, as MatchWithReason would return it.
+	Result MatchResult
+}
+
+// matchTrace is the ring buffer behind MatcherOptions.TraceHistory. It has
+// its own lock so recording never contends with AddPatterns for mu.
+type matchTrace struct {
+	mu   sync.Mutex
+	buf  []TracedMatch
+	next int  // index the next decision is written to
+	full bool // buf has wrapped, so next is also the oldest entry
+}
+
+// newMatchTrace returns a ring buffer for the last n decisions, or nil if
+// n is not positive.
+func newMatchTrace(n int) *matchTrace {
+	if n <= 0 {
+		return nil
+	}
+	return &matchTrace{buf: make([]TracedMatch, n)}
+}
+
+func (t *matchTrace) record(path string, isDir bool, result MatchResult) {
+	t.mu.Lock()
+	t.buf[t.next] = TracedMatch{Path: path, IsDir: isDir, Result: result}
+	if t.next++; t.next == len(t.buf) {
+		t.next = 0
+		t.full = true
+	}
+	t.mu.Unlock()
+}
+
+// recent returns the recorded decisions, oldest first.
+func (t *matchTrace) recent() []TracedMatch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]TracedMatch(nil), t.buf[:t.next]...)
+	}
+	out := make([]TracedMatch, 0, len(t.buf))
+	out = append(out, t.buf[t.next:]...)
+	return append(out, t.buf[:t.next]...)
+}
+
+// RecentMatches returns the last MatcherOptions.TraceHistory match
+// decisions made through m, oldest first, each with the path it was made
+// for and its full MatchResult. Decisions are recorded wherever OnMatch
+// would be called, including through Sub views, Snapshots and the walkers
+// that share m's history; a matcher returned by Merge or Compact keeps a
+// history of its own. Returns nil when TraceHistory is not set or nothing
+// has been matched yet.
+//
+// The returned slice is a copy; mutating it does not affect the matcher.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) RecentMatches() []TracedMatch {
+	if m.trace == nil {
+		return nil
+	}
+	if recent := m.trace.recent(); len(recent) > 0 {
+		return recent
+	}
+	return nil
+}
+
+// observing reports whether match decisions are reported anywhere, so the
+// full result must be computed rather than settled early.
+func (m *Matcher) observing() bool {
+	return m.opts.OnMatch != nil || m.trace != nil
+}
+
+// report passes the decision for path to the trace history and OnMatch,
+// whichever are configured. It must be called outside mu.
+func (m *Matcher) report(path string, isDir bool, result MatchResult) {
+	if m.trace != nil {
+		m.trace.record(path, isDir, result)
+	}
+	if m.opts.OnMatch != nil {
+		m.opts.OnMatch(result)
+	}
+}
@@ -0,0 +1,109 @@
+package ignore
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRecentMatches_RingBuffer(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{TraceHistory: 3})
+	m.AddPatterns("", []byte("*.log\n!keep.log\nbuild/\n"))
+
+	if got := m.RecentMatches(); got != nil {
+		t.Errorf("RecentMatches() = %+v, want nil before any match", got)
+	}
+
+	m.Match("a.log", false)
+	if got := m.RecentMatches(); len(got) != 1 || got[0].Path != "a.log" || !got[0].Result.Ignored {
+		t.Errorf("RecentMatches() = %+v, want the a.log decision", got)
+	}
+
+	m.Match("keep.log", false)
+	m.MatchWithReason("build", true)
+	m.Match("main.go", false)
+	m.MatchMany([]string{"b.log", "src/"}, nil)
+
+	got := m.RecentMatches()
+	want := []string{"main.go", "b.log", "src/"}
+	if len(got) != len(want) {
+		t.Fatalf("RecentMatches() has %d entries, want %d", len(got), len(want))
+	}
+	for i, p := range want {
+		if got[i].Path != p {
+			t.Errorf("RecentMatches()[%d].Path = %q, want %q", i, got[i].Path, p)
+		}
+	}
+	// Match settles early without a trace; the trace carries the full
+	// decision, including the deciding rule.
+	if r := got[1].Result; !r.Ignored || r.Rule != "*.log" || r.Line != 1 {
+		t.Errorf("b.log result = %+v, want ignored by *.log on line 1", r)
+	}
+	if got[2].IsDir || got[2].Result.Ignored {
+		t.Errorf("src/ entry = %+v, want IsDir as passed and not ignored", got[2])
+	}
+
+	got[0].Path = "changed"
+	if m.RecentMatches()[0].Path != "main.go" {
+		t.Error("RecentMatches() should return a copy")
+	}
+}
+
+func TestRecentMatches_Sharing(t *testing.T) {
+	var calls int
+	m := NewWithOptions(MatcherOptions{TraceHistory: 10, OnMatch: func(MatchResult) { calls++ }})
+	m.AddPatterns("", []byte("*.log\n"))
+
+	m.Sub("src").Match("a.log", false)
+	m.Snapshot().Match("b.log", false)
+	m.MatchComponents([]string{"c", "d.log"}, false)
+	m.MatchNS("x", "e.log", false)
+
+	var paths []string
+	for _, tm := range m.RecentMatches() {
+		paths = append(paths, tm.Path)
+	}
+	if want := []string{"a.log", "b.log", "c/d.log", "e.log"}; !equalStrings(paths, want) {
+		t.Errorf("RecentMatches() paths = %q, want %q", paths, want)
+	}
+	if calls != 4 {
+		t.Errorf("OnMatch called %d times, want 4 alongside the trace", calls)
+	}
+
+	if got := m.Compact().RecentMatches(); got != nil {
+		t.Errorf("Compact().RecentMatches() = %+v, want a fresh history", got)
+	}
+	if got := New().RecentMatches(); got != nil {
+		t.Errorf("RecentMatches() = %+v, want nil without TraceHistory", got)
+	}
+}
+
+func TestRecentMatches_Concurrent(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{TraceHistory: 16})
+	m.AddPatterns("", []byte("*.log\n"))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				m.Match(fmt.Sprintf("%d/%d.log", g, i), false)
+				if i%50 == 0 {
+					m.RecentMatches()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	got := m.RecentMatches()
+	if len(got) != 16 {
+		t.Fatalf("RecentMatches() has %d entries, want 16", len(got))
+	}
+	for _, tm := range got {
+		if !tm.Result.Ignored {
+			t.Errorf("entry %+v should be ignored", tm)
+		}
+	}
+}
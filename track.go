@@ -0,0 +1,94 @@
+package ignore
+
+import (
+	"strings"
+)
+
+// AddForceTrackedDir marks the directory dir as force-tracked, modelling
+// "git add -f dir": the directory and everything under it are never ignored
+// by the rules already in effect above it, however broad they are. With
+// "build/" ignored and "build/keepme" force-tracked, Match("build/keepme/a.o",
+// false) is false while Match("build/other.o", false) stays true.
+//
+// Rules scoped to the force-tracked directory or below it — typically loaded
+// from its own .gitignore with a basePath of "build/keepme" or deeper — are
+// more specific than the override and still apply to paths under it. When
+// force-tracked directories nest, the innermost one decides which rules are
+// in scope. DefaultIgnored does not apply under a force-tracked directory.
+//
+// dir is normalized like a basePath and, for a Sub view, taken as relative
+// to the view. An empty dir is ignored.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) AddForceTrackedDir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir = m.scope(dir)
+	if dir == "" {
+		return
+	}
+	if m.opts.CaseInsensitive {
+		dir = strings.ToLower(dir)
+	}
+	m.forceTracked = appendUnique(m.forceTracked, dir)
+}
+
+// appendUnique appends dir to dirs unless it is already present.
+func appendUnique(dirs []string, dir string) []string {
+	for _, d := range dirs {
+		if d == dir {
+			return dirs
+		}
+	}
+	return append(dirs, dir)
+}
+
+// trackedDir returns the innermost force-tracked directory containing path
+// (or equal to it), or "" if there is none. Callers must hold mu.
+func (m *Matcher) trackedDir(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	best := ""
+	for _, t := range m.forceTracked {
+		if len(t) > len(best) && (path == t || strings.HasPrefix(path, t+"/")) {
+			best = t
+		}
+	}
+	return best
+}
+
+// rulesWithin returns the consulted rules whose basePath is dir or lies
+// below it. Callers must hold mu.
+func (m *Matcher) rulesWithin(dir string) []rule {
+	var within []rule
+	for _, r := range m.consulted() {
+		base := r.basePath
+		if m.opts.CaseInsensitive {
+			base = strings.ToLower(base)
+		}
+		if base == dir || strings.HasPrefix(base, dir+"/") {
+			within = append(within, r)
+		}
+	}
+	return within
+}
+
+// tracksBelow reports whether a force-tracked directory lies strictly below
+// dir, so that a walker must descend into dir even when dir is ignored.
+//
+// Thread-safe: can be called concurrently with Match.
+func (m *Matcher) tracksBelow(dir string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dir = m.scope(dir)
+	if m.opts.CaseInsensitive {
+		dir = strings.ToLower(dir)
+	}
+	for _, t := range m.forceTracked {
+		if dir == "" || strings.HasPrefix(t, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
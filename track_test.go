@@ -0,0 +1,156 @@
+package ignore
+
+import (
+	"io/fs"
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAddForceTrackedDir(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n*.log\n"))
+	m.AddForceTrackedDir("build/keepme")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"build", true, true},
+		{"build/other.o", false, true},
+		{"build/keepmenot/a.o", false, true},
+		{"build/keepme", true, false},
+		{"build/keepme/a.o", false, false},
+		{"build/keepme/sub/b.o", false, false},
+		{"build/keepme/debug.log", false, false},
+		{"debug.log", false, true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+		if got := m.MatchWithReason(tt.path, tt.isDir).Ignored; got != tt.want {
+			t.Errorf("MatchWithReason(%q, %v).Ignored = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+		if got := m.Snapshot().Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Snapshot().Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	if r := m.MatchWithReason("build/keepme/a.o", false); r.Matched || r.PathDepth != 3 {
+		t.Errorf("MatchWithReason under tracked dir = %+v, want unmatched at depth 3", r)
+	}
+}
+
+func TestAddForceTrackedDir_InnerRulesApply(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n"))
+	m.AddPatterns("build", []byte("*.o\n"))
+	m.AddPatterns("build/keepme", []byte("*.tmp\n"))
+	m.AddForceTrackedDir("build/keepme")
+
+	if m.Match("build/keepme/a.o", false) {
+		t.Error("rules scoped above the tracked directory should not apply under it")
+	}
+	r := m.MatchWithReason("build/keepme/scratch.tmp", false)
+	if !r.Ignored || r.Rule != "*.tmp" {
+		t.Errorf("rule from the tracked directory's own file should apply, got %+v", r)
+	}
+
+	// A nested tracked directory narrows the rules in scope again.
+	m.AddForceTrackedDir("build/keepme/tmp")
+	if m.Match("build/keepme/tmp/x.tmp", false) {
+		t.Error("innermost tracked directory should decide which rules apply")
+	}
+}
+
+func TestAddForceTrackedDir_SubAndOptions(t *testing.T) {
+	m := NewWithOptions(MatcherOptions{CaseInsensitive: true, DefaultIgnored: true})
+	m.AddPatterns("", []byte("!src/\n"))
+
+	sub := m.Sub("vendor")
+	sub.AddForceTrackedDir("LIB")
+	if sub.Match("lib/x.go", false) {
+		t.Error("tracked directory added to a Sub view should be relative to the view")
+	}
+	if sub.Match("LIB/y.go", false) {
+		t.Error("tracked directory should be case-folded under CaseInsensitive")
+	}
+	if !sub.Match("other/x.go", false) {
+		t.Error("DefaultIgnored should still apply outside the tracked directory")
+	}
+	if !m.Match("vendor/lib/x.go", false) {
+		t.Error("tracked directory added to a Sub view should stay in the view")
+	}
+
+	m.AddForceTrackedDir("")
+	if !m.Match("x.go", false) {
+		t.Error("empty tracked directory should be ignored")
+	}
+}
+
+func TestAddForceTrackedDir_Classify(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []byte("build/\n"))
+	m.AddForceTrackedDir("build/keepme")
+
+	tree := FileTree{IsDir: true, Children: []FileTree{
+		{Name: "build", IsDir: true, Children: []FileTree{
+			{Name: "a.o"},
+			{Name: "keepme", IsDir: true, Children: []FileTree{{Name: "b.o"}}},
+		}},
+	}}
+	got := m.Classify(tree)
+	build := got.Children[0]
+	if !build.Result.Ignored || !build.Children[0].Result.Ignored {
+		t.Errorf("build and build/a.o should be ignored: %+v", build)
+	}
+	keep := build.Children[1]
+	if keep.Result.Ignored || keep.Children[0].Result.Ignored {
+		t.Errorf("build/keepme and its contents should not be ignored: %+v", keep)
+	}
+}
+
+func TestAddForceTrackedDir_WalkDirFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":               {Data: []byte("build/\n")},
+		"build/a.o":                {Data: []byte("x")},
+		"build/other/b.o":          {Data: []byte("x")},
+		"build/keepme/.gitignore":  {Data: []byte("*.tmp\n")},
+		"build/keepme/c.o":         {Data: []byte("x")},
+		"build/keepme/scratch.tmp": {Data: []byte("x")},
+		"build/keepme/deep/d.o":    {Data: []byte("x")},
+		"src/main.go":              {Data: []byte("x")},
+	}
+
+	m := New()
+	m.AddForceTrackedDir("build/keepme")
+
+	var got []string
+	err := m.WalkDirFS(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDirFS: %v", err)
+	}
+	sort.Strings(got)
+
+	want := []string{
+		".gitignore",
+		"build/keepme/.gitignore",
+		"build/keepme/c.o",
+		"build/keepme/deep/d.o",
+		"src/main.go",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
@@ -35,12 +35,20 @@ type walkBackend struct {
 //     an OS-native path (slash on Linux/macOS, backslash on Windows), an
 //     fs.DirEntry, and a non-nil err if the entry could not be read. Ignored
 //     entries are silently skipped — fn is not called for them.
-//   - Ignored directories are pruned (their contents are not visited).
+//   - Ignored directories are pruned (their contents are not visited). This
+//     loses nothing: as in git, a negation cannot re-include a path inside
+//     an ignored directory, so Match reports all of its contents as ignored.
+//     To reach "dir/keep/important" under "dir/**", un-ignore the
+//     directories with "!dir/**/" before the negation.
 //   - The .git directory at the repository root is always pruned, regardless
 //     of matcher rules, to avoid walking git internals. Match itself does NOT
 //     treat .git as special — this prune is a WalkDir-specific behavior. To
 //     walk .git anyway, use filepath.WalkDir directly with Match for filtering.
-//   - Symlinks are not followed (filepath.WalkDir Lstat semantics).
+//   - Symlinks are not followed (filepath.WalkDir Lstat semantics). As in
+//     git, a symlink is matched by its own name as a non-directory, even
+//     when it points to a directory: "build/" does not match a link named
+//     build, and rules for the target do not apply to the link. Callers
+//     that want the target's status must resolve it and match that path.
 //
 // Paths supplied to user fn are OS-native (the same as filepath.WalkDir).
 // Internally the matcher receives the slash-normalised relative path.
@@ -72,26 +80,72 @@ func (m *Matcher) WalkDirFS(fsys fs.FS, root string, fn fs.WalkDirFunc) error {
 	return m.walkInternal(fsBackend(fsys), root, fn)
 }
 
-// walkInternal is the shared engine behind WalkDir and WalkDirFS.
+// WalkDirAll walks the file tree rooted at root like WalkDir, but calls fn
+// for ignored entries too, passing ignored = true, so tools can report what
+// the rules exclude without building a full list first. relPath is the
+// forward-slash path relative to root ("." for root itself) — the same path
+// handed to Match — and d is the entry from filepath.WalkDir.
+//
+// Ignored directories are still pruned after fn sees them, unless a
+// force-tracked directory lies below (see AddForceTrackedDir). Returning
+// fs.SkipDir from fn skips a directory's contents, or the rest of a file's
+// directory; fs.SkipAll ends the walk; any other error aborts it and is
+// returned. Nested .gitignore discovery and the .git prune work as in
+// WalkDir. Since fn has no error parameter, traversal errors (an unreadable
+// directory or .gitignore) abort the walk and are returned from WalkDirAll.
+//
+// Thread-safe: see WalkDir's concurrency notes.
+func (m *Matcher) WalkDirAll(root string, fn func(relPath string, d fs.DirEntry, ignored bool) error) error {
+	return m.walkEntries(osBackend, root, func(_, rel string, d fs.DirEntry, ignored bool, err error) error {
+		if err != nil {
+			return err
+		}
+		return fn(rel, d, ignored)
+	})
+}
+
+// walkVisitFunc receives each entry seen by walkEntries: the backend path,
+// the slash-separated path relative to root, the entry, whether the matcher
+// ignores it, and a non-nil err (with an empty rel) if it could not be read.
+type walkVisitFunc func(path, rel string, d fs.DirEntry, ignored bool, err error) error
+
+// walkInternal is the shared engine behind WalkDir and WalkDirFS: it hands
+// fn the entries walkEntries reports as not ignored.
 func (m *Matcher) walkInternal(b walkBackend, root string, fn fs.WalkDirFunc) error {
+	return m.walkEntries(b, root, func(path, _ string, d fs.DirEntry, ignored bool, err error) error {
+		if ignored {
+			return nil
+		}
+		return fn(path, d, err)
+	})
+}
+
+// walkEntries walks root with b, loading nested .gitignore files as it
+// descends, and passes every entry to visit with its ignored status.
+// Ignored directories are pruned once visit returns.
+func (m *Matcher) walkEntries(b walkBackend, root string, visit walkVisitFunc) error {
 	// Snapshot rules and opts under the read lock so the walker is unaffected
 	// by concurrent AddPatterns calls on the receiver.
 	m.mu.RLock()
 	child := &Matcher{
-		opts:  m.opts,
-		rules: append([]rule(nil), m.rules...),
+		opts:         m.opts,
+		rules:        append([]rule(nil), m.rules...),
+		forceTracked: append([]string(nil), m.forceTracked...),
+		fallback:     m.fallback,
+		trace:        m.trace,
+		negateEnd:    m.negateEnd,
 	}
 	m.mu.RUnlock()
 
 	return b.walkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return fn(path, d, err)
+			return visit(path, "", d, false, err)
 		}
 
 		// Compute path relative to root using forward slashes for matching.
 		rel, relErr := b.relPath(root, path)
 		if relErr != nil {
-			return fn(path, d, relErr)
+			return visit(path, "", d, false, relErr)
 		}
 
 		if d.IsDir() {
@@ -102,15 +156,23 @@ func (m *Matcher) walkInternal(b walkBackend, root string, fn fs.WalkDirFunc) er
 				return fs.SkipDir
 			}
 
-			// Prune ignored directories. The root is always kept.
+			// Prune ignored directories. The root is always kept, and so is
+			// an ignored directory with a force-tracked one below it: the
+			// walk descends to reach the tracked one.
 			if rel != "." && child.Match(rel, true) {
+				if cbErr := visit(path, rel, d, true, nil); cbErr != nil {
+					return cbErr
+				}
+				if child.tracksBelow(rel) {
+					return nil
+				}
 				return fs.SkipDir
 			}
 
 			// Discover a .gitignore in this directory and load it into the
 			// per-walk child matcher. ReadFile returns a not-exist error for
 			// directories without a .gitignore — that's the common case and
-			// silently ignored. Other read errors flow through fn.
+			// silently ignored. Other read errors flow through visit.
 			gitignorePath := b.joinPath(path, ".gitignore")
 			content, readErr := b.readFile(gitignorePath)
 			switch {
@@ -121,19 +183,15 @@ func (m *Matcher) walkInternal(b walkBackend, root string, fn fs.WalkDirFunc) er
 				}
 				child.addPatternsFromSource(basePath, content, gitignorePath)
 			case !errors.Is(readErr, fs.ErrNotExist):
-				if cbErr := fn(path, d, fmt.Errorf("reading %s: %w", gitignorePath, readErr)); cbErr != nil {
+				if cbErr := visit(path, "", d, false, fmt.Errorf("reading %s: %w", gitignorePath, readErr)); cbErr != nil {
 					return cbErr
 				}
 			}
 
-			return fn(path, d, nil)
+			return visit(path, rel, d, false, nil)
 		}
 
-		// File: skip silently if ignored, otherwise hand to caller.
-		if child.Match(rel, false) {
-			return nil
-		}
-		return fn(path, d, nil)
+		return visit(path, rel, d, child.Match(rel, false), nil)
 	})
 }
 
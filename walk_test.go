@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -311,6 +312,212 @@ func TestWalkDir_ConcurrentMatchOnReceiver(t *testing.T) {
 	wg.Wait()
 }
 
+func TestWalkDirAll_ReportsIgnored(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":        "*.log\nbuild/\n",
+		"main.go":           "x",
+		"debug.log":         "x",
+		"build/out.js":      "x",
+		"src/.gitignore":    "*.tmp\n",
+		"src/a.go":          "x",
+		"src/scratch.tmp":   "x",
+		".git/HEAD":         "x",
+		"src/deep/note.log": "x",
+	})
+
+	got := map[string]bool{}
+	err := New().WalkDirAll(root, func(relPath string, d fs.DirEntry, ignored bool) error {
+		got[relPath] = ignored
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDirAll: %v", err)
+	}
+
+	want := map[string]bool{
+		".":                 false,
+		".gitignore":        false,
+		"main.go":           false,
+		"debug.log":         true,
+		"build":             true, // reported, then pruned
+		"src":               false,
+		"src/.gitignore":    false,
+		"src/a.go":          false,
+		"src/scratch.tmp":   true, // nested .gitignore applies
+		"src/deep":          false,
+		"src/deep/note.log": true,
+	}
+	if len(got) != len(want) {
+		t.Errorf("visited %v\nwant %v", got, want)
+	}
+	for p, w := range want {
+		if ig, ok := got[p]; !ok || ig != w {
+			t.Errorf("%s: ignored = %v (visited %v), want %v", p, ig, ok, w)
+		}
+	}
+}
+
+func TestWalkDirAll_SymlinkMatchedByLinkName(t *testing.T) {
+	// As in git, a symlink is matched by its own name as a non-directory,
+	// whatever it points to: "build/" does not match a link named build, and
+	// rules for the target do not reach the link.
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"real/x.go": "x"})
+	if err := os.Symlink("real", filepath.Join(root, "build")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tests := []struct {
+		rules string
+		want  map[string]bool
+	}{
+		{"build/\n", map[string]bool{".": false, "build": false, "real": false, "real/x.go": false}},
+		{"build\n", map[string]bool{".": false, "build": true, "real": false, "real/x.go": false}},
+		{"real/\n", map[string]bool{".": false, "build": false, "real": true}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.TrimSpace(tt.rules), func(t *testing.T) {
+			m := New()
+			m.AddPatterns("", []byte(tt.rules))
+			got := map[string]bool{}
+			err := m.WalkDirAll(root, func(relPath string, d fs.DirEntry, ignored bool) error {
+				got[relPath] = ignored
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkDirAll: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("visited %v\nwant %v", got, tt.want)
+			}
+			for p, w := range tt.want {
+				if ig, ok := got[p]; !ok || ig != w {
+					t.Errorf("%s: ignored = %v (visited %v), want %v", p, ig, ok, w)
+				}
+			}
+		})
+	}
+}
+
+func TestWalkDirAll_CallbackControl(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"build/out.js":        "x",
+		"build/keepme/lib.js": "x",
+		"skip/a.txt":          "x",
+		"z.txt":               "x",
+	})
+	m := New()
+	m.AddPatterns("", []byte("build/\n"))
+	m.AddForceTrackedDir("build/keepme")
+
+	var got []string
+	err := m.WalkDirAll(root, func(relPath string, d fs.DirEntry, ignored bool) error {
+		if relPath == "skip" {
+			return fs.SkipDir
+		}
+		if !ignored {
+			got = append(got, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDirAll: %v", err)
+	}
+	sort.Strings(got)
+	want := []string{".", "build/keepme", "build/keepme/lib.js", "z.txt"}
+	if !equalStrings(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+
+	sentinel := errors.New("stop")
+	err = m.WalkDirAll(root, func(relPath string, d fs.DirEntry, ignored bool) error {
+		if ignored {
+			return sentinel
+		}
+		return nil
+	})
+	if !errors.Is(err, sentinel) {
+		t.Errorf("expected sentinel error from WalkDirAll, got %v", err)
+	}
+
+	if err := m.WalkDirAll(filepath.Join(root, "missing"), func(string, fs.DirEntry, bool) error {
+		t.Error("fn should not be called for a missing root")
+		return nil
+	}); err == nil {
+		t.Error("expected an error for a missing root")
+	}
+}
+
+// TestWalkDir_DeepNegation checks that pruning ignored directories agrees
+// with git when a negation names a path deep inside them: "dir/**" ignores
+// dir/keep itself, so "!dir/keep/important" cannot re-include the file, and
+// only un-ignoring the directories ("!dir/**/") lets the walk reach it.
+func TestWalkDir_DeepNegation(t *testing.T) {
+	tests := []struct {
+		name      string
+		gitignore string
+		want      []string
+	}{
+		{
+			name:      "parent ignored",
+			gitignore: "dir/**\n!dir/keep/important\n",
+			want:      []string{".gitignore", "dir"},
+		},
+		{
+			name:      "directories re-included",
+			gitignore: "dir/**\n!dir/**/\n!dir/keep/important\n",
+			want: []string{
+				".gitignore", "dir", "dir/keep", "dir/keep/important", "dir/other",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, map[string]string{
+				".gitignore":         tt.gitignore,
+				"dir/a":              "x",
+				"dir/keep/important": "x",
+				"dir/keep/x":         "x",
+				"dir/other/y":        "x",
+			})
+
+			got := collectWalk(t, New(), root)
+			if !equalStrings(got, tt.want) {
+				t.Errorf("got %v\nwant %v", got, tt.want)
+			}
+
+			if !gitAvailable() {
+				return
+			}
+			cmd := exec.Command("git", "init", "-q")
+			cmd.Dir = root
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git init: %v\n%s", err, out)
+			}
+			cmd = exec.Command("git", "ls-files", "--others", "--exclude-standard")
+			cmd.Dir = root
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("git ls-files: %v", err)
+			}
+			gitFiles := strings.Fields(string(out))
+			sort.Strings(gitFiles)
+			var files []string
+			for _, p := range got {
+				if info, err := os.Stat(filepath.Join(root, p)); err == nil && !info.IsDir() {
+					files = append(files, p)
+				}
+			}
+			if !equalStrings(files, gitFiles) {
+				t.Errorf("walked files %v, git lists %v", files, gitFiles)
+			}
+		})
+	}
+}
+
 func TestFiles_BasicAndFilesOnly(t *testing.T) {
 	root := t.TempDir()
 	writeTree(t, root, map[string]string{
@@ -496,6 +703,50 @@ func TestRepoFiles_LoadRepoErrorYielded(t *testing.T) {
 	}
 }
 
+func TestWalkDir_PreloadedNegation(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"keep.log":  "x",
+		"debug.log": "x",
+		"main.go":   "x",
+	})
+	// Rules loaded before walking, as from a global or exclude file, keep
+	// their negations in the walker's copy of the matcher.
+	m := New()
+	m.AddPatterns("", []byte("*.log\n!keep.log\n"))
+
+	if got, want := collectWalk(t, m, root), []string{"keep.log", "main.go"}; !equalStrings(got, want) {
+		t.Errorf("WalkDir visited %v, want %v", got, want)
+	}
+
+	all := map[string]bool{}
+	err := m.WalkDirAll(root, func(relPath string, d fs.DirEntry, ignored bool) error {
+		all[relPath] = ignored
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDirAll: %v", err)
+	}
+	if all["keep.log"] || !all["debug.log"] {
+		t.Errorf("WalkDirAll reported %v, want keep.log kept and debug.log ignored", all)
+	}
+
+	var fsGot []string
+	fsys := fstest.MapFS{"keep.log": {Data: []byte("x")}, "debug.log": {Data: []byte("x")}}
+	err = m.WalkDirFS(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			fsGot = append(fsGot, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkDirFS: %v", err)
+	}
+	if want := []string{"keep.log"}; !equalStrings(fsGot, want) {
+		t.Errorf("WalkDirFS visited %v, want %v", fsGot, want)
+	}
+}
+
 func TestWalkDirFS_BasicWithMapFS(t *testing.T) {
 	fsys := fstest.MapFS{
 		".gitignore":   {Data: []byte("*.log\n")},